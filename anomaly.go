@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/monitor"
+)
+
+// anomalyRecord is the summary of a backup kept in the anomaly history of a profile
+type anomalyRecord struct {
+	Time       time.Time `json:"time"`
+	Changed    int       `json:"files_changed"` // new and changed files
+	FilesTotal int       `json:"files_total"`
+	BytesAdded uint64    `json:"bytes_added"`
+}
+
+func newAnomalyRecord(summary monitor.Summary, now time.Time) anomalyRecord {
+	return anomalyRecord{
+		Time:       now,
+		Changed:    summary.FilesNew + summary.FilesChanged,
+		FilesTotal: summary.FilesTotal,
+		BytesAdded: summary.BytesAdded,
+	}
+}
+
+// getAnomalyHistoryFile returns the path of the history file of the profile
+func getAnomalyHistoryFile(anomaly *config.AnomalySection, profileName string) string {
+	if anomaly.HistoryFile != "" {
+		return anomaly.HistoryFile
+	}
+	return filepath.Join(xdg.StateHome, constants.ApplicationName, "anomaly-"+profileName+".json")
+}
+
+// loadAnomalyHistory returns the records of the history file, or no record when the file doesn't exist yet
+func loadAnomalyHistory(filename string) ([]anomalyRecord, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []anomalyRecord
+	if err = json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func saveAnomalyHistory(filename string, history []anomalyRecord) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o600)
+}
+
+// detectAnomalies compares the summary of a successful backup with the history of the profile,
+// and returns the anomalies found. The summary is then added to the history.
+func (r *resticWrapper) detectAnomalies(summary monitor.Summary) []string {
+	anomaly := r.profile.Backup.GetAnomaly()
+	if anomaly == nil || r.dryRun {
+		return nil
+	}
+	filename := getAnomalyHistoryFile(anomaly, r.profile.Name)
+	history, err := loadAnomalyHistory(filename)
+	if err != nil {
+		clog.Warningf("profile '%s': cannot load anomaly history %q, starting a new one: %s", r.profile.Name, filename, err)
+		history = nil
+	}
+
+	record := newAnomalyRecord(summary, time.Now())
+	anomalies := evaluateAnomalies(anomaly, history, record)
+	if len(anomalies) > 0 {
+		clog.Warningf("profile '%s': anomaly detected in the backup: %s", r.profile.Name, strings.Join(anomalies, ", "))
+	} else {
+		clog.Debugf("profile '%s': no anomaly detected in the backup", r.profile.Name)
+	}
+
+	// anomalies are kept in the history too: the median ignores a single spike,
+	// and the total number of files is compared with the last backup
+	history = append(history, record)
+	if size := anomaly.GetHistory(); len(history) > size {
+		history = history[len(history)-size:]
+	}
+	if err = saveAnomalyHistory(filename, history); err != nil {
+		clog.Warningf("profile '%s': cannot save anomaly history %q: %s", r.profile.Name, filename, err)
+	}
+	return anomalies
+}
+
+// evaluateAnomalies returns the thresholds of the anomaly detection crossed by the record
+func evaluateAnomalies(anomaly *config.AnomalySection, history []anomalyRecord, record anomalyRecord) (anomalies []string) {
+	const oneMB = 1048576
+
+	if anomaly.MaxChanged > 0 && record.Changed > anomaly.MaxChanged {
+		anomalies = append(anomalies, fmt.Sprintf("%d new and changed files (max-changed is %d)", record.Changed, anomaly.MaxChanged))
+	}
+	if anomaly.MaxAdded > 0 && record.BytesAdded > anomaly.MaxAdded*oneMB {
+		anomalies = append(anomalies, fmt.Sprintf("%d MB added (max-added is %d MB)", record.BytesAdded/oneMB, anomaly.MaxAdded))
+	}
+	if len(history) >= anomaly.GetMinHistory() {
+		if anomaly.MaxChangedRatio > 0 {
+			// at least one file: a backup usually having no change would otherwise always be an anomaly
+			median := math.Max(medianOf(history, func(r anomalyRecord) float64 { return float64(r.Changed) }), 1)
+			if ratio := float64(record.Changed) / median; ratio > anomaly.MaxChangedRatio {
+				anomalies = append(anomalies, fmt.Sprintf("%d new and changed files is %.1f times the median of %g (max-changed-ratio is %g)",
+					record.Changed, ratio, median, anomaly.MaxChangedRatio))
+			}
+		}
+		if anomaly.MaxAddedRatio > 0 {
+			// at least 1 MB, for the same reason
+			median := math.Max(medianOf(history, func(r anomalyRecord) float64 { return float64(r.BytesAdded) }), oneMB)
+			if ratio := float64(record.BytesAdded) / median; ratio > anomaly.MaxAddedRatio {
+				anomalies = append(anomalies, fmt.Sprintf("%d MB added is %.1f times the median of %.0f MB (max-added-ratio is %g)",
+					record.BytesAdded/oneMB, ratio, median/oneMB, anomaly.MaxAddedRatio))
+			}
+		}
+	}
+	if anomaly.MaxFilesDrop > 0 && len(history) > 0 {
+		previous := history[len(history)-1].FilesTotal
+		if previous > 0 && (previous-record.FilesTotal)*100 > anomaly.MaxFilesDrop*previous {
+			anomalies = append(anomalies, fmt.Sprintf("total number of files dropped by %d%% from %d to %d (max-files-drop is %d%%)",
+				(previous-record.FilesTotal)*100/previous, previous, record.FilesTotal, anomaly.MaxFilesDrop))
+		}
+	}
+	return
+}
+
+func medianOf(history []anomalyRecord, value func(anomalyRecord) float64) float64 {
+	values := make([]float64, 0, len(history))
+	for _, record := range history {
+		values = append(values, value(record))
+	}
+	if len(values) == 0 {
+		return 0
+	}
+	slices.Sort(values)
+	middle := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[middle-1] + values[middle]) / 2
+	}
+	return values[middle]
+}
+
+// runOnAnomaly sends the "send-on-anomaly" requests and runs the "run-on-anomaly" commands
+func (r *resticWrapper) runOnAnomaly(command string) error {
+	anomaly := r.profile.Backup.GetAnomaly()
+	if anomaly == nil {
+		return nil
+	}
+	r.sendMonitoring(anomaly.SendOnAnomaly, command, "send-on-anomaly", nil)
+	return r.runShellCommands(anomaly.RunOnAnomaly, "run-on-anomaly", command, nil)
+}
+
+// isRetentionBlocked returns true when the retention must not run after a backup with an anomaly
+func (r *resticWrapper) isRetentionBlocked() bool {
+	if len(r.anomalies) == 0 {
+		return false
+	}
+	anomaly := r.profile.Backup.GetAnomaly()
+	return anomaly != nil && anomaly.BlockRetention
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/monitor"
+	"github.com/creativeprojects/resticprofile/term"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateAnomalies(t *testing.T) {
+	t.Parallel()
+
+	const oneMB = 1048576
+	history := []anomalyRecord{
+		{Changed: 10, FilesTotal: 1000, BytesAdded: 20 * oneMB},
+		{Changed: 20, FilesTotal: 1000, BytesAdded: 10 * oneMB},
+		{Changed: 1000, FilesTotal: 1010, BytesAdded: 500 * oneMB},
+		{Changed: 30, FilesTotal: 1010, BytesAdded: 30 * oneMB},
+	}
+
+	testCases := []struct {
+		name      string
+		anomaly   config.AnomalySection
+		history   []anomalyRecord
+		record    anomalyRecord
+		anomalies []string
+	}{
+		{
+			name:    "max changed not reached",
+			anomaly: config.AnomalySection{MaxChanged: 100},
+			record:  anomalyRecord{Changed: 100},
+		},
+		{
+			name:      "max changed",
+			anomaly:   config.AnomalySection{MaxChanged: 100},
+			record:    anomalyRecord{Changed: 101},
+			anomalies: []string{"101 new and changed files (max-changed is 100)"},
+		},
+		{
+			name:      "max added",
+			anomaly:   config.AnomalySection{MaxAdded: 100},
+			record:    anomalyRecord{BytesAdded: 200 * oneMB},
+			anomalies: []string{"200 MB added (max-added is 100 MB)"},
+		},
+		{
+			name:    "changed ratio not reached",
+			anomaly: config.AnomalySection{MaxChangedRatio: 5},
+			history: history,
+			record:  anomalyRecord{Changed: 125, FilesTotal: 1010},
+		},
+		{
+			name:      "changed ratio",
+			anomaly:   config.AnomalySection{MaxChangedRatio: 5},
+			history:   history,
+			record:    anomalyRecord{Changed: 126, FilesTotal: 1010},
+			anomalies: []string{"126 new and changed files is 5.0 times the median of 25 (max-changed-ratio is 5)"},
+		},
+		{
+			name:    "changed ratio without enough history",
+			anomaly: config.AnomalySection{MaxChangedRatio: 5, MinHistory: 5},
+			history: history,
+			record:  anomalyRecord{Changed: 1000},
+		},
+		{
+			name:      "changed ratio with no change in history",
+			anomaly:   config.AnomalySection{MaxChangedRatio: 5, MinHistory: 1},
+			history:   []anomalyRecord{{Changed: 0}},
+			record:    anomalyRecord{Changed: 6},
+			anomalies: []string{"6 new and changed files is 6.0 times the median of 1 (max-changed-ratio is 5)"},
+		},
+		{
+			name:      "added ratio",
+			anomaly:   config.AnomalySection{MaxAddedRatio: 10},
+			history:   history,
+			record:    anomalyRecord{BytesAdded: 300 * oneMB, FilesTotal: 1010},
+			anomalies: []string{"300 MB added is 12.0 times the median of 25 MB (max-added-ratio is 10)"},
+		},
+		{
+			name:    "added ratio with nothing added in history",
+			anomaly: config.AnomalySection{MaxAddedRatio: 10, MinHistory: 1},
+			history: []anomalyRecord{{BytesAdded: 0}},
+			record:  anomalyRecord{BytesAdded: 10 * oneMB},
+		},
+		{
+			name:    "files drop not reached",
+			anomaly: config.AnomalySection{MaxFilesDrop: 10},
+			history: history,
+			record:  anomalyRecord{FilesTotal: 909},
+		},
+		{
+			name:      "files drop",
+			anomaly:   config.AnomalySection{MaxFilesDrop: 10},
+			history:   history,
+			record:    anomalyRecord{FilesTotal: 505},
+			anomalies: []string{"total number of files dropped by 50% from 1010 to 505 (max-files-drop is 10%)"},
+		},
+		{
+			name:    "files drop without history",
+			anomaly: config.AnomalySection{MaxFilesDrop: 10},
+			record:  anomalyRecord{FilesTotal: 0},
+		},
+		{
+			name:    "more files",
+			anomaly: config.AnomalySection{MaxFilesDrop: 10},
+			history: history,
+			record:  anomalyRecord{FilesTotal: 5000},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			anomalies := evaluateAnomalies(&tc.anomaly, tc.history, tc.record)
+			assert.Equal(t, tc.anomalies, anomalies)
+		})
+	}
+}
+
+func TestMedianOf(t *testing.T) {
+	t.Parallel()
+
+	changed := func(r anomalyRecord) float64 { return float64(r.Changed) }
+	assert.Equal(t, 0.0, medianOf(nil, changed))
+	assert.Equal(t, 3.0, medianOf([]anomalyRecord{{Changed: 3}}, changed))
+	assert.Equal(t, 2.5, medianOf([]anomalyRecord{{Changed: 3}, {Changed: 2}}, changed))
+	assert.Equal(t, 3.0, medianOf([]anomalyRecord{{Changed: 100}, {Changed: 1}, {Changed: 3}}, changed))
+}
+
+func TestAnomalyHistory(t *testing.T) {
+	t.Parallel()
+
+	historyFile := filepath.Join(t.TempDir(), "state", "history.json")
+	profile := config.NewProfile(nil, "name")
+	profile.Backup = &config.BackupSection{Anomaly: &config.AnomalySection{
+		History:      3,
+		HistoryFile:  historyFile,
+		MaxFilesDrop: 20,
+	}}
+	wrapper := newResticWrapper(&Context{profile: profile, terminal: term.NewTerminal()})
+
+	for _, total := range []int{100, 110, 120, 130} {
+		assert.Empty(t, wrapper.detectAnomalies(monitor.Summary{FilesNew: 1, FilesChanged: 2, FilesTotal: total}))
+	}
+	anomalies := wrapper.detectAnomalies(monitor.Summary{FilesTotal: 10})
+	assert.Equal(t, []string{"total number of files dropped by 92% from 130 to 10 (max-files-drop is 20%)"}, anomalies)
+
+	// the anomaly is kept in the history
+	history, err := loadAnomalyHistory(historyFile)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, []int{120, 130, 10}, []int{history[0].FilesTotal, history[1].FilesTotal, history[2].FilesTotal})
+	assert.Equal(t, 3, history[0].Changed)
+	assert.WithinDuration(t, time.Now(), history[2].Time, time.Minute)
+
+	// an invalid history is replaced
+	require.NoError(t, os.WriteFile(historyFile, []byte("invalid"), 0o600))
+	assert.Empty(t, wrapper.detectAnomalies(monitor.Summary{FilesTotal: 10}))
+	history, err = loadAnomalyHistory(historyFile)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
+
+func TestAnomalyHistoryFile(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "history.json", getAnomalyHistoryFile(&config.AnomalySection{HistoryFile: "history.json"}, "name"))
+	assert.Equal(t, "anomaly-name.json", filepath.Base(getAnomalyHistoryFile(&config.AnomalySection{}, "name")))
+
+	history, err := loadAnomalyHistory(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestBackupWithAnomaly(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "output.txt")
+	require.NoError(t, os.WriteFile(outputFile, []byte("Files:  10 new,  2 changed,  100 unmodified\nprocessed 112 files, 1.000 MiB in 0:01\n"), 0o600))
+
+	testCases := []struct {
+		name           string
+		blockRetention bool
+		started        []string
+	}{
+		{name: "retention", blockRetention: false, started: []string{"backup", "retention"}},
+		{name: "blocked retention", blockRetention: true, started: []string{"backup"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testFile := filepath.Join(t.TempDir(), "anomaly.txt")
+			historyFile := filepath.Join(t.TempDir(), "history.json")
+			testConfig := fmt.Sprintf(`
+version: "1"
+profile:
+  backup:
+    stdout: "@%s"
+    anomaly:
+      history-file: %q
+      max-changed: 5
+      block-retention: %v
+      run-on-anomaly:
+        - "echo $ANOMALY > %s"
+  retention:
+    after-backup: true
+`, filepath.ToSlash(outputFile), filepath.ToSlash(historyFile), tc.blockRetention, filepath.ToSlash(testFile))
+
+			cfg, err := config.Load(strings.NewReader(testConfig), "yaml")
+			require.NoError(t, err)
+			profile, err := cfg.GetProfile("profile")
+			require.NoError(t, err)
+
+			ctx := &Context{
+				binary:   mockBinary,
+				profile:  profile,
+				command:  "backup",
+				terminal: term.NewTerminal(term.WithStdout(&bytes.Buffer{}), term.WithStderr(&bytes.Buffer{})),
+			}
+			receiver := &stepsReceiver{summaries: make(map[string]monitor.Summary), results: make(map[string]error)}
+			wrapper := newResticWrapper(ctx)
+			wrapper.addProgress(receiver)
+
+			require.NoError(t, wrapper.runProfile())
+			assert.Equal(t, tc.started, receiver.started)
+
+			anomalies := []string{"12 new and changed files (max-changed is 5)"}
+			assert.Equal(t, anomalies, receiver.summaries["backup"].Anomalies)
+			assert.Equal(t, anomalies, wrapper.getContext().Anomalies)
+
+			content, err := os.ReadFile(testFile)
+			require.NoError(t, err)
+			assert.Equal(t, anomalies[0], strings.TrimSpace(string(content)))
+		})
+	}
+}
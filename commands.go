@@ -76,12 +76,14 @@ func getOwnCommands() []ownCommand {
 			needConfiguration: false,
 			hide:              false,
 			flags: map[string]string{
-				"--random-key [size]":                            "generate a cryptographically secure random key to use as a restic keyfile (size defaults to 1024 when omitted)",
-				"--config-reference [--version 0.15] [template]": "generate a config file reference from a go template (defaults to the built-in markdown template when omitted)",
-				"--json-schema [--version 0.15] [v1|v2]":         "generate a JSON schema that validates resticprofile configuration files in YAML or JSON format",
-				"--bash-completion":                              "generate a shell completion script for bash",
-				"--zsh-completion":                               "generate a shell completion script for zsh",
-				"--fish-completion":                              "generate a shell completion script for fish",
+				"--random-key [size]":                             "generate a cryptographically secure random key to use as a restic keyfile (size defaults to 1024 when omitted)",
+				"--config-reference [--version 0.15] [template]":  "generate a config file reference from a go template (defaults to the built-in markdown template when omitted)",
+				"--json-schema [--version 0.15] [v1|v2]":          "generate a JSON schema that validates resticprofile configuration files in YAML or JSON format",
+				"--encrypt [--sops] [--recipient age1...] <file>": "encrypt a configuration file with age (whole file) or SOPS (values only), using the public keys of --age-key-file when no recipient is given",
+				"--import autorestic|crontab [--system] <file>":   "convert an autorestic configuration or the crontab entries running restic into a configuration file (use - to read stdin)",
+				"--bash-completion":                               "generate a shell completion script for bash",
+				"--zsh-completion":                                "generate a shell completion script for zsh",
+				"--fish-completion":                               "generate a shell completion script for fish",
 			},
 		},
 		// commands that need the configuration
@@ -91,6 +93,10 @@ func getOwnCommands() []ownCommand {
 			longDescription:   "The \"profiles\" command prints brief information on all profiles and groups that are declared in the configuration file",
 			action:            displayProfilesCommand,
 			needConfiguration: true,
+			flags: map[string]string{
+				"--sources":               "display the configuration files declaring each profile",
+				"--format text|json|yaml": "output format (defaults to text)",
+			},
 		},
 		{
 			name:              "show",
@@ -98,6 +104,22 @@ func getOwnCommands() []ownCommand {
 			longDescription:   "The \"show\" command prints the effective configuration of the selected profile or group.\n\nThe effective profile or group configuration is built by loading all includes, applying inheritance, mixins, templates and variables and parsing the result.",
 			action:            showProfileOrGroup,
 			needConfiguration: true,
+			flags:             map[string]string{"--format text|json|yaml": "output format (defaults to text), confidential values are masked"},
+		},
+		{
+			name:              "env",
+			description:       "print the environment of the current profile, to run restic commands directly from a shell",
+			longDescription:   "The \"env\" command prints the environment variables of the selected profile (profile \"env\", repository, password file or command, cacert, etc.) in a format that can be evaluated by a shell, e.g. use \"eval $(resticprofile -n profile env)\" before running restic commands directly.\n\nThe output contains confidential values like passwords in clear text.",
+			action:            exportEnvironment,
+			needConfiguration: true,
+			flags:             map[string]string{"--format sh|fish|pwsh|dotenv": "output format (defaults to sh, or pwsh on Windows)"},
+		},
+		{
+			name:              "shell",
+			description:       "start an interactive shell with the environment of the current profile",
+			longDescription:   "The \"shell\" command starts the shell of the current user (from $SHELL, or PowerShell on Windows) with the environment of the selected profile, as printed by the \"env\" command. Restic commands typed in this shell use the repository of the profile.",
+			action:            startProfileShell,
+			needConfiguration: true,
 		},
 		{
 			name:              "schedule",
@@ -129,7 +151,10 @@ func getOwnCommands() []ownCommand {
 			action:            statusSchedule,
 			needConfiguration: true,
 			hide:              false,
-			flags:             map[string]string{"--all": "display the status of all scheduled jobs of all profiles and groups"},
+			flags: map[string]string{
+				"--all":                   "display the status of all scheduled jobs of all profiles and groups",
+				"--format text|json|yaml": "output format (defaults to text)",
+			},
 		},
 		{
 			name:              "run-schedule",
@@ -259,6 +284,14 @@ func showProfileOrGroup(ctx commandContext) error {
 	c := ctx.config
 	flags := ctx.flags
 
+	format, err := getOutputFormat(ctx.request.arguments)
+	if err != nil {
+		return err
+	}
+	if format != formatText {
+		return withoutLogs(func() error { return showProfileOrGroupFormatted(ctx, format) })
+	}
+
 	// Load global section
 	global, err := c.GetGlobalSection()
 	if err != nil {
@@ -320,6 +353,31 @@ func showProfileOrGroup(ctx commandContext) error {
 	return nil
 }
 
+// showProfileOrGroupFormatted prints the profile or group in a machine-readable format
+func showProfileOrGroupFormatted(ctx commandContext, format string) error {
+	name := ctx.flags.name
+	var profileOrGroup config.Schedulable
+	if ctx.config.HasProfile(name) {
+		profile, cleanup, err := openProfile(ctx.config, name)
+		defer cleanup()
+		if err != nil {
+			return fmt.Errorf("profile '%s': %w", name, err)
+		}
+		profileOrGroup = profile
+
+	} else if ctx.config.HasProfileGroup(name) {
+		group, err := ctx.config.GetProfileGroup(name)
+		if err != nil {
+			return fmt.Errorf("group '%s': %w", name, err)
+		}
+		profileOrGroup = group
+
+	} else {
+		return fmt.Errorf("profile or group '%s': %w", name, config.ErrNotFound)
+	}
+	return writeFormatted(ctx.terminal, format, newShowOutput(profileOrGroup))
+}
+
 func showSchedules(output io.Writer, schedules []*config.Schedule) {
 	slices.SortFunc(schedules, config.CompareSchedules)
 	for _, schedule := range schedules {
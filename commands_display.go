@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"sort"
 	"strings"
 	"text/tabwriter"
@@ -128,6 +129,7 @@ func displayCommonUsageHelp(ctx commandContext) {
 
 	out("Usage:\n")
 	out("\t%s [restic flags]\n", getCommonUsageHelpLine("restic-command", true))
+	out("\t%s --all | --select label=value [--parallel N] [restic flags]\n", getCommonUsageHelpLine("restic-command", false))
 	out("\t%s [command specific flags]\n", getCommonUsageHelpLine("resticprofile-command", true))
 	out("\n")
 	out(ansi.Bold("resticprofile flags:\n"))
@@ -286,34 +288,89 @@ func displayVersion(ctx commandContext) error {
 }
 
 func displayProfilesCommand(ctx commandContext) error {
+	format, err := getOutputFormat(ctx.request.arguments)
+	if err != nil {
+		return err
+	}
+	if format != formatText {
+		return withoutLogs(func() error {
+			withSources := slices.Contains(ctx.request.arguments, "--sources")
+			return writeFormatted(ctx.terminal, format, newProfilesOutput(ctx.config, withSources))
+		})
+	}
 	displayProfiles(ctx)
+	if slices.Contains(ctx.request.arguments, "--sources") {
+		displayProfileSources(ctx)
+	}
 	displayGroups(ctx)
 	return nil
 }
 
+// displayProfileSources displays the configuration files declaring each profile (including abstract and inactive profiles)
+func displayProfileSources(ctx commandContext) {
+	out, closer := displayWriter(ctx.terminal)
+	defer closer()
+
+	names := ctx.config.GetProfileNames()
+	sort.Strings(names)
+	out("%s (name, files):\n", ansi.Bold("Profile sources"))
+	for _, name := range names {
+		sources := ctx.config.GetProfileSources(name)
+		if len(sources) == 0 {
+			sources = []string{"-"}
+		}
+		out("\t%s:\t%s\n", name, strings.Join(sources, ", "))
+	}
+	out("\n")
+}
+
 func displayProfiles(ctx commandContext) {
 	out, closer := displayWriter(ctx.terminal)
 	defer closer()
 
 	profiles := ctx.config.GetProfiles()
 	keys := sortedProfileKeys(profiles)
+	inactive := make([]string, 0)
 	if len(profiles) == 0 {
 		out("\nThere's no available profile in the configuration\n")
 	} else {
 		out("\n%s (name, sections, description):\n", ansi.Bold("Profiles available"))
 		for _, name := range keys {
-			sections := profiles[name].DefinedCommands()
-			sort.Strings(sections)
+			profile := profiles[name]
+			if profile.Abstract {
+				continue
+			}
+			if profile.GetActivation().InactiveReason() != "" {
+				inactive = append(inactive, name)
+				continue
+			}
+			sections := activeCommands(profile)
 			if len(sections) == 0 {
-				out("\t%s:\t(n/a)\t%s\n", name, profiles[name].Description)
+				out("\t%s:\t(n/a)\t%s\n", name, profile.Description)
 			} else {
-				out("\t%s:\t(%s)\t%s\n", name, ansi.Cyan(strings.Join(sections, ", ")), profiles[name].Description)
+				out("\t%s:\t(%s)\t%s\n", name, ansi.Cyan(strings.Join(sections, ", ")), profile.Description)
 			}
 		}
+		if len(inactive) > 0 {
+			out("\n%s: %s\n", ansi.Bold("Profiles not active on this host"), strings.Join(inactive, ", "))
+		}
 	}
 	out("\n")
 }
 
+// activeCommands returns the sorted list of commands defined in the profile that are active on this host
+func activeCommands(profile *config.Profile) []string {
+	sections := make([]string, 0)
+	for _, command := range profile.DefinedCommands() {
+		if section, ok := config.GetSectionWith[config.Activation](profile, command); ok && section.GetActivation().InactiveReason() != "" {
+			continue
+		}
+		sections = append(sections, command)
+	}
+	sort.Strings(sections)
+	return sections
+}
+
 func displayGroups(ctx commandContext) {
 	out, closer := displayWriter(ctx.terminal)
 	defer closer()
@@ -324,7 +381,10 @@ func displayGroups(ctx commandContext) {
 	}
 	out("%s (name, profiles, description):\n", ansi.Bold("Groups available"))
 	for name, groupList := range groups {
-		out("\t%s:\t[%s]\t%s\n", name, ansi.Cyan(strings.Join(groupList.Profiles, ", ")), groupList.Description)
+		members := collect.All(groupList.Profiles, func(profileName string) bool {
+			return groupList.GetMemberActivation(profileName).InactiveReason() == ""
+		})
+		out("\t%s:\t[%s]\t%s\n", name, ansi.Cyan(strings.Join(members, ", ")), groupList.Description)
 	}
 	out("\n")
 }
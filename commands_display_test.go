@@ -2,10 +2,14 @@ package main
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/creativeprojects/resticprofile/config"
 	"github.com/creativeprojects/resticprofile/term"
 	"github.com/fatih/color"
 	"github.com/stretchr/testify/assert"
@@ -87,3 +91,58 @@ func TestDisplayVersionVerbose2(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, strings.Contains(buffer.String(), runtime.GOOS))
 }
+
+func TestDisplayProfilesWithConditions(t *testing.T) {
+	configContent := `version = "2"
+        [profiles.active]
+         [profiles.active.backup]
+          source = "/"
+         [profiles.active.check]
+          enabled = false
+        [profiles.inactive]
+         only-on-os = ["plan9"]
+        [profiles.abstract]
+         abstract = true
+        [groups.group]
+         profiles = ["active", "inactive", "other"]
+         [groups.group.conditions.other]
+          enabled = false
+    `
+	cfg, err := config.Load(bytes.NewBufferString(configContent), config.FormatTOML)
+	require.NoError(t, err)
+
+	buffer := &bytes.Buffer{}
+	err = displayProfilesCommand(commandContext{Context: Context{config: cfg, terminal: term.NewTerminal(term.WithStdout(buffer))}})
+	require.NoError(t, err)
+
+	output := buffer.String()
+	assert.Regexp(t, `active:\s+\(backup\)`, output)
+	assert.Regexp(t, `Profiles not active on this host: inactive\n`, output)
+	assert.NotContains(t, output, "abstract")
+	assert.Regexp(t, `group:\s+\[active, inactive\]`, output)
+}
+
+func TestDisplayProfileSources(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "profiles.toml")
+	dropInFile := filepath.Join(dir, "profiles.d", "app.yaml")
+	require.NoError(t, os.Mkdir(filepath.Dir(dropInFile), 0o700))
+	require.NoError(t, os.WriteFile(configFile, []byte("version = \"2\"\n[profiles.default]\nrepository = \"local:/backup\"\n"), 0o600))
+	require.NoError(t, os.WriteFile(dropInFile, []byte("profiles:\n  app:\n    inherit: default\n"), 0o600))
+
+	cfg, err := config.LoadFile(configFile, "")
+	require.NoError(t, err)
+
+	buffer := &bytes.Buffer{}
+	ctx := commandContext{Context: Context{config: cfg, terminal: term.NewTerminal(term.WithStdout(buffer))}}
+	require.NoError(t, displayProfilesCommand(ctx))
+	assert.NotContains(t, buffer.String(), "Profile sources")
+
+	buffer.Reset()
+	ctx.request.arguments = []string{"--sources"}
+	require.NoError(t, displayProfilesCommand(ctx))
+	output := buffer.String()
+	assert.Contains(t, output, "Profile sources (name, files):")
+	assert.Regexp(t, `app:\s+`+regexp.QuoteMeta(dropInFile)+`\n`, output)
+	assert.Regexp(t, `default:\s+`+regexp.QuoteMeta(configFile)+`\n`, output)
+}
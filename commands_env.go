@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/platform"
+)
+
+// envFlags maps the restic flags of a profile to the environment variables read by restic
+var envFlags = map[string]string{
+	constants.ParameterRepository:      "RESTIC_REPOSITORY",
+	constants.ParameterRepositoryFile:  "RESTIC_REPOSITORY_FILE",
+	constants.ParameterPasswordFile:    "RESTIC_PASSWORD_FILE",
+	constants.ParameterPasswordCommand: "RESTIC_PASSWORD_COMMAND",
+	constants.ParameterKeyHint:         "RESTIC_KEY_HINT",
+	"cacert":                           "RESTIC_CACERT",
+	"tls-client-cert":                  "RESTIC_TLS_CLIENT_CERT",
+	"cache-dir":                        "RESTIC_CACHE_DIR",
+	"compression":                      "RESTIC_COMPRESSION",
+	"pack-size":                        "RESTIC_PACK_SIZE",
+	"read-concurrency":                 "RESTIC_READ_CONCURRENCY",
+}
+
+// envFormats declares how to print a variable definition in each supported format
+var envFormats = map[string]func(name, value string) string{
+	"sh": func(name, value string) string {
+		return fmt.Sprintf("export %s='%s'", name, strings.ReplaceAll(value, `'`, `'\''`))
+	},
+	"fish": func(name, value string) string {
+		return fmt.Sprintf("set -gx %s '%s'", name, strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value))
+	},
+	"pwsh": func(name, value string) string {
+		return fmt.Sprintf("$env:%s = '%s'", name, strings.ReplaceAll(value, `'`, `''`))
+	},
+	"dotenv": func(name, value string) string {
+		return fmt.Sprintf(`%s="%s"`, name, strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`).Replace(value))
+	},
+}
+
+// exportEnvironment prints the environment of the selected profile, to use restic directly from a shell
+func exportEnvironment(ctx commandContext) error {
+	format := "sh"
+	if platform.IsWindows() {
+		format = "pwsh"
+	}
+	args := ctx.request.arguments
+	if index := slices.Index(args, "--format"); index >= 0 {
+		if index+1 >= len(args) {
+			return fmt.Errorf("missing value for --format")
+		}
+		format = args[index+1]
+	}
+	formatter, found := envFormats[format]
+	if !found {
+		return fmt.Errorf("unsupported format %q, expected one of: %s", format, strings.Join(slices.Sorted(maps.Keys(envFormats)), ", "))
+	}
+
+	env, err := getProfileShellEnvironment(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		_, _ = fmt.Fprintln(ctx.terminal, formatter(name, env[name]))
+	}
+	return nil
+}
+
+// startProfileShell starts an interactive shell with the environment of the selected profile
+func startProfileShell(ctx commandContext) error {
+	env, err := getProfileShellEnvironment(ctx)
+	if err != nil {
+		return err
+	}
+	program, args := getInteractiveShell()
+	clog.Infof("profile '%s': starting %s, type \"exit\" to leave", ctx.request.profile, program)
+
+	cmd := exec.Command(program, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = ctx.terminal
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		cmd.Env = append(cmd.Env, name+"="+env[name])
+	}
+	if err = cmd.Run(); err != nil {
+		if exitError := new(exec.ExitError); errors.As(err, &exitError) {
+			// the exit code of the last command typed in the shell doesn't make the shell fail
+			clog.Debugf("shell exited: %s", err.Error())
+			return nil
+		}
+		return fmt.Errorf("cannot start %s: %w", program, err)
+	}
+	return nil
+}
+
+// getInteractiveShell returns the shell of the current user
+func getInteractiveShell() (program string, args []string) {
+	if platform.IsWindows() {
+		if _, err := exec.LookPath("pwsh.exe"); err == nil {
+			return "pwsh.exe", []string{"-NoLogo"}
+		}
+		return "powershell.exe", []string{"-NoLogo"}
+	}
+	if program = os.Getenv("SHELL"); program == "" {
+		program = "/bin/sh"
+	}
+	return
+}
+
+// getProfileShellEnvironment loads the selected profile and returns its environment
+func getProfileShellEnvironment(ctx commandContext) (map[string]string, error) {
+	profileName := ctx.request.profile
+	if !ctx.config.HasProfile(profileName) {
+		return nil, fmt.Errorf("profile '%s': %w", profileName, config.ErrNotFound)
+	}
+	profile, cleanup, err := openProfile(ctx.config, profileName)
+	defer cleanup()
+	if err != nil {
+		return nil, fmt.Errorf("profile '%s': %w", profileName, err)
+	}
+	if profile.Abstract {
+		return nil, fmt.Errorf("profile '%s' is abstract: it can only be inherited", profileName)
+	}
+	return getResticEnvironment(profile), nil
+}
+
+// getResticEnvironment returns the profile environment with the restic flags that can be set as environment variables
+func getResticEnvironment(profile *config.Profile) map[string]string {
+	env := profile.GetEnvironment(false).ValuesAsMap()
+	env[constants.EnvProfileName] = profile.Name
+
+	flags := profile.GetCommonFlags()
+	for flag, name := range envFlags {
+		if values, found := flags.Get(flag); found && len(values) > 0 {
+			env[name] = values[len(values)-1].Value()
+		}
+	}
+
+	// a repository with a password is written to a temporary file that won't exist after resticprofile exits
+	if profile.Repository.IsConfidential() && profile.RepositoryFile == "" {
+		delete(env, "RESTIC_REPOSITORY_FILE")
+		env["RESTIC_REPOSITORY"] = profile.Repository.Value()
+	}
+	return env
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/schedule"
+	"github.com/creativeprojects/resticprofile/shell"
+	"github.com/creativeprojects/resticprofile/util/collect"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+// profilesOutput is the machine-readable output of the "profiles" command
+type profilesOutput struct {
+	Profiles []profileOutput `json:"profiles" yaml:"profiles"`
+	Groups   []groupOutput   `json:"groups" yaml:"groups"`
+}
+
+type profileOutput struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description" yaml:"description"`
+	Active      bool     `json:"active" yaml:"active"`
+	Commands    []string `json:"commands" yaml:"commands"`
+	Sources     []string `json:"sources,omitempty" yaml:"sources,omitempty"`
+}
+
+type groupOutput struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description" yaml:"description"`
+	Profiles    []string `json:"profiles" yaml:"profiles"`
+}
+
+// showOutput is the machine-readable output of the "show" command
+type showOutput struct {
+	Kind        string           `json:"kind" yaml:"kind"`
+	Name        string           `json:"name" yaml:"name"`
+	Description string           `json:"description" yaml:"description"`
+	Profiles    []string         `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+	Commands    []commandOutput  `json:"commands,omitempty" yaml:"commands,omitempty"`
+	Schedules   []scheduleOutput `json:"schedules" yaml:"schedules"`
+}
+
+type commandOutput struct {
+	Name      string              `json:"name" yaml:"name"`
+	Flags     map[string][]string `json:"flags" yaml:"flags"`
+	Arguments []string            `json:"arguments,omitempty" yaml:"arguments,omitempty"`
+}
+
+type scheduleOutput struct {
+	Kind       string   `json:"kind,omitempty" yaml:"kind,omitempty"` // unknown for jobs not declared in the configuration
+	Name       string   `json:"name" yaml:"name"`
+	Command    string   `json:"command" yaml:"command"`
+	At         []string `json:"at" yaml:"at"`
+	Permission string   `json:"permission" yaml:"permission"`
+	Priority   string   `json:"priority" yaml:"priority"`
+	Log        string   `json:"log,omitempty" yaml:"log,omitempty"`
+}
+
+// statusOutput is the machine-readable output of the "status" command
+type statusOutput struct {
+	Schedules []scheduleStatusOutput `json:"schedules" yaml:"schedules"`
+}
+
+type scheduleStatusOutput struct {
+	scheduleOutput `yaml:",inline"`
+	Declared       bool `json:"declared" yaml:"declared"` // declared in the configuration file
+	Installed      bool `json:"installed" yaml:"installed"`
+}
+
+// getOutputFormat returns the output format requested with "--format" (defaults to text)
+func getOutputFormat(args []string) (string, error) {
+	index := slices.Index(args, "--format")
+	if index < 0 {
+		return formatText, nil
+	}
+	if index+1 >= len(args) {
+		return "", fmt.Errorf("missing value for --format")
+	}
+	switch format := args[index+1]; format {
+	case formatText, formatJSON, formatYAML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, expected one of: %s, %s, %s", format, formatText, formatJSON, formatYAML)
+	}
+}
+
+// writeFormatted writes the value in JSON or YAML format
+func writeFormatted(output io.Writer, format string, value any) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case formatYAML:
+		encoder := yaml.NewEncoder(output)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(value)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// withoutLogs discards the log output while running action, so that it doesn't mix with a machine-readable output
+func withoutLogs(action func() error) error {
+	logger := clog.GetDefaultLogger()
+	handler := logger.GetHandler()
+	logger.SetHandler(clog.NewDiscardHandler())
+	defer logger.SetHandler(handler)
+	return action()
+}
+
+func newProfilesOutput(c *config.Config, withSources bool) profilesOutput {
+	output := profilesOutput{
+		Profiles: make([]profileOutput, 0),
+		Groups:   make([]groupOutput, 0),
+	}
+	profiles := c.GetProfiles()
+	for _, name := range sortedProfileKeys(profiles) {
+		profile := profiles[name]
+		if profile.Abstract {
+			continue
+		}
+		item := profileOutput{
+			Name:        name,
+			Description: profile.Description,
+			Active:      profile.GetActivation().InactiveReason() == "",
+			Commands:    activeCommands(profile),
+		}
+		if withSources {
+			item.Sources = c.GetProfileSources(name)
+		}
+		output.Profiles = append(output.Profiles, item)
+	}
+
+	groups := c.GetProfileGroups()
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		group := groups[name]
+		output.Groups = append(output.Groups, groupOutput{
+			Name:        name,
+			Description: group.Description,
+			Profiles: collect.All(group.Profiles, func(profileName string) bool {
+				return group.GetMemberActivation(profileName).InactiveReason() == ""
+			}),
+		})
+	}
+	return output
+}
+
+func newShowOutput(profileOrGroup config.Schedulable) showOutput {
+	output := showOutput{
+		Kind:      profileOrGroup.Kind(),
+		Schedules: newSchedulesOutput(slices.Collect(maps.Values(profileOrGroup.Schedules()))),
+	}
+	switch value := profileOrGroup.(type) {
+	case *config.Profile:
+		output.Name = value.Name
+		output.Description = value.Description
+		output.Commands = make([]commandOutput, 0)
+		for _, command := range value.DefinedCommands() {
+			output.Commands = append(output.Commands, newCommandOutput(value, command))
+		}
+	case *config.Group:
+		output.Name = value.Name
+		output.Description = value.Description
+		output.Profiles = value.Profiles
+	}
+	return output
+}
+
+// newCommandOutput returns the flags and arguments of the command, where confidential values are masked
+func newCommandOutput(profile *config.Profile, command string) commandOutput {
+	output := commandOutput{
+		Name:  command,
+		Flags: make(map[string][]string),
+	}
+	config.GetNonConfidentialArgs(profile, profile.GetCommandFlags(command)).Walk(func(name string, arg *shell.Arg) *shell.Arg {
+		if name == "" {
+			output.Arguments = append(output.Arguments, arg.GetConfidentialValue())
+			return arg
+		}
+		values := output.Flags[name]
+		if values == nil {
+			values = make([]string, 0, 1)
+		}
+		if arg.HasValue() {
+			values = append(values, arg.GetConfidentialValue())
+		}
+		output.Flags[name] = values
+		return arg
+	})
+
+	// show the repository instead of the temporary file replacing a repository with a password
+	if profile.Repository.IsConfidential() && profile.RepositoryFile == "" {
+		if _, found := output.Flags[constants.ParameterRepositoryFile]; found {
+			delete(output.Flags, constants.ParameterRepositoryFile)
+			output.Flags[constants.ParameterRepository] = []string{profile.Repository.String()}
+		}
+	}
+	return output
+}
+
+func newScheduleOutput(schedule *config.Schedule) scheduleOutput {
+	origin := schedule.ScheduleOrigin()
+	kind := constants.SchedulableKindProfile
+	if origin.Type == config.ScheduleOriginGroup {
+		kind = constants.SchedulableKindGroup
+	}
+	return scheduleOutput{
+		Kind:       kind,
+		Name:       origin.Name,
+		Command:    origin.Command,
+		At:         slices.Clone(schedule.Schedules),
+		Permission: schedule.Permission,
+		Priority:   schedule.Priority,
+		Log:        schedule.Log,
+	}
+}
+
+func newSchedulesOutput(schedules []*config.Schedule) []scheduleOutput {
+	schedules = slices.Clone(schedules)
+	slices.SortFunc(schedules, config.CompareSchedules)
+	output := make([]scheduleOutput, 0, len(schedules))
+	for _, schedule := range schedules {
+		output = append(output, newScheduleOutput(schedule))
+	}
+	return output
+}
+
+// newStatusOutput merges the declared schedules with the jobs installed in the scheduler
+func newStatusOutput(handler schedule.Handler, configFile, profileName string, schedules []*config.Schedule) (statusOutput, error) {
+	output := statusOutput{Schedules: make([]scheduleStatusOutput, 0, len(schedules))}
+
+	if err := handler.Init(); err != nil {
+		return output, err
+	}
+	defer handler.Close()
+
+	installed := make(map[string]bool)
+	configs, err := handler.Scheduled(profileName)
+	if err != nil {
+		if len(configs) == 0 {
+			return output, err
+		}
+		clog.Errorf("some configurations failed to load:\n%v", err)
+	}
+	for _, cfg := range configs {
+		if cfg.ConfigFile == configFile {
+			installed[cfg.ProfileName+"/"+cfg.CommandName] = true
+		}
+	}
+
+	for _, item := range newSchedulesOutput(schedules) {
+		key := item.Name + "/" + item.Command
+		output.Schedules = append(output.Schedules, scheduleStatusOutput{
+			scheduleOutput: item,
+			Declared:       true,
+			Installed:      installed[key],
+		})
+		delete(installed, key)
+	}
+
+	// jobs still installed but no longer declared in the configuration
+	for _, cfg := range configs {
+		key := cfg.ProfileName + "/" + cfg.CommandName
+		if cfg.ConfigFile != configFile || !installed[key] || (profileName != "" && cfg.ProfileName != profileName) {
+			continue
+		}
+		output.Schedules = append(output.Schedules, scheduleStatusOutput{
+			scheduleOutput: scheduleOutput{
+				Name:       cfg.ProfileName,
+				Command:    cfg.CommandName,
+				At:         slices.Clone(cfg.Schedules),
+				Permission: cfg.Permission,
+				Priority:   cfg.Priority,
+				Log:        cfg.Log,
+			},
+			Installed: true,
+		})
+		delete(installed, key)
+	}
+	return output, nil
+}
@@ -14,7 +14,10 @@ import (
 	"github.com/creativeprojects/clog"
 	"github.com/creativeprojects/resticprofile/config"
 	"github.com/creativeprojects/resticprofile/config/jsonschema"
+	"github.com/creativeprojects/resticprofile/crypt"
+	"github.com/creativeprojects/resticprofile/importer"
 	"github.com/creativeprojects/resticprofile/restic"
+	"github.com/creativeprojects/resticprofile/term"
 	"github.com/creativeprojects/resticprofile/util/templates"
 )
 
@@ -40,6 +43,10 @@ func generateCommand(ctx commandContext) (err error) {
 		_, err = ctx.terminal.Println(bashCompletionScript)
 	} else if slices.Contains(args, "--config-reference") {
 		err = generateConfigReference(ctx.terminal, args[slices.Index(args, "--config-reference")+1:])
+	} else if slices.Contains(args, "--encrypt") {
+		err = generateEncryptedFile(ctx.terminal, ctx.flags.ageKeyFile, args[slices.Index(args, "--encrypt")+1:])
+	} else if slices.Contains(args, "--import") {
+		err = generateImportedConfig(ctx.terminal, args[slices.Index(args, "--import")+1:])
 	} else if slices.Contains(args, "--json-schema") {
 		err = generateJsonSchema(ctx.terminal, args[slices.Index(args, "--json-schema")+1:])
 	} else if slices.Contains(args, "--random-key") {
@@ -208,6 +215,99 @@ func generateJsonSchema(output io.Writer, args []string) (err error) {
 	}
 }
 
+// generateEncryptedFile encrypts a configuration file for the recipients (or the public keys of the age identities when no recipient is given)
+func generateEncryptedFile(output io.Writer, keyFile string, args []string) error {
+	kind := crypt.KindAge
+	keys := make([]string, 0)
+	filename := ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--sops":
+			kind = crypt.KindSops
+		case "--recipient":
+			if i+1 < len(args) {
+				i++
+				keys = append(keys, args[i])
+			}
+		default:
+			filename = args[i]
+		}
+	}
+	if filename == "" {
+		return fmt.Errorf("missing configuration file to encrypt")
+	}
+
+	recipients, err := crypt.ParseRecipients(keys)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+	if len(recipients) == 0 {
+		identities, err := crypt.LoadIdentities(keyFile)
+		if err != nil {
+			return fmt.Errorf("no recipient specified: %w", err)
+		}
+		recipients = crypt.RecipientsFromIdentities(identities)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("cannot read configuration file: %w", err)
+	}
+	format := strings.TrimPrefix(filepath.Ext(filename), ".")
+	encrypted, err := crypt.Encrypt(content, format, kind, recipients)
+	if err != nil {
+		return fmt.Errorf("cannot encrypt %q: %w", filename, err)
+	}
+	_, err = output.Write(encrypted)
+	return err
+}
+
+// generateImportedConfig converts an autorestic configuration or a crontab running restic into a configuration file
+func generateImportedConfig(output *term.Terminal, args []string) (err error) {
+	kind, filename, system := "", "", false
+	for _, arg := range args {
+		switch {
+		case arg == "--system":
+			system = true
+		case kind == "":
+			kind = arg
+		default:
+			filename = arg
+		}
+	}
+	if filename == "" {
+		return fmt.Errorf("missing file to import, expected: --import autorestic|crontab <file>")
+	}
+	input, source := io.Reader(os.Stdin), "stdin"
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return fmt.Errorf("cannot read file to import: %w", err)
+		}
+		defer file.Close()
+		input, source = file, filename
+	}
+
+	var imported *importer.Config
+	switch kind {
+	case "autorestic":
+		imported, err = importer.Autorestic(input, source)
+	case "crontab":
+		// system crontab files have a user field
+		system = system || filename == "/etc/crontab" || strings.HasPrefix(filepath.ToSlash(filename), "/etc/cron.d/")
+		imported, err = importer.Crontab(input, source, system)
+	default:
+		return fmt.Errorf("cannot import %q, expected autorestic or crontab", kind)
+	}
+	if err != nil {
+		return err
+	}
+	for _, warning := range imported.Warnings {
+		_, _ = fmt.Fprintf(output.Stderr(), "warning: %s\n", warning)
+	}
+	return imported.Write(output)
+}
+
 // SectionInfoData is used as data for go templates that render profile section references
 type SectionInfoData struct {
 	templates.DefaultData
@@ -36,12 +36,12 @@ func createSchedule(ctx commandContext) error {
 
 	// Step 1: Collect all jobs of all selected profiles
 	for _, profileName := range selectProfilesAndGroups(c, request.profile, args) {
-		scheduler, jobs, _, err := getScheduleJobs(c, profileName)
+		scheduler, jobs, schedulable, err := getScheduleJobs(c, profileName)
 		if err == nil {
 			err = requireScheduleJobs(jobs, profileName)
 
-			// Skip profile with no schedules when "--all" option is set.
-			if err != nil && slices.Contains(args, "--all") {
+			// Skip profile with no schedules when "--all" option is set, or when the profile is not active on this host.
+			if err != nil && (slices.Contains(args, "--all") || isInactiveProfile(schedulable)) {
 				continue
 			}
 		}
@@ -120,6 +120,14 @@ func statusSchedule(ctx commandContext) error {
 	request := ctx.request
 	args := ctx.request.arguments
 
+	format, err := getOutputFormat(args)
+	if err != nil {
+		return err
+	}
+	if format != formatText {
+		return withoutLogs(func() error { return statusScheduleFormatted(ctx, format) })
+	}
+
 	defer c.DisplayConfigurationIssues()
 
 	if slices.Contains(args, "--legacy") {
@@ -165,13 +173,36 @@ func statusSchedule(ctx commandContext) error {
 		profileName = ""
 	}
 	schedulerConfig := schedule.NewSchedulerConfig(ctx.global)
-	err := statusScheduledJobs(schedule.NewHandler(schedulerConfig), ctx.config.GetConfigFile(), profileName)
+	err = statusScheduledJobs(schedule.NewHandler(schedulerConfig), ctx.config.GetConfigFile(), profileName)
 	if err != nil {
 		return retryElevated(err, ctx.flags)
 	}
 	return nil
 }
 
+// statusScheduleFormatted prints the declared schedules and their installed status in a machine-readable format
+func statusScheduleFormatted(ctx commandContext, format string) error {
+	profileName := ctx.request.profile
+	if slices.Contains(ctx.request.arguments, "--all") {
+		profileName = ""
+	}
+	var schedules []*config.Schedule
+	for _, name := range selectProfilesAndGroups(ctx.config, ctx.request.profile, ctx.request.arguments) {
+		_, jobs, _, err := getScheduleJobs(ctx.config, name)
+		if err != nil {
+			return err
+		}
+		schedules = append(schedules, jobs...)
+	}
+
+	handler := schedule.NewHandler(schedule.NewSchedulerConfig(ctx.global))
+	output, err := newStatusOutput(handler, ctx.config.GetConfigFile(), profileName, schedules)
+	if err != nil {
+		return retryElevated(err, ctx.flags)
+	}
+	return writeFormatted(ctx.terminal, format, output)
+}
+
 // selectProfilesAndGroups returns a list with length >= 1, containing profile and group names that have been selected in flags or extra args.
 // With "--all" set in args, names of all profiles and groups are returned, otherwise profileName is returned as-is.
 func selectProfilesAndGroups(c *config.Config, profileName string, args []string) []string {
@@ -234,7 +265,26 @@ func getProfileScheduleJobs(c *config.Config, profileName string) (*config.Profi
 		return nil, nil, fmt.Errorf("cannot load profile '%s': %w", profileName, err)
 	}
 
-	return profile, slices.Collect(maps.Values(profile.Schedules())), nil
+	// abstract profiles are never scheduled
+	if profile.Abstract {
+		clog.Debugf("skipping schedules of abstract profile '%s'", profileName)
+		return profile, nil, nil
+	}
+	// nothing gets scheduled where the profile (or the section) cannot run
+	if reason := profile.GetActivation().InactiveReason(); reason != "" {
+		clog.Infof("skipping schedules of profile '%s': %s", profileName, reason)
+		return profile, nil, nil
+	}
+	schedules := profile.Schedules()
+	for command := range schedules {
+		if section, ok := config.GetSectionWith[config.Activation](profile, command); ok {
+			if reason := section.GetActivation().InactiveReason(); reason != "" {
+				clog.Infof("skipping %s schedule of profile '%s': %s", command, profileName, reason)
+				delete(schedules, command)
+			}
+		}
+	}
+	return profile, slices.Collect(maps.Values(schedules)), nil
 }
 
 func getGroupScheduleJobs(c *config.Config, profileName string) (*config.Group, []*config.Schedule, error) {
@@ -256,6 +306,12 @@ func requireScheduleJobs(schedules []*config.Schedule, profileName string) error
 	return nil
 }
 
+// isInactiveProfile returns true when schedulable is an abstract profile or a profile that is not active on this host
+func isInactiveProfile(schedulable config.Schedulable) bool {
+	profile, ok := schedulable.(*config.Profile)
+	return ok && (profile.Abstract || profile.GetActivation().InactiveReason() != "")
+}
+
 func getRemovableScheduleJobs(c *config.Config, profileName string) (schedule.SchedulerConfig, []*config.Schedule, error) {
 	scheduler, schedules, schedulable, err := getScheduleJobs(c, profileName)
 	if err != nil {
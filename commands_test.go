@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
 
+	"filippo.io/age"
 	"github.com/creativeprojects/resticprofile/batt"
 	"github.com/creativeprojects/resticprofile/config"
 	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/crypt"
 	"github.com/creativeprojects/resticprofile/schedule"
 	"github.com/creativeprojects/resticprofile/term"
 	"github.com/creativeprojects/resticprofile/util/collect"
@@ -123,6 +127,53 @@ schedule = "daily"
 	}
 }
 
+func TestAbstractProfileSchedules(t *testing.T) {
+	testConfig := `
+version = "2"
+[profiles.base]
+abstract = true
+[profiles.base.check]
+schedule = "daily"
+[profiles.profile]
+inherit = "base"
+`
+	cfg, err := config.Load(bytes.NewBufferString(testConfig), "toml")
+	require.NoError(t, err)
+
+	profile, schedules, err := getProfileScheduleJobs(cfg, "base")
+	assert.NoError(t, err)
+	assert.Empty(t, schedules)
+	assert.True(t, isInactiveProfile(profile))
+
+	profile, schedules, err = getProfileScheduleJobs(cfg, "profile")
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 1)
+	assert.False(t, isInactiveProfile(profile))
+}
+
+func TestGeneratedProfileSchedules(t *testing.T) {
+	testConfig := `
+version = "2"
+[generate-profiles.projects]
+items = ["web", "api"]
+[generate-profiles.projects.profile.backup]
+source = "/srv/${ITEM}"
+schedule = "daily"
+`
+	cfg, err := config.Load(bytes.NewBufferString(testConfig), "toml")
+	require.NoError(t, err)
+
+	assert.Contains(t, selectProfilesAndGroups(cfg, "", []string{"--all"}), "projects-web")
+
+	for _, name := range []string{"projects-web", "projects-api"} {
+		profile, schedules, err := getProfileScheduleJobs(cfg, name)
+		require.NoError(t, err)
+		assert.Equal(t, name, profile.Name)
+		require.Len(t, schedules, 1)
+		assert.Equal(t, name, schedules[0].ScheduleOrigin().Name)
+	}
+}
+
 func TestSelectProfiles(t *testing.T) {
 	testConfig := `
 [global]
@@ -310,6 +361,51 @@ func TestGenerateCommand(t *testing.T) {
 		assert.Equal(t, 684, len(strings.TrimSpace(buffer.String())))
 	})
 
+	t.Run("--encrypt", func(t *testing.T) {
+		identity, err := age.GenerateX25519Identity()
+		require.NoError(t, err)
+		dir := t.TempDir()
+		keyFile := filepath.Join(dir, "keys.txt")
+		require.NoError(t, os.WriteFile(keyFile, []byte(identity.String()+"\n"), 0o600))
+		configFile := filepath.Join(dir, "profiles.yaml")
+		require.NoError(t, os.WriteFile(configFile, []byte("version: 2\nprofiles:\n  default:\n    repository: secret\n"), 0o600))
+
+		for _, kind := range []crypt.Kind{crypt.KindAge, crypt.KindSops} {
+			t.Run(kind.String(), func(t *testing.T) {
+				buffer.Reset()
+				args := []string{"--encrypt", "--recipient", identity.Recipient().String(), configFile}
+				if kind == crypt.KindSops {
+					args = append(args, "--sops")
+				}
+				require.NoError(t, generateCommand(contextWithArguments(args)))
+				assert.NotContains(t, buffer.String(), "secret")
+				assert.Equal(t, kind, crypt.Detect([]byte(buffer.String())))
+
+				decrypted, err := crypt.Decrypt([]byte(buffer.String()), "yaml", []age.Identity{identity})
+				require.NoError(t, err)
+				assert.Contains(t, string(decrypted), "secret")
+			})
+		}
+
+		t.Run("recipient from key file", func(t *testing.T) {
+			buffer.Reset()
+			ctx := contextWithArguments([]string{"--encrypt", configFile})
+			ctx.flags.ageKeyFile = keyFile
+			require.NoError(t, generateCommand(ctx))
+			assert.Equal(t, crypt.KindAge, crypt.Detect([]byte(buffer.String())))
+		})
+
+		t.Run("no recipient", func(t *testing.T) {
+			t.Setenv(crypt.EnvSopsAgeKeyFile, "")
+			t.Setenv(crypt.EnvSopsAgeKey, "")
+			assert.Error(t, generateCommand(contextWithArguments([]string{"--encrypt", configFile})))
+		})
+
+		t.Run("no file", func(t *testing.T) {
+			assert.Error(t, generateCommand(contextWithArguments([]string{"--encrypt", "--recipient", identity.Recipient().String()})))
+		})
+	})
+
 	t.Run("invalid-option", func(t *testing.T) {
 		buffer.Reset()
 		opts := []string{"", "invalid", "--unknown"}
@@ -112,13 +112,18 @@ func (c *Completer) completeFlagSetValue(flag *pflag.Flag, word string) (complet
 	case "name":
 		list = c.listProfileNames()
 
+	case "set":
+		for _, name := range c.listProfileNames() {
+			list = append(list, name+".")
+		}
+
 	case "format":
 		list = []string{"toml", "json", "yaml", "hcl"}
 
 	case "theme":
 		list = []string{"dark", "light", "none"}
 
-	case "config":
+	case "config", "age-key-file":
 		fallthrough
 	case "log":
 		completions = []string{RequestFileCompletion}
@@ -134,15 +139,19 @@ func (c *Completer) listProfileNames() (list []string) {
 	if c.profiles == nil {
 		filename := ""
 		format := ""
+		keyFile := ""
 		if configFlag := c.flags.Lookup("config"); configFlag != nil {
 			filename = configFlag.Value.String()
 		}
 		if formatFlag := c.flags.Lookup("format"); formatFlag != nil {
 			format = formatFlag.Value.String()
 		}
+		if keyFileFlag := c.flags.Lookup("age-key-file"); keyFileFlag != nil {
+			keyFile = keyFileFlag.Value.String()
+		}
 
 		if file, err := filesearch.NewFinder().FindConfigurationFile(filename); err == nil {
-			if conf, err := config.LoadFile(file, format); err == nil {
+			if conf, err := config.LoadFile(file, format, config.WithAgeKeyFile(keyFile)); err == nil {
 				list = append(list, conf.GetProfileNames()...)
 				for name := range conf.GetProfileGroups() {
 					list = append(list, name)
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/creativeprojects/resticprofile/util/maybe"
+)
+
+// Activation provides access to the activation conditions of a profile, a section or a group member
+type Activation interface {
+	GetActivation() *ActivationSection
+}
+
+// ActivationSection contains the conditions to activate a profile, a section or a group member
+type ActivationSection struct {
+	Enabled     maybe.Bool `mapstructure:"enabled" default:"true" description:"Set to false to skip it everywhere"`
+	OnlyOnHosts []string   `mapstructure:"only-on-hosts" examples:"server;laptop-*" description:"Only activate on a host matching one of the names (glob patterns are allowed, matching is case insensitive)"`
+	OnlyOnOS    []string   `mapstructure:"only-on-os" examples:"linux;darwin;windows;freebsd" description:"Only activate on one of the operating systems (GOOS names)"`
+	RunIf       string     `mapstructure:"run-if" description:"Shell command evaluated before running: only activate when the command exits with status 0 (not evaluated when listing or scheduling)"`
+}
+
+func (a *ActivationSection) GetActivation() *ActivationSection { return a }
+
+// InactiveReason returns why the conditions that don't need to run a command (enabled, only-on-hosts and only-on-os)
+// fail on this host. It returns an empty string when these conditions are met.
+func (a *ActivationSection) InactiveReason() string {
+	hostname, _ := os.Hostname()
+	return a.inactiveReason(hostname, runtime.GOOS)
+}
+
+func (a *ActivationSection) inactiveReason(hostname, goos string) string {
+	if a == nil {
+		return ""
+	}
+	if a.Enabled.IsStrictlyFalse() {
+		return "not enabled"
+	}
+	if len(a.OnlyOnHosts) > 0 && !matchHostname(hostname, a.OnlyOnHosts) {
+		return fmt.Sprintf("host %q is not in only-on-hosts %v", hostname, a.OnlyOnHosts)
+	}
+	if len(a.OnlyOnOS) > 0 && !matchName(goos, a.OnlyOnOS) {
+		return fmt.Sprintf("OS %q is not in only-on-os %v", goos, a.OnlyOnOS)
+	}
+	return ""
+}
+
+// InactiveReason returns the first reason why one of the activation sections is not active on this host (see ActivationSection.InactiveReason).
+// It returns an empty string when all the sections are active.
+func InactiveReason(sections []*ActivationSection) string {
+	for _, section := range sections {
+		if reason := section.InactiveReason(); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// matchHostname returns true when the hostname (or the short hostname without domain) matches one of the patterns
+func matchHostname(hostname string, patterns []string) bool {
+	if matchName(hostname, patterns) {
+		return true
+	}
+	if short, _, found := strings.Cut(hostname, "."); found {
+		return matchName(short, patterns)
+	}
+	return false
+}
+
+// matchName returns true when name matches one of the (case insensitive) glob patterns
+func matchName(name string, patterns []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if matched, err := path.Match(pattern, name); matched || (err != nil && pattern == name) {
+			return true
+		}
+	}
+	return false
+}
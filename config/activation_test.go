@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/creativeprojects/resticprofile/util/maybe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivationInactiveReason(t *testing.T) {
+	fixtures := []struct {
+		section  *ActivationSection
+		hostname string
+		goos     string
+		reason   string
+	}{
+		{section: nil},
+		{section: &ActivationSection{}},
+		{section: &ActivationSection{Enabled: maybe.True()}},
+		{section: &ActivationSection{Enabled: maybe.False()}, reason: "not enabled"},
+		{section: &ActivationSection{OnlyOnHosts: []string{"server"}}, hostname: "server"},
+		{section: &ActivationSection{OnlyOnHosts: []string{"server"}}, hostname: "SERVER"},
+		{section: &ActivationSection{OnlyOnHosts: []string{"server"}}, hostname: "server.example.com"},
+		{section: &ActivationSection{OnlyOnHosts: []string{"server.example.com"}}, hostname: "server.example.com"},
+		{section: &ActivationSection{OnlyOnHosts: []string{"laptop-*", "server"}}, hostname: "laptop-01"},
+		{section: &ActivationSection{OnlyOnHosts: []string{"[invalid"}}, hostname: "[invalid"},
+		{section: &ActivationSection{OnlyOnHosts: []string{"server"}}, hostname: "laptop", reason: `host "laptop" is not in only-on-hosts [server]`},
+		{section: &ActivationSection{OnlyOnHosts: []string{"server.example.com"}}, hostname: "server", reason: `host "server" is not in only-on-hosts [server.example.com]`},
+		{section: &ActivationSection{OnlyOnOS: []string{"linux", "darwin"}}, goos: "darwin"},
+		{section: &ActivationSection{OnlyOnOS: []string{"linux", "darwin"}}, goos: "windows", reason: `OS "windows" is not in only-on-os [linux darwin]`},
+		{section: &ActivationSection{OnlyOnOS: []string{"Windows"}}, goos: "windows"},
+		{section: &ActivationSection{RunIf: "false"}},
+	}
+	for _, fixture := range fixtures {
+		assert.Equal(t, fixture.reason, fixture.section.inactiveReason(fixture.hostname, fixture.goos), "%+v", fixture)
+	}
+}
+
+func TestActivationConditions(t *testing.T) {
+	testConfig := `
+version = 2
+
+[profiles.disabled]
+enabled = false
+
+[profiles.profile]
+only-on-os = ["plan9"]
+[profiles.profile.backup]
+enabled = false
+run-if = "test -d /mnt/backup"
+[profiles.profile.check]
+only-on-hosts = ["server", "nas-*"]
+[profiles.profile.cat]
+enabled = false
+
+[groups.group]
+profiles = ["disabled", "profile"]
+[groups.group.conditions.profile]
+only-on-hosts = ["server"]
+`
+	c, err := Load(bytes.NewBufferString(testConfig), FormatTOML)
+	require.NoError(t, err)
+
+	profile, err := c.GetProfile("disabled")
+	require.NoError(t, err)
+	assert.Equal(t, "not enabled", InactiveReason(profile.GetActivationSections("backup")))
+
+	profile, err = c.GetProfile("profile")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plan9"}, profile.OnlyOnOS)
+
+	sections := profile.GetActivationSections("backup")
+	require.Len(t, sections, 2)
+	assert.True(t, sections[1].Enabled.IsStrictlyFalse())
+	assert.Equal(t, "test -d /mnt/backup", sections[1].RunIf)
+	assert.NotContains(t, profile.Backup.OtherFlags, "enabled")
+	assert.NotContains(t, profile.Backup.OtherFlags, "run-if")
+
+	sections = profile.GetActivationSections("check")
+	require.Len(t, sections, 2)
+	assert.Equal(t, []string{"server", "nas-*"}, sections[1].OnlyOnHosts)
+
+	sections = profile.GetActivationSections("cat")
+	require.Len(t, sections, 2)
+	assert.True(t, sections[1].Enabled.IsStrictlyFalse())
+
+	assert.Len(t, profile.GetActivationSections("prune"), 1)
+
+	// restic flags don't contain activation conditions
+	assert.NotContains(t, profile.GetCommandFlags("backup").ToMap(), "enabled")
+	assert.NotContains(t, profile.GetCommandFlags("backup").ToMap(), "run-if")
+
+	group, err := c.GetProfileGroup("group")
+	require.NoError(t, err)
+	assert.Nil(t, group.GetMemberActivation("disabled"))
+	assert.Equal(t, []string{"server"}, group.GetMemberActivation("profile").OnlyOnHosts)
+}
@@ -0,0 +1,55 @@
+package config
+
+import "github.com/creativeprojects/resticprofile/constants"
+
+// AnomalySection configures the detection of anomalies in the summary of a backup (e.g. a mass encryption or
+// an accidental deletion), by comparing it with the history of the previous backups of the profile
+type AnomalySection struct {
+	History         int                     `mapstructure:"history" default:"10" range:"[1:]" description:"Number of previous backup summaries kept in the history of the profile"`
+	MinHistory      int                     `mapstructure:"min-history" default:"3" range:"[1:]" description:"Minimum number of backup summaries in the history before comparing with the median"`
+	HistoryFile     string                  `mapstructure:"history-file" description:"Path to the history file of the profile. Defaults to a file in the user state directory"`
+	MaxChangedRatio float64                 `mapstructure:"max-changed-ratio" range:"[0:]" examples:"5;10" description:"Anomaly when the number of new and changed files is more than this ratio to the median of the history (0 to disable)"`
+	MaxAddedRatio   float64                 `mapstructure:"max-added-ratio" range:"[0:]" examples:"5;10" description:"Anomaly when the size added to the repository is more than this ratio to the median of the history (0 to disable)"`
+	MaxChanged      int                     `mapstructure:"max-changed" range:"[0:]" examples:"1000;10000" description:"Anomaly when the number of new and changed files is more than this value (0 to disable)"`
+	MaxAdded        uint64                  `mapstructure:"max-added" examples:"1024;10240" description:"Anomaly when the size added to the repository (in MB) is more than this value (0 to disable)"`
+	MaxFilesDrop    int                     `mapstructure:"max-files-drop" range:"[0:100]" examples:"10;20" description:"Anomaly when the total number of files dropped by more than this percentage since the previous backup (0 to disable)"`
+	BlockRetention  bool                    `mapstructure:"block-retention" description:"Do not apply the retention (nor a \"forget\" step) after a backup with an anomaly, to keep the older snapshots"`
+	RunOnAnomaly    []string                `mapstructure:"run-on-anomaly" description:"Run shell command(s) after a backup with an anomaly"`
+	SendOnAnomaly   []SendMonitoringSection `mapstructure:"send-on-anomaly" description:"Send HTTP request(s) after a backup with an anomaly"`
+}
+
+func (a *AnomalySection) setRootPath(_ *Profile, rootPath string) {
+	a.HistoryFile = fixPath(a.HistoryFile, expandEnv, expandUserHome, absolutePrefix(rootPath))
+	for index := range a.SendOnAnomaly {
+		a.SendOnAnomaly[index].BodyTemplate = fixPath(a.SendOnAnomaly[index].BodyTemplate, expandEnv, expandUserHome, absolutePrefix(rootPath))
+	}
+}
+
+// IsEmpty returns true when no anomaly threshold is configured
+func (a *AnomalySection) IsEmpty() bool {
+	return a == nil || (a.MaxChangedRatio <= 0 && a.MaxAddedRatio <= 0 && a.MaxChanged <= 0 && a.MaxAdded == 0 && a.MaxFilesDrop <= 0)
+}
+
+// GetHistory returns the number of backup summaries kept in the history
+func (a *AnomalySection) GetHistory() int {
+	if a.History < 1 {
+		return constants.DefaultAnomalyHistory
+	}
+	return a.History
+}
+
+// GetMinHistory returns the minimum number of backup summaries needed to compare with the median
+func (a *AnomalySection) GetMinHistory() int {
+	if a.MinHistory < 1 {
+		return constants.DefaultAnomalyMinHistory
+	}
+	return a.MinHistory
+}
+
+// GetAnomaly returns the anomaly detection of the backup, or nil when no anomaly threshold is configured
+func (s *BackupSection) GetAnomaly() *AnomalySection {
+	if s == nil || s.Anomaly.IsEmpty() {
+		return nil
+	}
+	return s.Anomaly
+}
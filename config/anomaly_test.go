@@ -0,0 +1,64 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnomalyFromConfig(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  backup:
+    source: /home
+    anomaly:
+      history: 20
+      history-file: history.json
+      max-changed-ratio: 5.5
+      max-added: 1024
+      max-files-drop: 10
+      block-retention: true
+      run-on-anomaly: "echo anomaly"
+      send-on-anomaly:
+        - url: http://localhost/anomaly
+          body-template: template.txt
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "profile")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	profile.SetRootPath("/root")
+
+	anomaly := profile.Backup.GetAnomaly()
+	require.NotNil(t, anomaly)
+	assert.Equal(t, 20, anomaly.GetHistory())
+	assert.Equal(t, constants.DefaultAnomalyMinHistory, anomaly.GetMinHistory())
+	assert.Equal(t, 5.5, anomaly.MaxChangedRatio)
+	assert.Equal(t, uint64(1024), anomaly.MaxAdded)
+	assert.Equal(t, 10, anomaly.MaxFilesDrop)
+	assert.True(t, anomaly.BlockRetention)
+	assert.Equal(t, []string{"echo anomaly"}, anomaly.RunOnAnomaly)
+	require.Len(t, anomaly.SendOnAnomaly, 1)
+	assert.Equal(t, "http://localhost/anomaly", anomaly.SendOnAnomaly[0].URL.Value())
+	assert.Equal(t, filepath.FromSlash("/root/template.txt"), anomaly.SendOnAnomaly[0].BodyTemplate)
+	assert.Equal(t, filepath.FromSlash("/root/history.json"), anomaly.HistoryFile)
+
+	// the anomaly detection is not a flag of the backup command
+	flags := profile.GetCommandFlags(constants.CommandBackup).ToMap()
+	assert.NotContains(t, flags, "anomaly")
+}
+
+func TestAnomalyIsEmpty(t *testing.T) {
+	var backup *BackupSection
+	assert.Nil(t, backup.GetAnomaly())
+	assert.Nil(t, (&BackupSection{}).GetAnomaly())
+	assert.Nil(t, (&BackupSection{Anomaly: &AnomalySection{BlockRetention: true}}).GetAnomaly())
+	assert.NotNil(t, (&BackupSection{Anomaly: &AnomalySection{MaxFilesDrop: 10}}).GetAnomaly())
+
+	anomaly := &AnomalySection{}
+	assert.Equal(t, constants.DefaultAnomalyHistory, anomaly.GetHistory())
+	assert.Equal(t, constants.DefaultAnomalyMinHistory, anomaly.GetMinHistory())
+}
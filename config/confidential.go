@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"regexp"
 
+	"github.com/creativeprojects/resticprofile/mask"
 	"github.com/creativeprojects/resticprofile/shell"
 )
 
@@ -58,6 +59,18 @@ func (c *ConfidentialValue) hideValue() {
 		return
 	}
 	c.public = ConfidentialReplacement
+	mask.AddValues(c.confidential)
+}
+
+// hideURL hides the password and the confidential query parameters of a URL in the public representation
+func (c *ConfidentialValue) hideURL() {
+	if c.IsConfidential() {
+		return
+	}
+	if secrets := mask.URLSecrets(c.confidential); len(secrets) > 0 {
+		c.public = mask.URL(c.confidential)
+		mask.AddValues(secrets...)
+	}
 }
 
 // hideSubmatches hides all occurrences of submatches in the public representation
@@ -105,14 +118,6 @@ func confidentialValueDecoder() func(from reflect.Type, to reflect.Type, data an
 	}
 }
 
-// See https://restic.readthedocs.io/en/latest/030_preparing_a_new_repo.html
-var (
-	httpHeaderNames     = regexp.MustCompile("(?i)^(Authorization)$")
-	urlConfidentialPart = regexp.MustCompile("[:/][^:/@]+?:([^:@]+?)@[^:/@]+?") // user:pass@host
-	urlEnvKeys          = regexp.MustCompile("(?i)^.+(_AUTH|_URL)$")
-	hiddenEnvKeys       = regexp.MustCompile("(?i)^(.+_KEY|.+_TOKEN|.*PASSWORD.*|.*SECRET.*)$")
-)
-
 // ProcessConfidentialValues hides confidential parts inside the specified Profile.
 func ProcessConfidentialValues(profile *Profile) {
 	if profile == nil {
@@ -120,7 +125,7 @@ func ProcessConfidentialValues(profile *Profile) {
 	}
 
 	// Handle the repo URL
-	profile.Repository.hideSubmatches(urlConfidentialPart)
+	profile.Repository.hideURL()
 
 	// Handle env variables
 	ProcessConfidentialEnvironment(profile.Environment)
@@ -130,10 +135,10 @@ func ProcessConfidentialValues(profile *Profile) {
 		for _, monitoringSections := range sections.GetSendMonitoring().getAllSendMonitoringSections() {
 			for index, section := range monitoringSections {
 				// URL
-				monitoringSections[index].URL.hideSubmatches(urlConfidentialPart)
+				monitoringSections[index].URL.hideURL()
 				// Headers
 				for hi, header := range section.Headers {
-					if httpHeaderNames.MatchString(header.Name) {
+					if mask.IsConfidentialHeader(header.Name) {
 						section.Headers[hi].Value.hideValue()
 					}
 				}
@@ -145,11 +150,11 @@ func ProcessConfidentialValues(profile *Profile) {
 // ProcessConfidentialEnvironment hides confidential parts inside the specified environment.
 func ProcessConfidentialEnvironment(env map[string]ConfidentialValue) {
 	for name, value := range env {
-		if hiddenEnvKeys.MatchString(name) {
+		if mask.IsConfidentialEnv(name) {
 			value.hideValue()
 			env[name] = value
-		} else if urlEnvKeys.MatchString(name) {
-			value.hideSubmatches(urlConfidentialPart)
+		} else if mask.IsURLEnv(name) {
+			value.hideURL()
 			env[name] = value
 		}
 	}
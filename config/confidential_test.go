@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/mask"
 	"github.com/creativeprojects/resticprofile/platform"
 	"github.com/creativeprojects/resticprofile/shell"
 	"github.com/creativeprojects/resticprofile/util/templates"
@@ -113,6 +114,7 @@ func TestConfidentialURLs(t *testing.T) {
 		"azure:foo:/":                                          "-",
 		"gs:foo:/":                                             "-",
 		"rclone:b2prod:yggdrasil/foo/bar/baz":                  "-",
+		"rest:https://host:8000/?token=abcd&name=repo":         fmt.Sprintf("rest:https://host:8000/?token=%s&name=repo", ConfidentialReplacement),
 	}
 
 	for url, expected := range urls {
@@ -207,6 +209,40 @@ func TestConfidentialEnvironment(t *testing.T) {
 	}
 }
 
+func TestConfidentialCustomRules(t *testing.T) {
+	t.Cleanup(mask.Reset)
+
+	testConfig := `
+
+[global]
+confidential-env = ["*_VAULT"]
+confidential-headers = ["X-Vault"]
+confidential-query-parameters = ["vault"]
+
+[profile]
+repository = "rest:https://host/?vault=abcd"
+
+[profile.env]
+MY_VAULT = "abcd"
+
+[[profile.backup.send-after]]
+url = "https://host/"
+headers = [{ name = "X-Vault", value = "abcd" }]
+`
+	c, err := Load(bytes.NewBufferString(testConfig), "toml")
+	require.NoError(t, err)
+	global, err := c.GetGlobalSection()
+	require.NoError(t, err)
+	mask.Configure(global.GetMaskRules())
+
+	profile, err := c.GetProfile("profile")
+	require.NoError(t, err)
+
+	assert.Equal(t, "rest:https://host/?vault="+ConfidentialReplacement, profile.Repository.String())
+	assert.Equal(t, ConfidentialReplacement, profile.Environment["MY_VAULT"].String())
+	assert.Equal(t, ConfidentialReplacement, profile.Backup.SendAfter[0].Headers[0].Value.String())
+}
+
 func TestShowConfigHidesConfidentialValues(t *testing.T) {
 	t.Parallel()
 
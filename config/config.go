@@ -14,30 +14,44 @@ import (
 	"sync"
 	"text/template"
 
+	"filippo.io/age"
 	"github.com/creativeprojects/clog"
 	"github.com/creativeprojects/resticprofile/constants"
 	"github.com/creativeprojects/resticprofile/filesearch"
+	"github.com/creativeprojects/resticprofile/restic"
 	"github.com/creativeprojects/resticprofile/util"
 	"github.com/creativeprojects/resticprofile/util/maybe"
 	"github.com/creativeprojects/resticprofile/util/templates"
 	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 )
 
 // Config wraps up a viper configuration object
 type Config struct {
-	keyDelim        string
-	format          string
-	configFile      string
-	includeFiles    []string
-	envFiles        []string
-	lastProfileKey  string
-	viper           *viper.Viper
-	mixinUses       []map[string][]*mixinUse
-	mixins          map[string]*mixin
-	sourceTemplates *template.Template
-	version         Version
-	issues          struct {
+	keyDelim              string
+	format                string
+	configFile            string
+	mainFile              string   // file loaded as the main configuration (first fragment when configFile is a drop-in directory)
+	dropInFiles           []string // configuration fragments from the drop-in directory
+	includeFiles          []string
+	envFiles              []string
+	ageKeyFile            string
+	identities            []age.Identity
+	settings              []string // profile settings from the command line
+	vars                  []string // template variables from the command line
+	overrides             []commandLineOverride
+	templateVars          map[string]string
+	generatorCommandLines map[string][]string // output of the commands of profile generators
+	lastProfileKey        string
+	resticVersion         string // restic version for the semverCompare template function
+	resticVersionAssumed  bool   // semverCompare used the latest known version, as resticVersion was not set
+	viper                 *viper.Viper
+	mixinUses             []map[string][]*mixinUse
+	mixins                map[string]*mixin
+	sourceTemplates       *template.Template
+	version               Version
+	issues                struct {
 		changedPaths  map[string][]string // 'path' items that had been changed to absolute paths
 		failedSection map[string]error    // profile sections that failed to get parsed or resolved
 	}
@@ -45,6 +59,10 @@ type Config struct {
 		groups map[string]*Group
 		global *Global
 	}
+	sources struct {
+		profiles   map[string][]string // files declaring each profile
+		generators map[string]string   // file declaring each profile generator
+	}
 }
 
 var (
@@ -68,56 +86,90 @@ func newConfig(format string) *Config {
 	}
 }
 
+// formatFromExtension returns the format of the file from its extension (ignoring the extension of an encrypted file)
 func formatFromExtension(configFile string) string {
-	return strings.TrimPrefix(filepath.Ext(configFile), ".")
+	return strings.TrimPrefix(filepath.Ext(strings.TrimSuffix(configFile, EncryptedExtension)), ".")
 }
 
 // LoadFile loads configuration from file
 // Leave format blank for auto-detection from the file extension
-func LoadFile(configFile, format string) (config *Config, err error) {
+func LoadFile(configFile, format string, options ...func(cfg *Config)) (config *Config, err error) {
+	finder := filesearch.NewFinder()
+
+	// Find drop-in fragments (a drop-in directory can also be used in place of the configuration file)
+	mainFile := configFile
+	dropIns, err := finder.FindConfigurationDropIns(configFile)
+	if err != nil {
+		return nil, err
+	}
+	if finder.IsDropInDirectory(configFile) {
+		if len(dropIns) == 0 {
+			return nil, fmt.Errorf("no configuration file found in drop-in directory %q", configFile)
+		}
+		mainFile = dropIns[0]
+	}
+
 	if format == "" {
-		format = formatFromExtension(configFile)
+		format = formatFromExtension(mainFile)
 	}
 
 	config = newConfig(format)
 	config.configFile = configFile
+	config.mainFile = mainFile
+	config.dropInFiles = dropIns
+	for _, option := range options {
+		option(config)
+	}
+	if err = config.parseTemplateVars(); err != nil {
+		return
+	}
 
-	readAndAdd := func(configFile string, replace bool) error {
+	readAndAdd := func(configFile, name, format string, replace bool) error {
 		clog.Debugf("loading: %s", configFile)
-		file, fileErr := os.Open(configFile)
+		content, fileErr := os.ReadFile(configFile)
 		if fileErr != nil {
 			return fmt.Errorf("cannot open configuration file for reading: %w", fileErr)
 		}
-		defer file.Close()
+		// decrypted content is only kept in memory
+		content, fileErr = config.decrypt(configFile, format, content)
+		if fileErr != nil {
+			return fileErr
+		}
 
-		return config.addTemplate(file, configFile, replace)
+		return config.addTemplate(bytes.NewReader(content), name, replace)
+	}
+
+	addInclude := func(include string) (err error) {
+		if include == mainFile || slices.Contains(config.includeFiles, include) {
+			return
+		}
+		format := formatFromExtension(include)
+
+		switch {
+		case format == FormatHCL && config.format != FormatHCL:
+			err = fmt.Errorf("hcl format (%s) cannot be used in includes from %s: %s", include, config.format, config.configFile)
+		case config.format == FormatHCL && format != FormatHCL:
+			err = fmt.Errorf("%s is in hcl format, includes must use the same format: cannot load %s", config.configFile, include)
+		default:
+			err = readAndAdd(include, include, format, false)
+			if err == nil {
+				config.includeFiles = append(config.includeFiles, include)
+			}
+		}
+		return
 	}
 
 	// Load config file
-	err = readAndAdd(configFile, true)
+	err = readAndAdd(mainFile, configFile, config.format, true)
 	if err != nil {
 		return
 	}
 
-	// Load includes (if any).
+	// Load includes (if any), then drop-in fragments
 	var includes []string
-	if includes, err = filesearch.NewFinder().FindConfigurationIncludes(configFile, config.getIncludes()); err == nil {
-		for _, include := range includes {
-			format := formatFromExtension(include)
-
-			switch {
-			case format == FormatHCL && config.format != FormatHCL:
-				err = fmt.Errorf("hcl format (%s) cannot be used in includes from %s: %s", include, config.format, config.configFile)
-			case config.format == FormatHCL && format != FormatHCL:
-				err = fmt.Errorf("%s is in hcl format, includes must use the same format: cannot load %s", config.configFile, include)
-			default:
-				err = readAndAdd(include, false)
-				if err == nil {
-					config.includeFiles = append(config.includeFiles, include)
-				}
-			}
-
-			if err != nil {
+	if includes, err = finder.FindConfigurationIncludes(configFile, config.getIncludes()); err == nil {
+		for _, include := range append(includes, dropIns...) {
+			if err = addInclude(include); err != nil {
 				break
 			}
 		}
@@ -125,6 +177,9 @@ func LoadFile(configFile, format string) (config *Config, err error) {
 	if err == nil && config.includeFiles != nil {
 		err = config.loadTemplates()
 	}
+	if err == nil {
+		err = config.parseOverrides()
+	}
 
 	return
 }
@@ -136,7 +191,13 @@ func Load(input io.Reader, format string, options ...func(cfg *Config)) (config
 	for _, option := range options {
 		option(config)
 	}
+	if err = config.parseTemplateVars(); err != nil {
+		return
+	}
 	err = config.addTemplate(input, config.configFile, true)
+	if err == nil {
+		err = config.parseOverrides()
+	}
 	return
 }
 
@@ -161,6 +222,47 @@ func (c *Config) templateName(name string) string {
 	return "__config:" + name // prefixing name to avoid clash with named template defines
 }
 
+// templateError removes the template name prefix from err so that it points to the line in the configuration file
+func (c *Config) templateError(err error) error {
+	if message := err.Error(); strings.Contains(message, c.templateName("")) {
+		return errors.New(strings.ReplaceAll(message, c.templateName(""), ""))
+	}
+	return err
+}
+
+// SetResticVersion sets the restic version used by the template function semverCompare.
+// The templates are evaluated again when they compared with a different version assumed before
+func (c *Config) SetResticVersion(version string) error {
+	assumed := c.resticVersionAssumed
+	previous := c.getResticVersion()
+	c.resticVersion = version
+	c.resticVersionAssumed = false
+	if !assumed || previous == c.getResticVersion() || c.sourceTemplates == nil {
+		return nil
+	}
+	if err := c.loadTemplates(); err != nil {
+		return err
+	}
+	return c.parseOverrides()
+}
+
+// ResticVersionAssumed returns true when the templates compared with the latest known restic version,
+// because the restic version was not set with SetResticVersion
+func (c *Config) ResticVersionAssumed() bool {
+	return c.resticVersionAssumed
+}
+
+// getResticVersion returns the restic version set with SetResticVersion, or the latest known version when not set
+func (c *Config) getResticVersion() string {
+	if c.resticVersion == "" {
+		if versions := restic.KnownVersions(); len(versions) > 0 {
+			c.resticVersionAssumed = true
+			return versions[0]
+		}
+	}
+	return c.resticVersion
+}
+
 func (c *Config) addTemplate(input io.Reader, name string, replace bool) error {
 	if rs, ok := input.(io.ReadSeeker); ok {
 		input = util.NewUTF8Reader(rs)
@@ -175,7 +277,8 @@ func (c *Config) addTemplate(input io.Reader, name string, replace bool) error {
 	var source *template.Template
 	if c.sourceTemplates == nil || replace {
 		envFile := templates.EnvFileFunc(func() (string, func(string)) { return c.lastProfileKey, c.addEnvFile })
-		source = templates.New(c.templateName(name), envFile)
+		semverCompare := templates.SemverCompareFunc(c.getResticVersion)
+		source = templates.New(c.templateName(name), envFile, semverCompare)
 		c.sourceTemplates = source
 	} else {
 		source = c.sourceTemplates.New(c.templateName(name))
@@ -183,7 +286,7 @@ func (c *Config) addTemplate(input io.Reader, name string, replace bool) error {
 
 	_, err = source.Parse(inputString.String())
 	if err != nil {
-		return fmt.Errorf("cannot compile %w", err)
+		return fmt.Errorf("cannot compile %w", c.templateError(err))
 	}
 
 	if replace {
@@ -192,8 +295,8 @@ func (c *Config) addTemplate(input io.Reader, name string, replace bool) error {
 	return err
 }
 
-// load configuration from an io.Reader
-func (c *Config) load(input io.Reader, format string, replace bool) (err error) {
+// load configuration from an io.Reader. source is the name of the file (if any) the configuration comes from
+func (c *Config) load(input io.Reader, source, format string, replace bool) (err error) {
 	if format == "conf" { // A .conf file is TOML format
 		format = "toml"
 	}
@@ -229,6 +332,8 @@ func (c *Config) load(input io.Reader, format string, replace bool) (err error)
 
 	if previousVersion != c.GetVersion() && previousVersion > VersionUnknown {
 		err = errors.New("cannot include different versions of the configuration file, all files must use the same version")
+	} else if source != "" {
+		err = c.addSources(source, vp)
 	}
 	return
 }
@@ -291,36 +396,43 @@ func (c *Config) reloadTemplates(data TemplateData) error {
 		return errors.New("no available template to execute, please load it first")
 	}
 
+	maps.Copy(data.Vars, c.templateVars)
+
+	c.sources.profiles = nil
+	c.sources.generators = nil
+
 	buffer := &bytes.Buffer{}
-	executeTemplate := func(name, format string, replace bool) error {
+	executeTemplate := func(name, source, format string, replace bool) error {
 		buffer.Reset()
 		err := c.sourceTemplates.ExecuteTemplate(buffer, c.templateName(name), data)
 		if err != nil {
-			return fmt.Errorf("cannot execute %w", err)
+			return fmt.Errorf("cannot execute %w", c.templateError(err))
 		}
 
 		traceConfig(data.Profile.Name, name, replace, buffer)
-		return c.load(buffer, format, replace)
+		return c.load(buffer, source, format, replace)
 	}
 
 	// Load main config file
 	var err error
-	err = executeTemplate(c.configFile, c.format, true)
+	err = executeTemplate(c.configFile, c.mainFile, c.format, true)
 
 	// Load includes
 	if err == nil && c.includeFiles != nil {
 		for _, file := range c.includeFiles {
-			err = executeTemplate(file, formatFromExtension(file), false)
+			err = executeTemplate(file, file, formatFromExtension(file), false)
 			if err != nil {
 				break
 			}
 		}
 	}
 
-	// Load mixins and apply outside of profiles
+	// Load mixins, generate profiles and apply mixins outside of profiles
 	if err == nil && c.GetVersion() >= Version02 {
 		c.mixins = parseMixins(c.viper)
-		err = c.applyNonProfileMixins()
+		if err = c.generateProfiles(); err == nil {
+			err = c.applyNonProfileMixins()
+		}
 	}
 
 	// clear cached items
@@ -607,7 +719,18 @@ func (c *Config) GetProfile(profileKey string) (profile *Profile, err error) {
 		return
 	}
 
+	// Apply settings from the command line
+	if err = c.applyOverrides(profile); err != nil {
+		profile = nil
+		return
+	}
+
 	c.postProcessProfile(profile)
+
+	if err = profile.validate(); err != nil {
+		err = fmt.Errorf("invalid configuration in profile '%s': %w", profileKey, err)
+		profile = nil
+	}
 	return
 }
 
@@ -630,6 +753,12 @@ func (c *Config) postProcessProfile(profile *Profile) {
 }
 
 func (c *Config) applyProfileInheritanceAndMixins(profileName string) (err error) {
+	return c.applyProfileInheritance(profileName, nil)
+}
+
+// applyProfileInheritance merges the parent profiles into the profile, then applies the mixins.
+// chain contains the names of the derived profiles, to detect inheritance cycles
+func (c *Config) applyProfileInheritance(profileName string, chain []string) (err error) {
 	c.requireMinVersion(Version02)
 
 	profilePath := c.getProfilePath(profileName)
@@ -638,53 +767,112 @@ func (c *Config) applyProfileInheritanceAndMixins(profileName string) (err error
 		return
 	}
 
-	if inherit := c.viper.GetString(c.flatKey(profilePath, constants.SectionConfigurationInherit)); len(inherit) > 0 {
+	if parents := c.getInheritedProfiles(profilePath); len(parents) > 0 {
+		chain = append(chain, profileName)
+		// create merged profile for: parent 1 > parent 2 > ... > derived
+		mergedProfile := viper.NewWithOptions(viper.KeyDelimiter(c.keyDelim))
 
-		inheritPath := c.getProfilePath(inherit)
-		if !c.IsSet(inheritPath) {
-			err = ErrNotFound
-		} else {
-			err = c.applyProfileInheritanceAndMixins(inherit) // recursive inheritance, the deepest first
-		}
+		for _, inherit := range parents {
+			if err = checkInheritanceCycle(chain, inherit); err != nil {
+				return
+			}
 
-		if err == nil {
-			// create merged profile for: parent > derived
-			mergedProfile := viper.NewWithOptions(viper.KeyDelimiter(c.keyDelim))
+			inheritPath := c.getProfilePath(inherit)
+			if !c.IsSet(inheritPath) {
+				err = ErrNotFound
+			} else {
+				err = c.applyProfileInheritance(inherit, chain) // recursive inheritance, the deepest first
+			}
+			if errors.Is(err, ErrNotFound) {
+				err = fmt.Errorf("error in profile '%s': parent profile '%s' not found", profileName, inherit)
+			}
+			if err != nil {
+				return
+			}
 
-			// init with parent (excluding some fields that must never be inherited)
-			parent := c.viper.GetStringMap(inheritPath)
+			// merge parent (excluding some fields that must never be inherited)
+			parent := maps.Clone(c.viper.GetStringMap(inheritPath))
 			delete(parent, constants.SectionConfigurationDescription)
 			delete(parent, constants.SectionConfigurationMixinUse)
 			delete(parent, constants.SectionConfigurationInherit)
+			delete(parent, constants.SectionConfigurationAbstract)
+			// a parent can prepend or append to the lists of the previous parents
+			revolveAppendToListKeys(mergedProfile, parent)
 
-			if err = mergedProfile.MergeConfigMap(parent); err == nil {
-				// Merge derived onto parent (removing "inherit" instruction to ensure it is done only once)
-				derived := c.viper.GetStringMap(profilePath)
-				derived[constants.SectionConfigurationInherit] = ""
-				revolveAppendToListKeys(mergedProfile, derived)
-
-				err = mergedProfile.MergeConfigMap(derived)
-			}
-			if err != nil {
+			if err = mergedProfile.MergeConfigMap(parent); err != nil {
 				return
 			}
+		}
 
-			// apply merged profile to config
-			err = mergeConfigMap(c.viper, profilePath, c.keyDelim, mergedProfile.AllSettings())
+		// Merge derived onto parents (removing "inherit" instruction to ensure it is done only once)
+		derived := c.viper.GetStringMap(profilePath)
+		derived[constants.SectionConfigurationInherit] = []string{}
+		revolveAppendToListKeys(mergedProfile, derived)
+
+		if err = mergedProfile.MergeConfigMap(derived); err != nil {
+			return
 		}
 
-		if errors.Is(err, ErrNotFound) {
-			err = fmt.Errorf("error in profile '%s': parent profile '%s' not found", profileName, inherit)
+		// apply merged profile to config
+		if err = mergeConfigMap(c.viper, profilePath, c.keyDelim, mergedProfile.AllSettings()); err != nil {
+			return
 		}
 	}
 
 	// apply mixins
-	if err == nil {
-		err = c.applyMixinsToProfile(profileName)
+	err = c.applyMixinsToProfile(profileName)
+	return
+}
+
+// getInheritedProfiles returns the names of the parent profiles (in order) declared in profilePath
+func (c *Config) getInheritedProfiles(profilePath string) (parents []string) {
+	var values []string
+	switch value := c.viper.Get(c.flatKey(profilePath, constants.SectionConfigurationInherit)).(type) {
+	case nil:
+	case string:
+		values = []string{value}
+	default:
+		values = cast.ToStringSlice(value)
+	}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			parents = append(parents, value)
+		}
 	}
 	return
 }
 
+// getCompositeCommandNames returns the sorted names of the composite commands declared in the profile or in its parents.
+// The profile is not resolved: loading the configuration of the profile is left to GetProfile
+func (c *Config) getCompositeCommandNames(profileName string) (names []string) {
+	visited := make(map[string]bool)
+	var collect func(name string)
+	collect = func(name string) {
+		name = strings.ToLower(name)
+		if visited[name] || !c.HasProfile(name) {
+			return
+		}
+		visited[name] = true
+		profilePath := c.getProfilePath(name)
+		commands := cast.ToStringMap(c.viper.Get(c.flatKey(profilePath, constants.SectionConfigurationCommands)))
+		names = append(names, slices.Collect(maps.Keys(commands))...)
+		for _, parent := range c.getInheritedProfiles(profilePath) {
+			collect(parent)
+		}
+	}
+	collect(profileName)
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// checkInheritanceCycle returns an error when inheriting from parent would create a cycle in chain
+func checkInheritanceCycle(chain []string, parent string) error {
+	if slices.ContainsFunc(chain, func(name string) bool { return strings.EqualFold(name, parent) }) {
+		return fmt.Errorf("error in profile '%s': inheritance cycle detected: %s", chain[len(chain)-1], strings.Join(append(slices.Clone(chain), parent), " -> "))
+	}
+	return nil
+}
+
 // getProfile from configuration. If the profile is not found, it returns errNotFound
 func (c *Config) getProfile(profileKey string) (profile *Profile, err error) {
 	if c.GetVersion() <= Version01 {
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/platform"
+	"github.com/spf13/viper"
+)
+
+const defaultGeneratedProfileName = "${GENERATOR}-${ITEM_NAME}"
+
+// profileGenerator describes a parsed profile generator definition (generate-profiles: ...)
+type profileGenerator struct {
+	Description string         `mapstructure:"description" description:"Describes the group of generated profiles"`
+	Items       []string       `mapstructure:"items" description:"Literal list of items: one profile is generated per item"`
+	Glob        string         `mapstructure:"glob" examples:"/srv/projects/*" description:"Glob pattern of directories: one profile is generated per matching directory. A relative pattern is relative to the configuration file"`
+	Command     string         `mapstructure:"command" description:"Shell command: one profile is generated per (non-empty) line of output. The command runs in the folder of the configuration file"`
+	Name        string         `mapstructure:"name" default:"${GENERATOR}-${ITEM_NAME}" description:"Name of the generated profiles. The variables ${GENERATOR}, ${ITEM}, ${ITEM_NAME} and ${INDEX} are available"`
+	Profile     map[string]any `mapstructure:"profile" description:"Profile template: the variables ${GENERATOR}, ${ITEM}, ${ITEM_NAME} and ${INDEX} are replaced in all values"`
+}
+
+var invalidProfileNameChars = regexp.MustCompile(`[^\w-]+`)
+
+// generatorItemName returns the last element of item with all the characters that cannot be used in a profile name replaced by "-"
+func generatorItemName(item string) string {
+	name := filepath.Base(filepath.Clean(item))
+	if name == "." || name == string(filepath.Separator) {
+		name = item
+	}
+	return strings.Trim(invalidProfileNameChars.ReplaceAllString(name, "-"), "-")
+}
+
+// runGeneratorCommand runs command in the shell and returns its output
+var runGeneratorCommand = func(command, dir string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if platform.IsWindows() {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+// generatorItems returns the list of items of the generator (literal items, then directories, then command lines)
+func (c *Config) generatorItems(name string, generator *profileGenerator) (items []string, err error) {
+	items = slices.Clone(generator.Items)
+	rootPath := filepath.Dir(c.GetConfigFile())
+
+	if generator.Glob != "" {
+		pattern := generator.Glob
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(rootPath, pattern)
+		}
+		var matches []string
+		if matches, err = filepath.Glob(pattern); err != nil {
+			return nil, fmt.Errorf("invalid glob %q in generator '%s': %w", generator.Glob, name, err)
+		}
+		for _, match := range matches {
+			if info, statErr := os.Stat(match); statErr == nil && info.IsDir() {
+				items = append(items, match)
+			}
+		}
+	}
+
+	if generator.Command != "" {
+		// the command runs only once even though the configuration templates are reloaded for each profile
+		lines, found := c.generatorCommandLines[generator.Command]
+		if !found {
+			var output []byte
+			if output, err = runGeneratorCommand(generator.Command, rootPath); err != nil {
+				return nil, fmt.Errorf("command %q in generator '%s' failed: %w", generator.Command, name, err)
+			}
+			scanner := bufio.NewScanner(bytes.NewReader(output))
+			for scanner.Scan() {
+				if line := strings.TrimSpace(scanner.Text()); line != "" {
+					lines = append(lines, line)
+				}
+			}
+			if c.generatorCommandLines == nil {
+				c.generatorCommandLines = make(map[string][]string)
+			}
+			c.generatorCommandLines[generator.Command] = lines
+		}
+		items = append(items, lines...)
+	}
+	return
+}
+
+// generateProfiles creates the profiles and the groups declared in the "generate-profiles" section (config v2)
+func (c *Config) generateProfiles() (err error) {
+	c.requireMinVersion(Version02)
+
+	definitions := c.viper.GetStringMap(constants.SectionConfigurationGenerateProfiles)
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	profiles := make(map[string]any)
+	groups := make(map[string]any)
+
+	for _, name := range slices.Sorted(maps.Keys(definitions)) {
+		generator := new(profileGenerator)
+		if err = c.unmarshalKey(c.flatKey(constants.SectionConfigurationGenerateProfiles, name), generator); err != nil {
+			return fmt.Errorf("cannot parse generator '%s': %w", name, err)
+		}
+		if c.IsSet(constants.SectionConfigurationGroups, name) || c.HasProfile(name) {
+			return fmt.Errorf("generator '%s': a profile or a group with the same name already exists", name)
+		}
+
+		var items []string
+		if items, err = c.generatorItems(name, generator); err != nil {
+			return
+		}
+		if len(items) == 0 {
+			clog.Warningf("generator '%s' has no item: no profile generated", name)
+		}
+		if generator.Name == "" {
+			generator.Name = defaultGeneratedProfileName
+		}
+
+		profileTemplate := &mixin{Source: generator.Profile}
+		names := make([]string, 0, len(items))
+		for index, item := range items {
+			variables := map[string]any{
+				"GENERATOR": name,
+				"ITEM":      item,
+				"ITEM_NAME": generatorItemName(item),
+				"INDEX":     strconv.Itoa(index + 1),
+			}
+			profileName := strings.ToLower(profileTemplate.expandVariables(generator.Name, variables))
+
+			switch {
+			case profileName == "" || strings.ContainsAny(profileName, ".$"):
+				err = fmt.Errorf("generator '%s': invalid profile name %q for item %q", name, profileName, item)
+			case profiles[profileName] != nil:
+				err = fmt.Errorf("generator '%s': item %q generates the duplicate profile name '%s'", name, item, profileName)
+			case c.HasProfile(profileName):
+				err = fmt.Errorf("generator '%s': generated profile '%s' conflicts with a profile of the same name", name, profileName)
+			}
+			if err != nil {
+				return
+			}
+
+			clog.Tracef("generator '%s': generating profile '%s' for item %q", name, profileName, item)
+			profile := profileTemplate.Resolve(variables)
+			if _, found := profile[constants.SectionConfigurationDescription]; !found {
+				profile[constants.SectionConfigurationDescription] = fmt.Sprintf("generated by '%s' for %s", name, item)
+			}
+			profiles[profileName] = profile
+			names = append(names, profileName)
+			if source := c.sources.generators[name]; source != "" {
+				c.addProfileSource(profileName, source)
+			}
+		}
+
+		description := generator.Description
+		if description == "" {
+			description = fmt.Sprintf("profiles generated by '%s'", name)
+		}
+		groups[name] = map[string]any{
+			"description": description,
+			"profiles":    names,
+		}
+	}
+
+	for name := range groups {
+		if profiles[name] != nil {
+			return fmt.Errorf("generator '%s': a generated profile has the same name as the generator", name)
+		}
+	}
+
+	generated := map[string]any{
+		constants.SectionConfigurationProfiles: profiles,
+		constants.SectionConfigurationGroups:   groups,
+	}
+
+	// collect mixin uses in the generated profiles
+	vp := viper.NewWithOptions(viper.KeyDelimiter(c.keyDelim))
+	if err = vp.MergeConfigMap(generated); err == nil {
+		var allUses map[string][]*mixinUse
+		if allUses, err = collectAllMixinUses(vp, c.keyDelim); err == nil && len(allUses) > 0 {
+			c.mixinUses = append(c.mixinUses, allUses)
+		}
+	}
+	if err == nil {
+		err = c.viper.MergeConfigMap(generated)
+	}
+	return
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratorItemName(t *testing.T) {
+	fixtures := map[string]string{
+		"project":           "project",
+		"/srv/projects/web": "web",
+		"/srv/projects/w b": "w-b",
+		"web.example.com":   "web-example-com",
+		"/srv/projects/":    "projects",
+		"-name-":            "name",
+	}
+	for item, name := range fixtures {
+		assert.Equal(t, name, generatorItemName(item), item)
+	}
+}
+
+func TestGenerateProfilesFromItems(t *testing.T) {
+	testConfig := `
+version: 2
+mixins:
+  tagged:
+    backup:
+      tag...: [ "$TAG" ]
+profiles:
+  base:
+    abstract: true
+    repository: "local:/backup"
+    backup:
+      tag: [ "base" ]
+generate-profiles:
+  projects:
+    description: "all the projects"
+    items: [ web, api ]
+    profile:
+      inherit: base
+      use:
+        - name: tagged
+          tag: "${ITEM}"
+      backup:
+        source: "/srv/${ITEM}"
+        tag...: [ "project-${INDEX}" ]
+  named:
+    items: [ "one" ]
+    name: "custom-${ITEM_NAME}"
+    profile:
+      description: "custom"
+`
+	c, err := Load(bytes.NewBufferString(testConfig), FormatYAML)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"base", "projects-web", "projects-api", "custom-one"}, c.GetProfileNames())
+
+	for i, item := range []string{"web", "api"} {
+		profile, err := c.GetProfile("projects-" + item)
+		require.NoError(t, err)
+		assert.Equal(t, "local:/backup", profile.Repository.String())
+		assert.Equal(t, "generated by 'projects' for "+item, profile.Description)
+		require.NotNil(t, profile.Backup)
+		assert.Equal(t, []string{"/srv/" + item}, profile.Backup.Source)
+		assert.Equal(t, []any{"base", "project-" + string(rune('1'+i)), item}, profile.Backup.OtherFlags["tag"])
+	}
+
+	profile, err := c.GetProfile("custom-one")
+	require.NoError(t, err)
+	assert.Equal(t, "custom", profile.Description)
+
+	group, err := c.GetProfileGroup("projects")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"projects-web", "projects-api"}, group.Profiles)
+	assert.Equal(t, "all the projects", group.Description)
+
+	group, err = c.GetProfileGroup("named")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"custom-one"}, group.Profiles)
+	assert.Equal(t, "profiles generated by 'named'", group.Description)
+}
+
+func TestGenerateProfilesFromGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"alpha", "beta.v2"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "projects", name), 0o755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "projects", "file"), nil, 0o600))
+	configFile := filepath.Join(dir, "profiles.toml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+version = "2"
+[generate-profiles.dirs]
+glob = "projects/*"
+[generate-profiles.dirs.profile.backup]
+source = "${ITEM}"
+`), 0o600))
+
+	c, err := LoadFile(configFile, "")
+	require.NoError(t, err)
+
+	group, err := c.GetProfileGroup("dirs")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dirs-alpha", "dirs-beta-v2"}, group.Profiles)
+
+	profile, err := c.GetProfile("dirs-beta-v2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "projects", "beta.v2")}, profile.Backup.Source)
+}
+
+func TestGenerateProfilesFromCommand(t *testing.T) {
+	calls := 0
+	defer func(run func(command, dir string) ([]byte, error)) { runGeneratorCommand = run }(runGeneratorCommand)
+	runGeneratorCommand = func(command, dir string) ([]byte, error) {
+		calls++
+		switch command {
+		case "list-databases":
+			return []byte("users\n\n  orders  \n"), nil
+		default:
+			return nil, errors.New("exit status 1")
+		}
+	}
+
+	testConfig := `
+version = "2"
+[generate-profiles.db]
+command = "list-databases"
+[generate-profiles.db.profile]
+run-before = "dump ${ITEM}"
+`
+	c, err := Load(bytes.NewBufferString(testConfig), FormatTOML)
+	require.NoError(t, err)
+
+	for _, name := range []string{"users", "orders"} {
+		profile, err := c.GetProfile("db-" + name)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dump " + name}, profile.RunBefore)
+	}
+	group, err := c.GetProfileGroup("db")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db-users", "db-orders"}, group.Profiles)
+	// the command runs once only
+	assert.Equal(t, 1, calls)
+
+	_, err = Load(bytes.NewBufferString(`
+version = "2"
+[generate-profiles.db]
+command = "fail"
+`), FormatTOML)
+	assert.EqualError(t, err, `command "fail" in generator 'db' failed: exit status 1`)
+}
+
+func TestGenerateProfilesErrors(t *testing.T) {
+	fixtures := []struct {
+		config string
+		err    string
+	}{
+		{
+			config: "[profiles.gen]\n[generate-profiles.gen]\nitems = [\"a\"]",
+			err:    "generator 'gen': a profile or a group with the same name already exists",
+		},
+		{
+			config: "[groups.gen]\nprofiles = []\n[generate-profiles.gen]\nitems = [\"a\"]",
+			err:    "generator 'gen': a profile or a group with the same name already exists",
+		},
+		{
+			config: "[profiles.gen-a]\n[generate-profiles.gen]\nitems = [\"a\"]",
+			err:    "generator 'gen': generated profile 'gen-a' conflicts with a profile of the same name",
+		},
+		{
+			config: "[generate-profiles.gen]\nitems = [\"a\", \"a\"]",
+			err:    `generator 'gen': item "a" generates the duplicate profile name 'gen-a'`,
+		},
+		{
+			config: "[generate-profiles.gen]\nitems = [\"a\"]\nname = \"${ITEM}.${UNKNOWN}\"",
+			err:    `generator 'gen': invalid profile name "a.${unknown}" for item "a"`,
+		},
+		{
+			config: "[generate-profiles.gen]\nitems = [\"gen\"]\nname = \"${ITEM}\"",
+			err:    "generator 'gen': a generated profile has the same name as the generator",
+		},
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.err, func(t *testing.T) {
+			_, err := Load(bytes.NewBufferString("version = \"2\"\n"+fixture.config), FormatTOML)
+			assert.EqualError(t, err, fixture.err)
+		})
+	}
+}
@@ -48,8 +48,13 @@ var (
 func (c *Config) getProfileNamesV1() (names []string) {
 	c.requireVersion(Version01)
 
+	return profileNamesV1(c.viper.AllSettings())
+}
+
+// profileNamesV1 returns the names of the profiles in the settings of a version 1 configuration
+func profileNamesV1(settings map[string]any) (names []string) {
 	names = make([]string, 0)
-	for sectionKey := range c.viper.AllSettings() {
+	for sectionKey := range settings {
 		if sectionKey == constants.SectionConfigurationGlobal ||
 			sectionKey == constants.SectionConfigurationGroups ||
 			sectionKey == constants.SectionConfigurationIncludes ||
@@ -86,6 +91,11 @@ func (c *Config) loadGroupsV1() (err error) {
 
 // getProfileV1 from version 1 configuration. If the profile is not found, it returns errNotFound
 func (c *Config) getProfileV1(profileKey string) (profile *Profile, err error) {
+	return c.getInheritedProfileV1(profileKey, nil)
+}
+
+// getInheritedProfileV1 loads a profile and its parent. chain contains the names of the derived profiles
+func (c *Config) getInheritedProfileV1(profileKey string, chain []string) (profile *Profile, err error) {
 	c.requireVersion(Version01)
 
 	if !c.IsSet(c.getProfilePath(profileKey)) {
@@ -99,18 +109,27 @@ func (c *Config) getProfileV1(profileKey string) (profile *Profile, err error) {
 		return nil, err
 	}
 
-	if profile.Inherit != "" {
-		inherit := profile.Inherit
+	if len(profile.Inherit) > 1 {
+		return nil, fmt.Errorf("error in profile '%s': inheriting from multiple profiles requires configuration version 2", profileKey)
+	}
+
+	if len(profile.Inherit) == 1 && profile.Inherit[0] != "" {
+		inherit := profile.Inherit[0]
+		chain = append(chain, profileKey)
+		if err = checkInheritanceCycle(chain, inherit); err != nil {
+			return nil, err
+		}
 		// Load inherited profile
-		profile, err = c.getProfile(inherit)
+		profile, err = c.getInheritedProfileV1(inherit, chain)
 		if err != nil {
 			if errors.Is(err, ErrNotFound) {
 				return nil, fmt.Errorf("error in profile '%s': parent profile '%s' not found", profileKey, inherit)
 			}
 			return nil, err
 		}
-		// It doesn't make sense to inherit the Description field
+		// It doesn't make sense to inherit the Description and Abstract fields
 		profile.Description = ""
+		profile.Abstract = false
 		// Reload this profile onto the inherited one
 		err = c.unmarshalKey(c.getProfilePath(profileKey), profile)
 		if err != nil {
@@ -6,3 +6,6 @@ const (
 	FormatJSON = "json"
 	FormatHCL  = "hcl"
 )
+
+// EncryptedExtension is the extension added to a configuration file encrypted with age
+const EncryptedExtension = ".age"
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/crypt"
+)
+
+// WithAgeKeyFile sets the age key file used to decrypt encrypted configuration files.
+// When empty, the key is loaded from the SOPS environment variables (SOPS_AGE_KEY_FILE or SOPS_AGE_KEY)
+func WithAgeKeyFile(keyFile string) func(cfg *Config) {
+	return func(cfg *Config) {
+		cfg.ageKeyFile = keyFile
+	}
+}
+
+// decrypt returns the plain content of a configuration file encrypted with age or SOPS.
+// Content that is not encrypted is returned unchanged.
+func (c *Config) decrypt(filename, format string, content []byte) ([]byte, error) {
+	kind := crypt.Detect(content)
+	if kind == crypt.KindNone {
+		return content, nil
+	}
+	if c.identities == nil {
+		identities, err := crypt.LoadIdentities(c.ageKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decrypt %s configuration file %q: %w", kind, filename, err)
+		}
+		c.identities = identities
+	}
+	clog.Debugf("decrypting %s configuration file: %s", kind, filename)
+	plain, err := crypt.Decrypt(content, format, c.identities)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt configuration file %q: %w", filename, err)
+	}
+	return plain, nil
+}
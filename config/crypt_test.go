@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/creativeprojects/resticprofile/crypt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEncryptedFiles(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	recipients := []age.Recipient{identity.Recipient()}
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "keys.txt")
+	require.NoError(t, os.WriteFile(keyFile, []byte(identity.String()+"\n"), 0o600))
+
+	createEncryptedFile := func(t *testing.T, name, format string, kind crypt.Kind, content string) string {
+		t.Helper()
+		encrypted, err := crypt.Encrypt([]byte(content), format, kind, recipients)
+		require.NoError(t, err)
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(filename, encrypted, 0o600))
+		return filename
+	}
+
+	configFile := createEncryptedFile(t, "profiles.toml.age", FormatTOML, crypt.KindAge, `
+version = 2
+includes = ["secrets.yaml", "more.json"]
+[profiles.default]
+repository = "local:/backup/{{ .Profile.Name }}"
+`)
+	createEncryptedFile(t, "secrets.yaml", FormatYAML, crypt.KindSops, `
+profiles:
+  default:
+    password-file: key
+    env:
+      B2_ACCOUNT_KEY: secret
+`)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "more.json"), []byte(`{"profiles": {"other": {"initialize": true}}}`), 0o600))
+
+	t.Run("with key file", func(t *testing.T) {
+		config, err := LoadFile(configFile, "", WithAgeKeyFile(keyFile))
+		require.NoError(t, err)
+		assert.Equal(t, FormatTOML, config.format)
+		assert.True(t, config.HasProfile("other"))
+
+		profile, err := config.GetProfile("default")
+		require.NoError(t, err)
+		assert.Equal(t, "local:/backup/default", profile.Repository.Value())
+		assert.Equal(t, filepath.Join(dir, "key"), profile.PasswordFile)
+		assert.Equal(t, "secret", profile.Environment["B2_ACCOUNT_KEY"].Value())
+	})
+
+	t.Run("with sops environment", func(t *testing.T) {
+		t.Setenv(crypt.EnvSopsAgeKeyFile, keyFile)
+		config, err := LoadFile(configFile, "")
+		require.NoError(t, err)
+		assert.True(t, config.HasProfile("default"))
+	})
+
+	t.Run("without key", func(t *testing.T) {
+		t.Setenv(crypt.EnvSopsAgeKeyFile, "")
+		t.Setenv(crypt.EnvSopsAgeKey, "")
+		_, err := LoadFile(configFile, "")
+		assert.ErrorIs(t, err, crypt.ErrNoIdentity)
+	})
+
+	t.Run("with wrong key", func(t *testing.T) {
+		other, err := age.GenerateX25519Identity()
+		require.NoError(t, err)
+		otherKeyFile := filepath.Join(t.TempDir(), "keys.txt")
+		require.NoError(t, os.WriteFile(otherKeyFile, []byte(other.String()+"\n"), 0o600))
+
+		_, err = LoadFile(configFile, "", WithAgeKeyFile(otherKeyFile))
+		assert.ErrorContains(t, err, "cannot decrypt configuration file")
+	})
+}
+
+func TestFormatFromEncryptedExtension(t *testing.T) {
+	assert.Equal(t, "yaml", formatFromExtension("profiles.yaml.age"))
+	assert.Equal(t, "toml", formatFromExtension("profiles.toml"))
+	assert.Equal(t, "age", formatFromExtension("profiles.age.age"))
+}
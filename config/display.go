@@ -47,6 +47,22 @@ func (d *Display) addKeyOnlyEntry(stack []string, key string) {
 	d.entries[len(d.entries)-1].keyOnly = true
 }
 
+// setOrigin marks the entries matching keys (in "section.key" format) as coming from origin
+func (d *Display) setOrigin(keys []string, origin string) {
+	for i, entry := range d.entries {
+		key := entry.key
+		if entry.section != "" {
+			key = entry.section + "." + key
+		}
+		for _, originKey := range keys {
+			if strings.EqualFold(key, originKey) {
+				d.entries[i].origin = origin
+				break
+			}
+		}
+	}
+}
+
 func (d *Display) Flush() {
 	const minWidth, tabWidth, padding = 0, 2, 2
 	tabWriter := tabwriter.NewWriter(d.writer, minWidth, tabWidth, padding, ' ', 0)
@@ -75,7 +91,11 @@ func (d *Display) Flush() {
 			continue
 		}
 		if len(entry.values) > 0 {
-			fmt.Fprintf(tabWriter, "%s%s:\t%s\n", prefix, entry.key, cleanupControlCharacters(entry.values[0]))
+			if entry.origin != "" {
+				fmt.Fprintf(tabWriter, "%s%s:\t%s\t(%s)\n", prefix, entry.key, cleanupControlCharacters(entry.values[0]), entry.origin)
+			} else {
+				fmt.Fprintf(tabWriter, "%s%s:\t%s\n", prefix, entry.key, cleanupControlCharacters(entry.values[0]))
+			}
 		}
 		if len(entry.values) > 1 {
 			for i := 1; i < len(entry.values); i++ {
@@ -92,6 +112,7 @@ type Entry struct {
 	key     string
 	keyOnly bool
 	values  []string
+	origin  string // where the value comes from, when not from the configuration file
 }
 
 func cleanupControlCharacters(value string) string {
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/mask"
+	"github.com/creativeprojects/resticprofile/platform"
 	"github.com/creativeprojects/resticprofile/util/maybe"
 )
 
@@ -17,6 +19,7 @@ type Global struct {
 	DefaultCommand       string              `mapstructure:"default-command" default:"snapshots" description:"The restic or resticprofile command to use when no command was specified"`
 	Initialize           bool                `mapstructure:"initialize" default:"false" description:"Initialize a repository if missing"`
 	NoAutoRepositoryFile maybe.Bool          `mapstructure:"prevent-auto-repository-file" default:"false" description:"Prevents using a repository file for repository definitions containing a password"`
+	PipeSecrets          bool                `mapstructure:"pipe-secrets" default:"false" description:"Passes confidential repositories and passwords to restic through inherited pipes instead of temporary files and environment variables (not available on Windows) - see https://creativeprojects.github.io/resticprofile/configuration/encryption/"`
 	ResticBinary         string              `mapstructure:"restic-binary" description:"Full path of the restic executable (detected if not set)"`
 	ResticVersion        string              `mapstructure:"restic-version" pattern:"^(|[0-9]+\\.[0-9]+(\\.[0-9]+)?)$" description:"Sets the restic version (detected if not set)"`
 	FilterResticFlags    bool                `mapstructure:"restic-arguments-filter" default:"true" description:"Remove unknown flags instead of passing all configured flags to restic"`
@@ -34,7 +37,11 @@ type Global struct {
 	SenderTimeout        time.Duration       `mapstructure:"send-timeout" default:"30s" examples:"15s;30s;2m30s" description:"Timeout when sending messages to a webhook - see https://creativeprojects.github.io/resticprofile/configuration/http_hooks/"`
 	CACertificates       []string            `mapstructure:"ca-certificates" description:"Path to PEM encoded certificates to trust in addition to system certificates when resticprofile sends to a webhook - see https://creativeprojects.github.io/resticprofile/configuration/http_hooks/"`
 	PreventSleep         bool                `mapstructure:"prevent-sleep" default:"false" description:"Prevent the system from sleeping while running commands - see https://creativeprojects.github.io/resticprofile/configuration/sleep/"`
+	ConfidentialEnv      []string            `mapstructure:"confidential-env" examples:"*_PASS;MY_VARIABLE" description:"Names of environment variables (glob patterns) with confidential values to mask, in addition to \"*_KEY\", \"*_TOKEN\", \"*PASSWORD*\" and \"*SECRET*\""`
+	ConfidentialHeaders  []string            `mapstructure:"confidential-headers" examples:"X-Auth;*-Session" description:"Names of HTTP headers (glob patterns) with confidential values to mask, in addition to \"Authorization\", \"Proxy-Authorization\", \"Cookie\", \"*-Token\" and \"*-Api-Key\""`
+	ConfidentialQuery    []string            `mapstructure:"confidential-query-parameters" examples:"code;session" description:"Names of URL query parameters (glob patterns) with confidential values to mask, in addition to \"*token*\", \"*secret*\", \"*password*\", \"*key\", \"auth\", \"sig\" and \"signature\""`
 	GroupContinueOnError bool                `mapstructure:"group-continue-on-error" default:"false" description:"Enable groups to continue with the next profile(s) instead of stopping at the first failure"`
+	PluginDirs           []string            `mapstructure:"plugin-dirs" examples:"~/.resticprofile/plugins;/usr/local/lib/resticprofile" description:"Directories searched for \"resticprofile-<name>\" plugin executables before the PATH - see https://creativeprojects.github.io/resticprofile/usage/plugins/"`
 }
 
 // NewGlobal instantiates a new Global with default values
@@ -61,7 +68,25 @@ func (p *Global) SetRootPath(rootPath string) {
 	p.SystemdUnitTemplate = fixPath(p.SystemdUnitTemplate, expandEnv, absolutePrefix(rootPath))
 	p.SystemdTimerTemplate = fixPath(p.SystemdTimerTemplate, expandEnv, absolutePrefix(rootPath))
 
+	for index, dir := range p.PluginDirs {
+		p.PluginDirs[index] = fixPath(dir, expandEnv, expandUserHome, absolutePrefix(rootPath))
+	}
+
 	for index, file := range p.CACertificates {
 		p.CACertificates[index] = fixPath(file, expandEnv, absolutePrefix(rootPath))
 	}
 }
+
+// GetMaskRules returns the rules to mask confidential values, in addition to mask.DefaultRules
+func (p *Global) GetMaskRules() mask.Rules {
+	return mask.Rules{
+		EnvKeys:         p.ConfidentialEnv,
+		HeaderNames:     p.ConfidentialHeaders,
+		QueryParameters: p.ConfidentialQuery,
+	}
+}
+
+// UsePipeSecrets returns true when confidential values are passed to restic through inherited pipes ("pipe-secrets")
+func (p *Global) UsePipeSecrets() bool {
+	return p != nil && p.PipeSecrets && !platform.IsWindows()
+}
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"slices"
+	"strings"
+
 	"github.com/creativeprojects/resticprofile/constants"
 	"github.com/creativeprojects/resticprofile/util/maybe"
 )
@@ -8,11 +11,12 @@ import (
 // Group of profiles
 type Group struct {
 	config           *Config
-	Name             string                     `show:"noshow"`
-	Description      string                     `mapstructure:"description" description:"Describe the group"`
-	Profiles         []string                   `mapstructure:"profiles" description:"Names of the profiles belonging to this group"`
-	ContinueOnError  maybe.Bool                 `mapstructure:"continue-on-error" default:"auto" description:"Continue with the next profile on a failure, overrides \"global.group-continue-on-error\""`
-	CommandSchedules map[string]*ScheduleConfig `mapstructure:"schedules" show:"noshow" description:"Allows to run the group on schedule for the specified command name (backup, copy, check, forget, prune)."`
+	Name             string                        `show:"noshow"`
+	Description      string                        `mapstructure:"description" description:"Describe the group"`
+	Profiles         []string                      `mapstructure:"profiles" description:"Names of the profiles belonging to this group"`
+	ContinueOnError  maybe.Bool                    `mapstructure:"continue-on-error" default:"auto" description:"Continue with the next profile on a failure, overrides \"global.group-continue-on-error\""`
+	MemberConditions map[string]*ActivationSection `mapstructure:"conditions" description:"Activation conditions of the profiles in this group, by profile name"`
+	CommandSchedules map[string]*ScheduleConfig    `mapstructure:"schedules" show:"noshow" description:"Allows to run the group on schedule for the specified command name (backup, copy, check, forget, prune, or a composite command declared in \"commands\")."`
 }
 
 func NewGroup(c *Config, name string) (g *Group) {
@@ -49,13 +53,32 @@ func (g *Group) Schedules() map[string]*Schedule {
 func (g *Group) SchedulableCommands() []string {
 	// once the deprecated retention schedule is removed, we can use the list from profiles
 	// return NewProfile(g.config, "").SchedulableCommands()
-	return []string{
+	commands := []string{
 		constants.CommandBackup,
 		constants.CommandCheck,
 		constants.CommandForget,
 		constants.CommandPrune,
 		constants.CommandCopy,
 	}
+	// add the composite commands declared in the profiles of the group
+	for _, profileName := range g.Profiles {
+		for _, command := range g.config.getCompositeCommandNames(profileName) {
+			if !slices.Contains(commands, command) {
+				commands = append(commands, command)
+			}
+		}
+	}
+	return commands
+}
+
+// GetMemberActivation returns the activation conditions of a profile in this group, or nil when there is none
+func (g *Group) GetMemberActivation(profileName string) *ActivationSection {
+	for name, conditions := range g.MemberConditions {
+		if strings.EqualFold(name, profileName) {
+			return conditions
+		}
+	}
+	return nil
 }
 
 func (g *Group) Kind() string {
@@ -1,10 +1,12 @@
 package config
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/creativeprojects/resticprofile/constants"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGroupSchedulableCommands(t *testing.T) {
@@ -21,3 +23,45 @@ func TestGroupSchedulableCommands(t *testing.T) {
 	// Test Kind() method
 	assert.Equal(t, constants.SchedulableKindGroup, group.Kind(), "Group kind should be SchedulableKindGroup")
 }
+
+func TestGroupSchedulableCompositeCommands(t *testing.T) {
+	testConfig := `
+version: "2"
+profiles:
+  first:
+    commands:
+      maintenance:
+        steps:
+          - command: prune
+  second:
+    commands:
+      maintenance:
+        steps:
+          - command: check
+      verify:
+        steps:
+          - command: check
+  third:
+    inherit: second
+    commands:
+      cleanup:
+        steps:
+          - command: forget
+groups:
+  all:
+    profiles: [first, second]
+  inherited:
+    profiles: [third]
+`
+	c, err := Load(bytes.NewBufferString(testConfig), FormatYAML)
+	require.NoError(t, err)
+	group, err := c.GetProfileGroup("all")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"backup", "check", "forget", "prune", "copy", "maintenance", "verify"}, group.SchedulableCommands())
+
+	// commands inherited from the parent profiles
+	group, err = c.GetProfileGroup("inherited")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backup", "check", "forget", "prune", "copy", "cleanup", "maintenance", "verify"}, group.SchedulableCommands())
+}
@@ -590,6 +590,7 @@ var infoTypes struct {
 	group,
 	mixins,
 	mixinUse,
+	profileGenerator,
 	profile,
 	scheduleConfig,
 	genericSection reflect.Type
@@ -604,6 +605,7 @@ func init() {
 		infoTypes.group = reflect.TypeFor[Group]()
 		infoTypes.mixins = reflect.TypeFor[mixin]()
 		infoTypes.mixinUse = reflect.TypeFor[mixinUse]()
+		infoTypes.profileGenerator = reflect.TypeFor[profileGenerator]()
 		infoTypes.profile = reflect.TypeFor[Profile]()
 		infoTypes.scheduleConfig = reflect.TypeFor[ScheduleConfig]()
 		infoTypes.genericSection = reflect.TypeFor[GenericSection]()
@@ -651,6 +653,15 @@ func NewMixinUseInfo() NamedPropertySet {
 	}
 }
 
+// NewProfileGeneratorInfo returns structural information on the "generate-profiles" config v2 section
+func NewProfileGeneratorInfo() NamedPropertySet {
+	return &namedPropertySet{
+		name:        constants.SectionConfigurationGenerateProfiles,
+		description: "Profile generator: creates one profile per item from a template, and a group of all the generated profiles named after the generator",
+		propertySet: propertySetFromType(infoTypes.profileGenerator),
+	}
+}
+
 // NewScheduleConfigInfo returns structural information on the "schedule" config structure
 func NewScheduleConfigInfo() NamedPropertySet {
 	return &namedPropertySet{
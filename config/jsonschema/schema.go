@@ -373,6 +373,20 @@ func schemaForMixins() SchemaType {
 	return object
 }
 
+func schemaForProfileGenerators() SchemaType {
+	info := config.NewProfileGeneratorInfo()
+	generatorType := schemaForPropertySet(info)
+	// the profile template has the same schema as the declared profiles
+	generatorType.Properties["profile"] = &schemaReference{
+		Ref: "#/properties/" + constants.SectionConfigurationProfiles + "/patternProperties/" + matchAll,
+	}
+
+	object := newSchemaObject()
+	object.Description = "Profile generators declaration."
+	object.PatternProperties[matchAll] = generatorType
+	return object
+}
+
 func schemaForMixinUse() SchemaType {
 	info := config.NewMixinUseInfo()
 	useType := schemaForPropertySet(info)
@@ -446,12 +460,13 @@ func schemaForConfigV2(profileInfo config.ProfileInfo) (object *schemaObject) {
 	object = newSchemaObject()
 	object.Description = "resticprofile configuration v2"
 	object.Properties = map[string]SchemaType{
-		constants.SectionConfigurationGlobal:   schemaForGlobal(),
-		constants.SectionConfigurationGroups:   schemaForGroups(config.Version02),
-		constants.SectionConfigurationIncludes: schemaForIncludes(),
-		constants.SectionConfigurationMixins:   schemaForMixins(),
-		constants.SectionConfigurationProfiles: schemaForProfile(profileInfo),
-		constants.ParameterVersion:             schemaForConfigVersion(config.Version02),
+		constants.SectionConfigurationGlobal:           schemaForGlobal(),
+		constants.SectionConfigurationGroups:           schemaForGroups(config.Version02),
+		constants.SectionConfigurationIncludes:         schemaForIncludes(),
+		constants.SectionConfigurationMixins:           schemaForMixins(),
+		constants.SectionConfigurationProfiles:         schemaForProfile(profileInfo),
+		constants.SectionConfigurationGenerateProfiles: schemaForProfileGenerators(),
+		constants.ParameterVersion:                     schemaForConfigVersion(config.Version02),
 	}
 	object.Required = append(object.Required, constants.ParameterVersion)
 	{
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/constants"
+)
+
+// LimitsSection overrides the bandwidth and the priority of restic during a time window
+type LimitsSection struct {
+	Window        string `mapstructure:"window" examples:"Mon..Fri 08:00..18:00;Sat,Sun;22:00..06:00" description:"Days of the week and time of the day when the limits apply. Days and time are both optional, a time window ending before it starts crosses midnight"`
+	LimitUpload   int    `mapstructure:"limit-upload" description:"Limits uploads to a maximum rate in KiB/s (restic \"--limit-upload\" flag)"`
+	LimitDownload int    `mapstructure:"limit-download" description:"Limits downloads to a maximum rate in KiB/s (restic \"--limit-download\" flag)"`
+	Nice          *int   `mapstructure:"nice" range:"[-20:19]" description:"Sets the unix \"nice\" value of restic (on any OS). Lowering the value needs elevated privileges"`
+	IONice        bool   `mapstructure:"ionice" default:"false" description:"Enables setting the linux IO priority class and level of restic (only on linux OS)"`
+	IONiceClass   int    `mapstructure:"ionice-class" default:"2" range:"[1:3]" description:"Sets the linux \"ionice-class\" (I/O scheduling class) to apply when \"ionice\" is enabled (1=realtime, 2=best-effort, 3=idle)"`
+	IONiceLevel   int    `mapstructure:"ionice-level" default:"0" range:"[0:7]" description:"Sets the linux \"ionice-level\" (I/O priority within the scheduling class) to apply when \"ionice\" is enabled (0=highest priority, 7=lowest priority)"`
+
+	window *TimeWindow
+}
+
+// GetIONiceClass returns the IO priority class, defaulting to best-effort
+func (l *LimitsSection) GetIONiceClass() int {
+	if l.IONiceClass == 0 {
+		return constants.DefaultIONiceClass
+	}
+	return l.IONiceClass
+}
+
+// Matches returns true when the time is inside the window of the limits
+func (l *LimitsSection) Matches(now time.Time) (bool, error) {
+	if err := l.parseWindow(); err != nil {
+		return false, err
+	}
+	return l.window.Contains(now), nil
+}
+
+// parseWindow parses the window once. It returns an error when the window is invalid
+func (l *LimitsSection) parseWindow() error {
+	if l.window != nil {
+		return nil
+	}
+	window, err := ParseTimeWindow(l.Window)
+	if err != nil {
+		return err
+	}
+	l.window = &window
+	return nil
+}
+
+// TimeWindow is a time of the day, on some days of the week
+type TimeWindow struct {
+	days  [7]bool
+	start time.Duration
+	end   time.Duration
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseTimeWindow parses a window like "Mon..Fri 08:00..18:00". The days ("Mon..Fri", "Sat,Sun")
+// and the time of the day ("22:00..06:00") are both optional, but the window cannot be empty
+func ParseTimeWindow(window string) (TimeWindow, error) {
+	result := TimeWindow{end: 24 * time.Hour}
+	fields := strings.Fields(window)
+	if len(fields) == 0 || len(fields) > 2 {
+		return result, fmt.Errorf("invalid window %q: expected days and/or time of the day", window)
+	}
+	hasDays, hasTime := false, false
+	for _, field := range fields {
+		var err error
+		if strings.Contains(field, ":") && !hasTime {
+			result.start, result.end, err = parseTimeRange(field)
+			hasTime = true
+		} else if !hasDays && !hasTime {
+			result.days, err = parseDays(field)
+			hasDays = true
+		} else {
+			err = fmt.Errorf("unexpected %q", field)
+		}
+		if err != nil {
+			return result, fmt.Errorf("invalid window %q: %w", window, err)
+		}
+	}
+	if !hasDays {
+		for day := range result.days {
+			result.days[day] = true
+		}
+	}
+	return result, nil
+}
+
+func parseDays(days string) (result [7]bool, err error) {
+	for _, item := range strings.Split(strings.ToLower(days), ",") {
+		from, to, isRange := strings.Cut(item, "..")
+		first, found := weekdays[from]
+		if !found {
+			return result, fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, found = weekdays[to]; !found {
+				return result, fmt.Errorf("unknown day %q", to)
+			}
+		}
+		// a range of days can wrap around the end of the week, e.g. "Sat..Mon"
+		for day := first; ; day = (day + 1) % 7 {
+			result[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func parseTimeRange(timeRange string) (start, end time.Duration, err error) {
+	from, to, found := strings.Cut(timeRange, "..")
+	if !found {
+		return 0, 0, fmt.Errorf("expected a time range like 08:00..18:00, found %q", timeRange)
+	}
+	if start, err = parseTimeOfDay(from); err != nil {
+		return
+	}
+	if end, err = parseTimeOfDay(to); err != nil {
+		return
+	}
+	if start == end {
+		err = fmt.Errorf("empty time range %q", timeRange)
+	}
+	return
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	hours, minutes, found := strings.Cut(value, ":")
+	h, errHours := strconv.Atoi(hours)
+	m, errMinutes := strconv.Atoi(minutes)
+	if !found || errHours != nil || errMinutes != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m > 0) {
+		return 0, fmt.Errorf("invalid time of the day %q", value)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// Contains returns true when the time is inside the window. When the time of the day crosses midnight,
+// the hours after midnight belong to the day the window started
+func (w TimeWindow) Contains(now time.Time) bool {
+	timeOfDay := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second
+	day := now.Weekday()
+	if w.start < w.end {
+		return w.days[day] && timeOfDay >= w.start && timeOfDay < w.end
+	}
+	if timeOfDay >= w.start {
+		return w.days[day]
+	}
+	return timeOfDay < w.end && w.days[(day+6)%7]
+}
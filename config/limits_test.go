@@ -0,0 +1,130 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsFromConfig(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  limits:
+    - window: "Mon..Fri 08:00..18:00"
+      limit-upload: 2000
+      nice: 10
+    - window: "Sat,Sun"
+      limit-download: 5000
+      nice: 0
+      ionice: true
+      ionice-level: 7
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "profile")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+
+	require.Len(t, profile.Limits, 2)
+	assert.Equal(t, "Mon..Fri 08:00..18:00", profile.Limits[0].Window)
+	assert.Equal(t, 2000, profile.Limits[0].LimitUpload)
+	assert.Equal(t, new(10), profile.Limits[0].Nice)
+	// nice 0 is set
+	assert.Equal(t, new(0), profile.Limits[1].Nice)
+	assert.Equal(t, 5000, profile.Limits[1].LimitDownload)
+	assert.True(t, profile.Limits[1].IONice)
+	assert.Equal(t, constants.DefaultIONiceClass, profile.Limits[1].GetIONiceClass())
+	assert.Equal(t, 7, profile.Limits[1].IONiceLevel)
+}
+
+func TestInvalidLimitsWindowInConfig(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  limits:
+    - window: "Mon..Fry"
+      limit-upload: 2000
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "profile")
+	assert.ErrorContains(t, err, `invalid configuration in profile 'profile': limits: invalid window "Mon..Fry"`)
+	assert.Nil(t, profile)
+}
+
+func TestTimeWindow(t *testing.T) {
+	// 2024-01-01 is a Monday
+	at := func(day int, hour, minute int) time.Time {
+		return time.Date(2024, time.January, day, hour, minute, 0, 0, time.Local)
+	}
+
+	testCases := []struct {
+		window  string
+		inside  []time.Time
+		outside []time.Time
+	}{
+		{
+			window:  "Mon..Fri 08:00..18:00",
+			inside:  []time.Time{at(1, 8, 0), at(5, 17, 59)},
+			outside: []time.Time{at(1, 7, 59), at(1, 18, 0), at(6, 12, 0)},
+		},
+		{
+			window:  "sat,SUN",
+			inside:  []time.Time{at(6, 0, 0), at(7, 23, 59)},
+			outside: []time.Time{at(5, 23, 59), at(8, 0, 0)},
+		},
+		{
+			window:  "Fri..Mon",
+			inside:  []time.Time{at(5, 12, 0), at(7, 12, 0), at(8, 12, 0)},
+			outside: []time.Time{at(2, 12, 0), at(4, 12, 0)},
+		},
+		{
+			window:  "22:00..06:00",
+			inside:  []time.Time{at(1, 22, 0), at(2, 5, 59)},
+			outside: []time.Time{at(1, 6, 0), at(1, 21, 59)},
+		},
+		{
+			// the hours after midnight belong to the day the window started
+			window:  "Friday 22:00..06:00",
+			inside:  []time.Time{at(5, 23, 0), at(6, 3, 0)},
+			outside: []time.Time{at(5, 3, 0), at(6, 23, 0)},
+		},
+		{
+			window:  "Mon,Wed..Thu 12:00..24:00",
+			inside:  []time.Time{at(1, 23, 59), at(3, 12, 0), at(4, 12, 0)},
+			outside: []time.Time{at(2, 12, 0), at(1, 11, 0)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.window, func(t *testing.T) {
+			window, err := ParseTimeWindow(tc.window)
+			require.NoError(t, err)
+			for _, inside := range tc.inside {
+				assert.True(t, window.Contains(inside), inside.String())
+			}
+			for _, outside := range tc.outside {
+				assert.False(t, window.Contains(outside), outside.String())
+			}
+		})
+	}
+}
+
+func TestInvalidTimeWindow(t *testing.T) {
+	for _, window := range []string{
+		"",
+		"Mon..Fri 08:00..18:00 extra",
+		"Monfri",
+		"Mon..Fry",
+		"08:00",
+		"08:00..25:00",
+		"08:60..09:00",
+		"08:00..08:00",
+		"08:00..18:00 Mon",
+	} {
+		t.Run(window, func(t *testing.T) {
+			_, err := ParseTimeWindow(window)
+			assert.ErrorContains(t, err, "invalid window")
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"time"
+
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/util/maybe"
+)
+
+// MountSection is a volume mounted while the profile runs
+type MountSection struct {
+	Device       string        `mapstructure:"device" examples:"//nas/backup;nas:/export/backup;/dev/sdb1" description:"Device, network share or URL of the volume to mount"`
+	MountPoint   string        `mapstructure:"mount-point" examples:"/mnt/nas" description:"Directory where the volume is mounted"`
+	Type         string        `mapstructure:"type" examples:"cifs;nfs;ext4" description:"Type of filesystem (\"-t\" option of the mount command)"`
+	Options      []string      `mapstructure:"options" examples:"ro;credentials=/root/.smbcredentials;vers=4" description:"Mount options (\"-o\" option of the mount command)"`
+	Mount        maybe.Bool    `mapstructure:"mount" default:"true" description:"Mount the volume when it is not mounted yet. Set to false when the volume is mounted by another service (e.g. an automount): resticprofile then waits until it is ready"`
+	ReadyFile    string        `mapstructure:"ready-file" examples:".ready;backup/config" description:"File (relative to the mount point) that must exist before the volume is considered ready"`
+	WaitTimeout  time.Duration `mapstructure:"wait-timeout" default:"30s" description:"Maximum time to wait until the volume is mounted and ready"`
+	UnmountAfter maybe.Bool    `mapstructure:"unmount-after" default:"true" description:"Unmount the volume once the profile finished, when it was mounted by resticprofile"`
+}
+
+func (m *MountSection) setRootPath(_ *Profile, rootPath string) {
+	m.Device = fixPath(m.Device, expandEnv)
+	m.MountPoint = fixPath(m.MountPoint, expandEnv, expandUserHome, absolutePrefix(rootPath))
+}
+
+// GetWaitTimeout returns the maximum time to wait until the volume is ready
+func (m *MountSection) GetWaitTimeout() time.Duration {
+	if m.WaitTimeout <= 0 {
+		return constants.DefaultMountWaitTimeout
+	}
+	return m.WaitTimeout
+}
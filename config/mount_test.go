@@ -0,0 +1,50 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountsFromConfig(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  mounts:
+    - device: //nas/backup
+      mount-point: /mnt/nas
+      type: cifs
+      options:
+        - credentials=/root/.smbcredentials
+        - vers=3.0
+      ready-file: .ready
+      wait-timeout: 1m
+      unmount-after: false
+    - mount-point: usb
+      mount: false
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "profile")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	profile.SetRootPath("/root")
+
+	require.Len(t, profile.Mounts, 2)
+	nas := profile.Mounts[0]
+	assert.Equal(t, "//nas/backup", nas.Device)
+	assert.Equal(t, "cifs", nas.Type)
+	assert.Equal(t, []string{"credentials=/root/.smbcredentials", "vers=3.0"}, nas.Options)
+	assert.Equal(t, ".ready", nas.ReadyFile)
+	assert.Equal(t, time.Minute, nas.GetWaitTimeout())
+	assert.True(t, nas.Mount.IsTrueOrUndefined())
+	assert.True(t, nas.UnmountAfter.IsStrictlyFalse())
+
+	usb := profile.Mounts[1]
+	assert.Equal(t, filepath.Join("/root", "usb"), usb.MountPoint)
+	assert.True(t, usb.Mount.IsStrictlyFalse())
+	assert.Equal(t, constants.DefaultMountWaitTimeout, usb.GetWaitTimeout())
+	assert.True(t, usb.UnmountAfter.IsTrueOrUndefined())
+}
@@ -0,0 +1,255 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// commandLineOverride is a profile setting defined on the command line with "--set profile.section.key=value"
+type commandLineOverride struct {
+	source  string   // original definition from the command line
+	profile string   // name of the profile
+	path    []string // path of the setting inside the profile
+	value   string
+}
+
+// key returns the path of the setting inside the profile, separated by dots
+func (o commandLineOverride) key() string {
+	return strings.Join(o.path, ".")
+}
+
+// WithOverrides sets profile settings from the command line. Each setting is defined as "profile.section.key=value".
+// Settings are applied after includes, inheritance and mixins but before the profile configuration is resolved
+func WithOverrides(settings []string) func(cfg *Config) {
+	return func(cfg *Config) {
+		cfg.settings = settings
+	}
+}
+
+// WithTemplateVars defines variables available as {{ .Vars.NAME }} in configuration templates. Each variable is defined as "NAME=value"
+func WithTemplateVars(vars []string) func(cfg *Config) {
+	return func(cfg *Config) {
+		cfg.vars = vars
+	}
+}
+
+// splitDefinition splits a "name=value" definition from the command line
+func splitDefinition(definition string) (name, value string, err error) {
+	var found bool
+	name, value, found = strings.Cut(definition, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" {
+		err = fmt.Errorf("invalid definition %q: expected syntax is \"name=value\"", definition)
+	}
+	return
+}
+
+// parseTemplateVars loads the template variables defined on the command line
+func (c *Config) parseTemplateVars() error {
+	c.templateVars = make(map[string]string, len(c.vars))
+	for _, definition := range c.vars {
+		name, value, err := splitDefinition(definition)
+		if err != nil {
+			return fmt.Errorf("--var: %w", err)
+		}
+		c.templateVars[name] = value
+	}
+	return nil
+}
+
+// parseOverrides loads the profile settings defined on the command line. It must be called after the configuration is loaded
+func (c *Config) parseOverrides() error {
+	c.overrides = nil
+	if len(c.settings) == 0 {
+		return nil
+	}
+	info := NewProfileInfo(true)
+
+	for _, definition := range c.settings {
+		key, value, err := splitDefinition(definition)
+		if err != nil {
+			return fmt.Errorf("--set: %w", err)
+		}
+		// profile names may contain dots: the longest existing name wins
+		profileName := ""
+		for i := strings.LastIndex(key, "."); i > 0; i = strings.LastIndex(key[:i], ".") {
+			if c.HasProfile(key[:i]) {
+				profileName = key[:i]
+				break
+			}
+		}
+		if profileName == "" {
+			return fmt.Errorf("--set %q: no profile matching %q, expected syntax is \"profile.section.key=value\"", definition, key)
+		}
+		path := strings.Split(key[len(profileName)+1:], ".")
+		if err = checkOverridePath(info, path); err != nil {
+			return fmt.Errorf("--set %q: %w", definition, err)
+		}
+		c.overrides = append(c.overrides, commandLineOverride{
+			source:  definition,
+			profile: profileName,
+			path:    path,
+			value:   value,
+		})
+	}
+	return nil
+}
+
+// checkOverridePath verifies the path of a setting exists in the profile
+func checkOverridePath(info ProfileInfo, path []string) error {
+	var set PropertySet = info
+	if section := info.SectionInfo(path[0]); section != nil {
+		if len(path) == 1 {
+			return fmt.Errorf("%q is a section, please specify a key inside the section", path[0])
+		}
+		if section.IsCommandSection() {
+			// unknown flags are passed to restic as is
+			set = &openPropertySet{PropertySet: section}
+		} else {
+			set = section
+		}
+		path = path[1:]
+	}
+
+	for i, name := range path {
+		if name == "" {
+			return fmt.Errorf("empty name in key %q", strings.Join(path, "."))
+		}
+		if set == nil {
+			return fmt.Errorf("%q has no property %q", strings.Join(path[:i], "."), name)
+		}
+		property := set.PropertyInfo(name)
+		if property == nil {
+			if set.IsClosed() {
+				return fmt.Errorf("unknown property %q", strings.Join(path[:i+1], "."))
+			}
+			// open sets (maps) accept any name but no nested property
+			set = nil
+			continue
+		}
+		if property.CanBePropertySet() {
+			set = property.PropertySet()
+		} else {
+			set = nil
+		}
+	}
+	return nil
+}
+
+// openPropertySet is a PropertySet accepting unknown properties
+type openPropertySet struct {
+	PropertySet
+}
+
+func (openPropertySet) IsClosed() bool { return false }
+
+// applyOverrides sets the profile settings defined on the command line.
+// Each setting is type checked against the profile structure
+func (c *Config) applyOverrides(profile *Profile) error {
+	content := make(map[string]any)
+	for _, override := range c.overrides {
+		if !strings.EqualFold(override.profile, profile.Name) {
+			continue
+		}
+		// type check on its own to report the faulty setting
+		decoder, err := c.newUnmarshaller(NewProfile(c, profile.Name))
+		if err == nil {
+			err = decoder.Decode(override.content())
+		}
+		if err != nil {
+			return fmt.Errorf("invalid setting --set %q: %w", override.source, err)
+		}
+		mergeOverride(content, override.path, override.value)
+		profile.commandLineKeys = append(profile.commandLineKeys, override.key())
+	}
+	if len(content) == 0 {
+		return nil
+	}
+
+	// the decoder writes into existing lists element by element: lists set on the command line replace the lists of the profile
+	for _, override := range c.overrides {
+		if strings.EqualFold(override.profile, profile.Name) {
+			clearList(reflect.ValueOf(profile), override.path)
+		}
+	}
+
+	decoder, err := c.newUnmarshaller(profile)
+	if err == nil {
+		err = decoder.Decode(content)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid command line settings for profile %q: %w", profile.Name, err)
+	}
+	return nil
+}
+
+// clearList empties the list field at path inside value, following the "mapstructure" names of the fields
+func clearList(value reflect.Value, path []string) bool {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return false
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct || len(path) == 0 {
+		return false
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if options == "squash" {
+			if clearList(value.Field(i), path) {
+				return true
+			}
+			continue
+		}
+		if name == "" || !strings.EqualFold(name, path[0]) {
+			continue
+		}
+		if len(path) > 1 {
+			return clearList(value.Field(i), path[1:])
+		}
+		if field.Type.Kind() == reflect.Slice && value.Field(i).CanSet() {
+			value.Field(i).SetZero()
+		}
+		return true
+	}
+	return false
+}
+
+// content returns the override as a nested structure
+func (o commandLineOverride) content() map[string]any {
+	content := map[string]any{o.path[len(o.path)-1]: o.value}
+	for i := len(o.path) - 2; i >= 0; i-- {
+		content = map[string]any{o.path[i]: content}
+	}
+	return content
+}
+
+// mergeOverride adds value at path inside content. The same setting repeated on the command line becomes a list of values
+func mergeOverride(content map[string]any, path []string, value string) {
+	for _, name := range path[:len(path)-1] {
+		nested, ok := content[name].(map[string]any)
+		if !ok {
+			nested = make(map[string]any)
+			content[name] = nested
+		}
+		content = nested
+	}
+	name := path[len(path)-1]
+	switch existing := content[name].(type) {
+	case nil:
+		content[name] = value
+	case string:
+		content[name] = []any{existing, value}
+	case []any:
+		content[name] = append(existing, value)
+	}
+}
+
+// CommandLineKeys returns the keys of the profile settings that were defined on the command line
+func (p *Profile) CommandLineKeys() []string {
+	return slices.Compact(slices.Sorted(slices.Values(p.commandLineKeys)))
+}
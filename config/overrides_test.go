@@ -0,0 +1,191 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandLineOverrides(t *testing.T) {
+	testConfig := `
+version = 2
+
+[mixins.tags]
+backup.tag = ["mixin"]
+
+[profiles.parent]
+repository = "local:/parent"
+verbose = 1
+[profiles.parent.backup]
+source = ["/parent"]
+check-before = true
+
+[profiles.child]
+inherit = "parent"
+use = "tags"
+[profiles.child.backup]
+source = ["/child"]
+
+[profiles."my.profile"]
+repository = "local:/dotted"
+
+[profiles.lists]
+[profiles.lists.backup]
+source = ["/a", "/b", "/c"]
+`
+	load := func(t *testing.T, settings ...string) (*Config, error) {
+		t.Helper()
+		return Load(bytes.NewBufferString(testConfig), FormatTOML, WithOverrides(settings))
+	}
+
+	t.Run("applied after inheritance and mixins", func(t *testing.T) {
+		c, err := load(t,
+			"child.verbose=2",
+			"child.backup.source=/override",
+			"child.backup.tag=cli",
+			"child.backup.check-before=false",
+			"child.env.MY_VAR=value",
+		)
+		require.NoError(t, err)
+
+		profile, err := c.GetProfile("child")
+		require.NoError(t, err)
+		assert.Equal(t, 2, profile.Verbose)
+		assert.Equal(t, []string{"/override"}, profile.Backup.Source)
+		assert.Equal(t, "cli", profile.Backup.OtherFlags["tag"])
+		assert.False(t, profile.Backup.CheckBefore)
+		assert.Equal(t, "local:/parent", profile.Repository.Value())
+		assert.Equal(t, "value", profile.Environment["MY_VAR"].Value())
+		assert.Equal(t, []string{"backup.check-before", "backup.source", "backup.tag", "env.MY_VAR", "verbose"}, profile.CommandLineKeys())
+
+		parent, err := c.GetProfile("parent")
+		require.NoError(t, err)
+		assert.Equal(t, 1, parent.Verbose)
+		assert.Empty(t, parent.CommandLineKeys())
+	})
+
+	t.Run("repeated setting is a list", func(t *testing.T) {
+		c, err := load(t, "parent.backup.source=/one", "parent.backup.source=/two")
+		require.NoError(t, err)
+
+		profile, err := c.GetProfile("parent")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/one", "/two"}, profile.Backup.Source)
+		assert.Equal(t, []string{"backup.source"}, profile.CommandLineKeys())
+	})
+
+	t.Run("list replaced", func(t *testing.T) {
+		c, err := load(t, "lists.backup.source=/x")
+		require.NoError(t, err)
+		profile, err := c.GetProfile("lists")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/x"}, profile.Backup.Source)
+
+		c, err = load(t, "lists.backup.source=/x", "lists.backup.source=/y")
+		require.NoError(t, err)
+		profile, err = c.GetProfile("lists")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/x", "/y"}, profile.Backup.Source)
+	})
+
+	t.Run("profile name with dots", func(t *testing.T) {
+		c, err := load(t, "my.profile.repository=local:/cli")
+		require.NoError(t, err)
+
+		profile, err := c.GetProfile("my.profile")
+		require.NoError(t, err)
+		assert.Equal(t, "local:/cli", profile.Repository.Value())
+	})
+
+	t.Run("resolved like the configuration", func(t *testing.T) {
+		dir := t.TempDir()
+		configFile := filepath.Join(dir, "profiles.toml")
+		require.NoError(t, os.WriteFile(configFile, []byte(testConfig), 0o600))
+		c, err := LoadFile(configFile, "", WithOverrides([]string{"parent.password-file=relative"}))
+		require.NoError(t, err)
+
+		profile, err := c.GetProfile("parent")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "relative"), profile.PasswordFile)
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		fixtures := []struct {
+			setting, message string
+		}{
+			{setting: "parent.verbose", message: `expected syntax is "name=value"`},
+			{setting: "=value", message: `expected syntax is "name=value"`},
+			{setting: "unknown.verbose=1", message: `no profile matching "unknown.verbose"`},
+			{setting: "parent=1", message: `no profile matching "parent"`},
+			{setting: "parent.backup=1", message: `"backup" is a section`},
+			{setting: "parent.unknown.nested=1", message: `"unknown" has no property "nested"`},
+			{setting: "parent.backup.unknown.nested=1", message: `"unknown" has no property "nested"`},
+			{setting: "parent.verbose.nested=1", message: `"verbose" has no property "nested"`},
+			{setting: "parent.backup..source=1", message: `empty name`},
+		}
+		for _, fixture := range fixtures {
+			t.Run(fixture.setting, func(t *testing.T) {
+				_, err := load(t, fixture.setting)
+				assert.ErrorContains(t, err, fixture.message)
+			})
+		}
+	})
+
+	t.Run("type checked", func(t *testing.T) {
+		fixtures := []string{
+			"parent.verbose=not-a-number",
+			"parent.initialize=not-a-bool",
+			"parent.backup.check-before=maybe",
+			"parent.stream-error=string",
+		}
+		for _, setting := range fixtures {
+			t.Run(setting, func(t *testing.T) {
+				c, err := load(t, setting)
+				require.NoError(t, err)
+
+				_, err = c.GetProfile("parent")
+				assert.ErrorContains(t, err, "invalid setting --set \""+setting+"\"")
+			})
+		}
+	})
+
+	t.Run("shown with origin", func(t *testing.T) {
+		c, err := load(t, "parent.backup.source=/cli", "parent.verbose=3")
+		require.NoError(t, err)
+
+		profile, err := c.GetProfile("parent")
+		require.NoError(t, err)
+
+		buffer := &strings.Builder{}
+		require.NoError(t, ShowStruct(buffer, profile, "profile parent"))
+		output := buffer.String()
+		assert.Regexp(t, `verbose:\s+3\s+\(command line\)`, output)
+		assert.Regexp(t, `source:\s+/cli\s+\(command line\)`, output)
+		assert.Regexp(t, `check-before:\s+true\n`, output)
+	})
+}
+
+func TestCommandLineTemplateVars(t *testing.T) {
+	testConfig := `
+version = 2
+
+[profiles.default]
+repository = "local:/{{ .Vars.REPO }}"
+password-file = "{{ .Vars.MISSING | or "key" }}"
+`
+	c, err := Load(bytes.NewBufferString(testConfig), FormatTOML, WithTemplateVars([]string{"REPO=backup=1"}))
+	require.NoError(t, err)
+
+	profile, err := c.GetProfile("default")
+	require.NoError(t, err)
+	assert.Equal(t, "local:/backup=1", profile.Repository.Value())
+	assert.Equal(t, "key", profile.PasswordFile)
+
+	_, err = Load(bytes.NewBufferString(testConfig), FormatTOML, WithTemplateVars([]string{"REPO"}))
+	assert.ErrorContains(t, err, "--var")
+}
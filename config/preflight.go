@@ -0,0 +1,47 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/creativeprojects/resticprofile/constants"
+)
+
+// Modes of a preflight check
+const (
+	PreflightFail = "fail"
+	PreflightWarn = "warn"
+	PreflightSkip = "skip"
+)
+
+// PreflightSection contains the checks running before the restic commands of a profile
+type PreflightSection struct {
+	Sources       string   `mapstructure:"sources" default:"skip" enum:"fail;warn;skip" description:"Check that the backup source paths exist and are not empty (e.g. an unmounted network share)"`
+	FreeSpace     string   `mapstructure:"free-space" default:"skip" enum:"fail;warn;skip" description:"Check the free space available for the restic cache directory and the temporary directory"`
+	MinFreeSpace  uint64   `mapstructure:"min-free-space" default:"1024" description:"Minimum free space (in MB) required by the \"free-space\" check"`
+	Repository    string   `mapstructure:"repository" default:"skip" enum:"fail;warn;skip" description:"Check that the repository is reachable (using \"restic cat config\")"`
+	Mounted       string   `mapstructure:"mounted" default:"skip" enum:"fail;warn;skip" description:"Check that the paths of \"mount-points\" are mounted"`
+	MountPoints   []string `mapstructure:"mount-points" examples:"/mnt/nas;/media/backup" description:"Mount points required by the \"mounted\" check"`
+	PasswordFiles string   `mapstructure:"password-files" default:"skip" enum:"fail;warn;skip" description:"Check that the password files of the profile are readable"`
+}
+
+func (p *PreflightSection) setRootPath(_ *Profile, rootPath string) {
+	p.MountPoints = fixPaths(p.MountPoints, expandEnv, expandUserHome, absolutePrefix(rootPath))
+}
+
+// GetMinFreeSpace returns the minimum free space in MB
+func (p *PreflightSection) GetMinFreeSpace() uint64 {
+	if p.MinFreeSpace == 0 {
+		return constants.DefaultPreflightMinFreeSpace
+	}
+	return p.MinFreeSpace
+}
+
+// PreflightMode returns the mode of a preflight check: fail, warn or skip (the default)
+func PreflightMode(mode string) string {
+	switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+	case PreflightFail, PreflightWarn:
+		return mode
+	default:
+		return PreflightSkip
+	}
+}
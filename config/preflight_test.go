@@ -0,0 +1,53 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreflightFromConfig(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  preflight:
+    sources: fail
+    free-space: warn
+    min-free-space: 2048
+    mounted: fail
+    mount-points:
+      - /mnt/nas
+      - backup
+    password-files: skip
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "profile")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	profile.SetRootPath("/root")
+
+	preflight := profile.Preflight
+	require.NotNil(t, preflight)
+	assert.Equal(t, PreflightFail, PreflightMode(preflight.Sources))
+	assert.Equal(t, PreflightWarn, PreflightMode(preflight.FreeSpace))
+	assert.Equal(t, uint64(2048), preflight.GetMinFreeSpace())
+	assert.Equal(t, PreflightSkip, PreflightMode(preflight.Repository))
+	assert.Equal(t, PreflightFail, PreflightMode(preflight.Mounted))
+	require.Len(t, preflight.MountPoints, 2)
+	assert.Equal(t, filepath.Join("/root", "backup"), preflight.MountPoints[1])
+	assert.Equal(t, PreflightSkip, PreflightMode(preflight.PasswordFiles))
+}
+
+func TestPreflightMode(t *testing.T) {
+	assert.Equal(t, PreflightFail, PreflightMode("Fail"))
+	assert.Equal(t, PreflightWarn, PreflightMode(" warn "))
+	assert.Equal(t, PreflightSkip, PreflightMode("skip"))
+	assert.Equal(t, PreflightSkip, PreflightMode(""))
+	assert.Equal(t, PreflightSkip, PreflightMode("unknown"))
+}
+
+func TestPreflightDefaultMinFreeSpace(t *testing.T) {
+	assert.Equal(t, uint64(constants.DefaultPreflightMinFreeSpace), (&PreflightSection{}).GetMinFreeSpace())
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"maps"
 	"os"
@@ -67,11 +68,14 @@ type resolver interface {
 type Profile struct {
 	RunShellCommandsSection `mapstructure:",squash"`
 	OtherFlagsSection       `mapstructure:",squash"`
+	ActivationSection       `mapstructure:",squash"`
 
 	config               *Config
 	resticVersion        *semver.Version
+	commandLineKeys      []string
 	Name                 string
 	Description          string                       `mapstructure:"description" description:"Describes the profile"`
+	Labels               map[string]string            `mapstructure:"labels" description:"Labels to select the profile with \"--select label=value\" when running a command on several profiles - see https://creativeprojects.github.io/resticprofile/usage/selection/"`
 	BaseDir              string                       `mapstructure:"base-dir" description:"Sets the working directory for this profile. The profile will fail when the working directory cannot be changed. Leave empty to use the current directory instead"`
 	Quiet                bool                         `mapstructure:"quiet" argument:"quiet"`
 	Verbose              int                          `mapstructure:"verbose" argument:"verbose"`
@@ -84,10 +88,15 @@ type Profile struct {
 	CACert               string                       `mapstructure:"cacert" argument:"cacert"`
 	TLSClientCert        string                       `mapstructure:"tls-client-cert" argument:"tls-client-cert"`
 	Initialize           bool                         `mapstructure:"initialize" default:"" description:"Initialize the restic repository if missing"`
-	Inherit              string                       `mapstructure:"inherit" show:"noshow" description:"Name of the profile to inherit all of the settings from"`
+	Inherit              []string                     `mapstructure:"inherit" show:"noshow" description:"Name of the profile(s) to inherit all of the settings from. Multiple parents are merged in order: settings of a parent override the ones of the previous parents"`
+	Abstract             bool                         `mapstructure:"abstract" description:"An abstract profile can only be inherited: it never runs, is never scheduled and is not listed by the \"profiles\" command"`
 	Lock                 string                       `mapstructure:"lock" description:"Path to the lock file to use with resticprofile locks"`
 	ForceLock            bool                         `mapstructure:"force-inactive-lock" description:"Allows to lock when the existing lock is considered stale"`
 	StreamError          []StreamErrorSection         `mapstructure:"stream-error" description:"Run shell command(s) when a pattern matches the stderr of restic"`
+	Preflight            *PreflightSection            `mapstructure:"preflight" description:"Checks running before the restic commands of the profile - see https://creativeprojects.github.io/resticprofile/usage/preflight/"`
+	Wake                 *WakeSection                 `mapstructure:"wake" description:"Wake up a remote host (Wake-on-LAN) and wait until it is reachable, before running the profile - see https://creativeprojects.github.io/resticprofile/usage/wake/"`
+	Mounts               []MountSection               `mapstructure:"mounts" description:"Volumes mounted before running the profile, and unmounted once it finished - see https://creativeprojects.github.io/resticprofile/usage/mounts/"`
+	Limits               []LimitsSection              `mapstructure:"limits" description:"Bandwidth and priority of restic during time windows (the first window matching the time restic starts applies) - see https://creativeprojects.github.io/resticprofile/usage/limits/"`
 	StatusFile           string                       `mapstructure:"status-file" description:"Path to the status file to update with a summary of last restic command result"`
 	PrometheusSaveToFile string                       `mapstructure:"prometheus-save-to-file" description:"Path to the prometheus metrics file to update with a summary of the last restic command result"`
 	PrometheusPush       string                       `mapstructure:"prometheus-push" format:"uri" description:"URL of the prometheus push gateway to send the summary of the last restic command result to"`
@@ -104,6 +113,7 @@ type Profile struct {
 	Prune                *GenericSectionWithSchedule  `mapstructure:"prune"`
 	Forget               *GenericSectionWithSchedule  `mapstructure:"forget"`
 	Copy                 *CopySection                 `mapstructure:"copy"`
+	Commands             map[string]*CommandSection   `mapstructure:"commands" description:"Named commands running a sequence of restic commands - see https://creativeprojects.github.io/resticprofile/configuration/commands/"`
 	OtherSections        map[string]*GenericSection   `show:",remain"`
 }
 
@@ -112,8 +122,13 @@ type GenericSection struct {
 	OtherFlagsSection       `mapstructure:",squash"`
 	RunShellCommandsSection `mapstructure:",squash"`
 	SendMonitoringSections  `mapstructure:",squash"`
+	ActivationSection       `mapstructure:",squash"`
+
+	Retry *RetrySection `mapstructure:"retry" description:"Retry the restic command after a transient failure - see https://creativeprojects.github.io/resticprofile/usage/retry/"`
 }
 
+func (g *GenericSection) GetRetry() *RetrySection { return g.Retry }
+
 func (g *GenericSection) setRootPath(p *Profile, rootPath string) {
 	g.SendMonitoringSections.setRootPath(p, rootPath)
 }
@@ -192,6 +207,12 @@ type BackupSection struct {
 	FilesFromVerbatim []string `mapstructure:"files-from-verbatim" argument:"files-from-verbatim"`
 	ExtendedStatus    bool     `mapstructure:"extended-status" argument:"json"`
 	NoErrorOnWarning  bool     `mapstructure:"no-error-on-warning" description:"Do not fail the backup when some files could not be read"`
+	SkipIfUnchanged   bool     `mapstructure:"skip-if-unchanged" description:"Skip the backup when the size and modification time of the files in \"source\" did not change since the last successful backup - see https://creativeprojects.github.io/resticprofile/usage/skip_unchanged/"`
+	ManifestFile      string   `mapstructure:"manifest-file" description:"Path to the manifest of the sources used by \"skip-if-unchanged\". Defaults to a file in the user state directory"`
+
+	StdinSources   []StdinSourceSection   `mapstructure:"stdin-sources" description:"Named sources backed up from the output of their commands, each one in a snapshot of its own - see https://creativeprojects.github.io/resticprofile/usage/stdin_sources/"`
+	SourceSnapshot *SourceSnapshotSection `mapstructure:"source-snapshot" description:"Back up the sources from a read-only filesystem snapshot (btrfs, LVM or ZFS) - see https://creativeprojects.github.io/resticprofile/usage/source_snapshot/"`
+	Anomaly        *AnomalySection        `mapstructure:"anomaly" description:"Detect anomalies in the backup summary compared with the previous backups - see https://creativeprojects.github.io/resticprofile/usage/anomaly/"`
 }
 
 func (s *BackupSection) IsEmpty() bool { return s == nil }
@@ -203,6 +224,7 @@ func (b *BackupSection) resolve(profile *Profile) {
 	if len(b.StdinCommand) > 0 {
 		b.UseStdin = true
 	}
+	b.resolveStdinSources()
 	// Resolve symlinks if we send relative paths to restic (to match paths in snapshots)
 	if b.SourceRelative {
 		if dir := strings.TrimSpace(profile.BaseDir); dir != "" {
@@ -237,6 +259,14 @@ func (s *BackupSection) setRootPath(p *Profile, rootPath string) {
 	s.FilesFromVerbatim = fixPaths(s.FilesFromVerbatim, expandEnv, expandUserHome, absolutePrefix(rootPath))
 	s.Exclude = fixPaths(s.Exclude, expandEnv, expandUserHome)
 	s.Iexclude = fixPaths(s.Iexclude, expandEnv, expandUserHome)
+	s.ManifestFile = fixPath(s.ManifestFile, expandEnv, expandUserHome, absolutePrefix(rootPath))
+
+	if s.SourceSnapshot != nil {
+		s.SourceSnapshot.setRootPath(p, rootPath)
+	}
+	if s.Anomaly != nil {
+		s.Anomaly.setRootPath(p, rootPath)
+	}
 }
 
 // RetentionSection contains the specific configuration to
@@ -247,6 +277,8 @@ type RetentionSection struct {
 
 	BeforeBackup maybe.Bool `mapstructure:"before-backup" description:"Apply retention before starting the backup command"`
 	AfterBackup  maybe.Bool `mapstructure:"after-backup" description:"Apply retention after the backup command succeeded. Defaults to true in configuration format v2 if any \"keep-*\" flag is set and \"before-backup\" is unset"`
+
+	Safety *RetentionSafetySection `mapstructure:"safety" description:"Refuse to apply the retention when it would remove too many snapshots - see https://creativeprojects.github.io/resticprofile/configuration/retention_safety/"`
 }
 
 func (r *RetentionSection) IsEmpty() bool { return r == nil }
@@ -311,6 +343,32 @@ func (s *GenericSectionWithSchedule) setRootPath(p *Profile, rootPath string) {
 
 func (s *GenericSectionWithSchedule) IsEmpty() bool { return s == nil }
 
+// CommandSection declares a composite command running a sequence of restic commands (steps)
+type CommandSection struct {
+	RunShellCommandsSection `mapstructure:",squash"`
+	SendMonitoringSections  `mapstructure:",squash"`
+	ActivationSection       `mapstructure:",squash"`
+	ScheduleBaseSection     `mapstructure:",squash"`
+
+	Description string        `mapstructure:"description" description:"Describes the command"`
+	Steps       []CommandStep `mapstructure:"steps" description:"Restic commands to run in order. Each step uses the flags of the profile section of the same name, overridden by the flags declared in the step"`
+}
+
+func (c *CommandSection) setRootPath(p *Profile, rootPath string) {
+	c.SendMonitoringSections.setRootPath(p, rootPath)
+	c.ScheduleBaseSection.setRootPath(p, rootPath)
+}
+
+func (c *CommandSection) IsEmpty() bool { return c == nil }
+
+// CommandStep is a restic command running as a step of a composite command
+type CommandStep struct {
+	OtherFlagsSection `mapstructure:",squash"`
+
+	Command         string `mapstructure:"command" examples:"backup;forget;prune;check" description:"Restic command to run"`
+	ContinueOnError bool   `mapstructure:"continue-on-error" description:"Run the next steps when this step fails. The command still fails once all steps ran"`
+}
+
 // ScheduleBaseSection contains the parameters for scheduling a command (backup, check, forget, etc.)
 type ScheduleBaseSection struct {
 	scheduleConfig                  *ScheduleConfig
@@ -467,7 +525,8 @@ func (s *CopySection) getCommandFlags(profile *Profile) (flags *shell.Args) {
 			// restic < 0.14: repo2, password-file2, etc. is the destination, repo, password-file, etc. the source
 			for name, value := range repositoryArgs {
 				if len(value) > 0 {
-					flags.AddFlag(fmt.Sprintf("%s2", name), shell.NewArg(value, shell.ArgConfigEscape))
+					confidential := name == constants.ParameterRepository && s.ToRepository.IsConfidential()
+					flags.AddFlag(fmt.Sprintf("%s2", name), shell.NewArg(value, shell.ArgConfigEscape, shell.NewConfidentialArgOption(confidential)))
 				}
 			}
 		} else {
@@ -477,7 +536,8 @@ func (s *CopySection) getCommandFlags(profile *Profile) (flags *shell.Args) {
 			}
 			for name, value := range repositoryArgs {
 				if len(value) > 0 {
-					flags.AddFlag(name, shell.NewArg(value, shell.ArgConfigEscape))
+					confidential := name == constants.ParameterRepository && s.ToRepository.IsConfidential()
+					flags.AddFlag(name, shell.NewArg(value, shell.ArgConfigEscape, shell.NewConfidentialArgOption(confidential)))
 				}
 			}
 		}
@@ -592,9 +652,39 @@ func (p *Profile) fillOtherSections() {
 	}
 }
 
+// verifyCompositeCommands removes the composite commands that cannot run
+func (p *Profile) verifyCompositeCommands() {
+	sections := p.allSectionStructs()
+	for name, command := range p.Commands {
+		var err error
+		_, isSection := sections[name]
+		_, isOtherSection := p.OtherSections[name]
+		if isSection || isOtherSection {
+			err = fmt.Errorf("%q is already the name of a restic command or a profile section", name)
+		} else if command != nil {
+			if len(command.Steps) == 0 {
+				err = errors.New("no steps defined")
+			}
+			for index, step := range command.Steps {
+				if _, found := restic.GetCommandForVersion(step.Command, restic.AnyVersion, true); !found {
+					err = fmt.Errorf("unknown restic command %q in step %d", step.Command, index+1)
+					break
+				}
+			}
+		}
+		if err != nil {
+			delete(p.Commands, name)
+			if p.hasConfig() {
+				p.config.reportFailedSection(fmt.Sprintf("commands.%s", name), err)
+			}
+		}
+	}
+}
+
 // ResolveConfiguration resolves dependencies between profile config flags
 func (p *Profile) ResolveConfiguration() {
 	p.fillOtherSections()
+	p.verifyCompositeCommands()
 
 	// Resolve paths that do not depend on root path
 	p.BaseDir = fixPath(p.BaseDir, expandEnv, expandUserHome)
@@ -658,6 +748,12 @@ func (p *Profile) SetRootPath(rootPath string) {
 	for _, s := range GetSectionsWith[relativePath](p) {
 		s.setRootPath(p, rootPath)
 	}
+	if p.Preflight != nil {
+		p.Preflight.setRootPath(p, rootPath)
+	}
+	for index := range p.Mounts {
+		p.Mounts[index].setRootPath(p, rootPath)
+	}
 
 	// Handle dynamic flags dealing with paths that are relative to root path
 	filepathFlags := []string{
@@ -803,6 +899,9 @@ func (p *Profile) replaceWithRepositoryFile(repository *ConfidentialValue, repos
 	}
 	if global == nil || global.NoAutoRepositoryFile.IsTrue() {
 		return
+	} else if global.UsePipeSecrets() {
+		clog.Trace(`"repository" is passed to restic through a pipe (pipe-secrets)`)
+		return
 	} else if global.NoAutoRepositoryFile.IsUndefined() {
 		env := p.GetEnvironment(true)
 		if len(env.Get("RESTIC_REPOSITORY")) > 0 || len(env.Get("RESTIC_REPOSITORY_FILE")) > 0 {
@@ -901,6 +1000,23 @@ func (p *Profile) GetCopySnapshotIDs() []string {
 	return p.Copy.Snapshots
 }
 
+// MatchLabels returns true when the profile has all the labels of the selector (label names are case-insensitive)
+func (p *Profile) MatchLabels(selector map[string]string) bool {
+	for name, value := range selector {
+		found := false
+		for label, labelValue := range p.Labels {
+			if strings.EqualFold(label, name) && labelValue == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 // DefinedCommands returns all commands (also called sections) defined in the profile (backup, check, forget, etc.)
 func (p *Profile) DefinedCommands() []string {
 	return slices.Sorted(maps.Keys(GetSectionsWith[any](p)))
@@ -924,9 +1040,32 @@ func (p *Profile) AllSections() (sections map[string]any) {
 	for name, section := range p.OtherSections {
 		sections[name] = section
 	}
+	for name, section := range p.Commands {
+		sections[name] = section
+	}
+	return
+}
+
+// GetCompositeCommand returns the command declared in the "commands" section of the profile
+func (p *Profile) GetCompositeCommand(name string) (command *CommandSection, found bool) {
+	command, found = p.Commands[name]
+	found = found && command != nil
 	return
 }
 
+// GetCommandStepFlags returns the flags of the restic command of the step, overridden by the flags declared in the step
+func (p *Profile) GetCommandStepFlags(step *CommandStep) *shell.Args {
+	flags := p.GetCommandFlags(step.Command)
+	// a flag without value in the step (false, 0 or empty) removes the flag of the section
+	for name, value := range step.GetOtherFlags() {
+		if _, hasValue := stringifyValueOf(value); !hasValue {
+			flags.Remove(name)
+		}
+	}
+	addArgsFromOtherFlags(flags, p, step)
+	return flags
+}
+
 // SchedulableCommands returns all command names that could have a schedule
 func (p *Profile) SchedulableCommands() []string {
 	return slices.Sorted(maps.Keys(GetDeclaredSectionsWith[scheduling](p)))
@@ -992,6 +1131,42 @@ func (p *Profile) GetMonitoringSections(command string) (monitoring SendMonitori
 	return
 }
 
+// GetRetry returns the retry policy of the section of command, or nil when the command is not retried
+func (p *Profile) GetRetry(command string) *RetrySection {
+	if section, ok := GetSectionWith[Retryable](p, command); ok {
+		if retry := section.GetRetry(); !retry.IsEmpty() {
+			return retry
+		}
+	}
+	return nil
+}
+
+// validate reports the errors in the configuration of the profile which cannot be found when decoding it
+func (p *Profile) validate() error {
+	var errs []error
+	sections := GetSectionsWith[Retryable](p)
+	for _, name := range slices.Sorted(maps.Keys(sections)) {
+		if err := sections[name].GetRetry().compile(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	for index := range p.Limits {
+		if err := p.Limits[index].parseWindow(); err != nil {
+			errs = append(errs, fmt.Errorf("limits: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetActivationSections returns the activation conditions of the profile, followed by the ones of the section of command (if any)
+func (p *Profile) GetActivationSections(command string) []*ActivationSection {
+	sections := []*ActivationSection{p.GetActivation()}
+	if section, ok := GetSectionWith[Activation](p, command); ok {
+		sections = append(sections, section.GetActivation())
+	}
+	return sections
+}
+
 func (o *Profile) Kind() string {
 	return constants.SchedulableKindProfile
 }
@@ -217,6 +217,141 @@ inherit = "parent"
 	}
 }
 
+func TestInheritFromMultipleParents(t *testing.T) {
+	testConfig := `
+version = 2
+[profiles.base]
+abstract = true
+repository = "base"
+run-before = ["base"]
+[profiles.base.env]
+first = "base"
+second = "base"
+[profiles.base.backup]
+source = ["/base"]
+tag = ["base"]
+
+[profiles.remote]
+abstract = true
+repository = "remote"
+"run-before..." = "remote"
+[profiles.remote.env]
+second = "remote"
+third = "remote"
+[profiles.remote.backup]
+source = ["/remote"]
+
+[profiles.profile]
+inherit = ["base", "remote"]
+"...run-before" = "profile"
+[profiles.profile.env]
+third = "profile"
+`
+	config, err := Load(bytes.NewBufferString(testConfig), "toml")
+	require.NoError(t, err)
+
+	// rerun on same config instance to ensure inheritance returns consistent results
+	for range 3 {
+		profile, err := config.getProfile("profile")
+		require.NoError(t, err)
+		require.NotNil(t, profile)
+
+		assert.False(t, profile.Abstract)
+		assert.Equal(t, "remote", profile.Repository.String())
+		assert.Equal(t, []string{"profile", "base", "remote"}, profile.RunBefore)
+		// maps are merged key by key
+		env := make(map[string]string)
+		for key, value := range profile.Environment {
+			env[key] = value.Value()
+		}
+		assert.Equal(t, map[string]string{"first": "base", "second": "remote", "third": "profile"}, env)
+		// lists are replaced
+		require.NotNil(t, profile.Backup)
+		assert.Equal(t, []string{"/remote"}, profile.Backup.Source)
+		assert.Equal(t, []any{"base"}, profile.Backup.OtherFlags["tag"])
+	}
+
+	base, err := config.getProfile("base")
+	require.NoError(t, err)
+	assert.True(t, base.Abstract)
+}
+
+func TestInheritanceDiamond(t *testing.T) {
+	testConfig := `
+version = 2
+[profiles.root]
+repository = "root"
+[profiles.left]
+inherit = "root"
+password-file = "left"
+[profiles.right]
+inherit = "root"
+initialize = true
+[profiles.profile]
+inherit = ["left", "right"]
+`
+	profile, err := getProfile("toml", testConfig, "profile", "")
+	require.NoError(t, err)
+	assert.Equal(t, "root", profile.Repository.String())
+	assert.Equal(t, "left", profile.PasswordFile)
+	assert.True(t, profile.Initialize)
+}
+
+func TestInheritanceCycle(t *testing.T) {
+	testConfig := `
+version = 2
+[profiles.first]
+inherit = "second"
+[profiles.second]
+inherit = ["other", "third"]
+[profiles.third]
+inherit = "FIRST"
+[profiles.other]
+[profiles.self]
+inherit = "self"
+`
+	_, err := getProfile("toml", testConfig, "first", "")
+	assert.EqualError(t, err, "error in profile 'third': inheritance cycle detected: first -> second -> third -> FIRST")
+
+	_, err = getProfile("toml", testConfig, "self", "")
+	assert.EqualError(t, err, "error in profile 'self': inheritance cycle detected: self -> self")
+
+	v1Config := `
+[first]
+inherit = "second"
+[second]
+inherit = "first"
+`
+	_, err = getProfile("toml", v1Config, "first", "")
+	assert.EqualError(t, err, "error in profile 'second': inheritance cycle detected: first -> second -> first")
+}
+
+func TestInheritFromMultipleParentsRequiresV2(t *testing.T) {
+	testConfig := `
+[parent1]
+[parent2]
+[profile]
+inherit = ["parent1", "parent2"]
+`
+	_, err := getProfile("toml", testConfig, "profile", "")
+	assert.EqualError(t, err, "error in profile 'profile': inheriting from multiple profiles requires configuration version 2")
+}
+
+func TestAbstractIsNotInherited(t *testing.T) {
+	runForVersions(t, func(t *testing.T, version, prefix string) {
+		testConfig := version + `
+[` + prefix + `parent]
+abstract = true
+[` + prefix + `profile]
+inherit = "parent"
+`
+		profile, err := getProfile("toml", testConfig, "profile", "")
+		require.NoError(t, err)
+		assert.False(t, profile.Abstract)
+		assert.NotContains(t, profile.OtherFlags, "abstract")
+	})
+}
+
 func TestProfileCommonFlags(t *testing.T) {
 	runForVersions(t, func(t *testing.T, version, prefix string) {
 		t.Helper()
@@ -621,6 +756,104 @@ source = "` + sourcePattern + `"
 	assert.Equal(t, sources, profile.Backup.Source)
 }
 
+func TestSkipIfUnchangedInBackup(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  backup:
+    source: /home
+    skip-if-unchanged: true
+    manifest-file: manifest.json
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "profile")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	profile.SetRootPath("/root")
+
+	assert.True(t, profile.Backup.SkipIfUnchanged)
+	assert.Equal(t, filepath.FromSlash("/root/manifest.json"), profile.Backup.ManifestFile)
+
+	// not flags of the backup command
+	flags := profile.GetCommandFlags(constants.CommandBackup).ToMap()
+	assert.NotContains(t, flags, "skip-if-unchanged")
+	assert.NotContains(t, flags, "manifest-file")
+}
+
+func TestStdinSourcesInBackup(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  backup:
+    tag: server
+    stdin-sources:
+      - name: postgres
+        command: pg_dumpall
+        tag: database
+        continue-on-error: true
+      - command:
+          - mysqldump --all-databases
+        filename: mysql.sql
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "profile")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+
+	assert.Equal(t, []StdinSourceSection{
+		{Name: "postgres", Command: []string{"pg_dumpall"}, Filename: "postgres", Tag: []string{"database"}, ContinueOnError: true},
+		{Name: "stdin-2", Command: []string{"mysqldump --all-databases"}, Filename: "mysql.sql"},
+	}, profile.Backup.StdinSources)
+
+	// not a flag of the backup command
+	flags := profile.GetCommandFlags(constants.CommandBackup).ToMap()
+	assert.NotContains(t, flags, "stdin-sources")
+}
+
+func TestSourceSnapshotInBackup(t *testing.T) {
+	testConfig := `
+version: "1"
+btrfs:
+  backup:
+    source: /home
+    source-snapshot:
+      type: btrfs
+      volume: home
+lvm:
+  backup:
+    source: /home
+    source-snapshot:
+      type: lvm
+      volume: vg0/home
+      path: /home
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "btrfs")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	profile.SetRootPath("/root")
+
+	snapshot := profile.Backup.SourceSnapshot
+	require.NotNil(t, snapshot)
+	assert.Equal(t, filepath.FromSlash("/root/home"), snapshot.Volume)
+	assert.Equal(t, filepath.FromSlash("/root/home"), snapshot.GetPath())
+	assert.Equal(t, filepath.FromSlash("/root/home/.resticprofile-btrfs"), snapshot.GetMount(profile.Name))
+
+	profile, err = getResolvedProfile("yaml", testConfig, "lvm")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	profile.SetRootPath("/root")
+
+	snapshot = profile.Backup.SourceSnapshot
+	require.NotNil(t, snapshot)
+	assert.Equal(t, "vg0/home", snapshot.Volume)
+	assert.Equal(t, "resticprofile-lvm", snapshot.GetName(profile.Name))
+	assert.Equal(t, filepath.Join(os.TempDir(), "resticprofile-lvm"), snapshot.GetMount(profile.Name))
+	assert.Equal(t, constants.DefaultSourceSnapshotSize, snapshot.GetSize())
+	assert.Equal(t, constants.DefaultSourceSnapshotOptions, snapshot.GetMountOptions())
+
+	// not a flag of the backup command
+	flags := profile.GetCommandFlags(constants.CommandBackup).ToMap()
+	assert.NotContains(t, flags, "source-snapshot")
+}
+
 func TestResolveSourcesWithFlagPrefixInBackup(t *testing.T) {
 	backupSource := func(t *testing.T, source string) []string {
 		t.Helper()
@@ -1746,3 +1979,80 @@ func getExamplesDirectory(t *testing.T) string {
 	t.Skip("examples directory not found")
 	return ""
 }
+
+func TestMatchLabels(t *testing.T) {
+	profile := NewProfile(nil, "profile")
+	profile.Labels = map[string]string{"env": "prod", "site": "paris"}
+
+	assert.True(t, profile.MatchLabels(nil))
+	assert.True(t, profile.MatchLabels(map[string]string{"env": "prod"}))
+	assert.True(t, profile.MatchLabels(map[string]string{"ENV": "prod", "site": "paris"}))
+	assert.False(t, profile.MatchLabels(map[string]string{"env": "Prod"}))
+	assert.False(t, profile.MatchLabels(map[string]string{"env": "prod", "tier": "1"}))
+	assert.False(t, NewProfile(nil, "empty").MatchLabels(map[string]string{"env": "prod"}))
+}
+
+func TestCompositeCommands(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  check:
+    read-data: true
+  commands:
+    maintenance:
+      description: weekly maintenance
+      schedule: weekly
+      run-after: "echo done"
+      steps:
+        - command: forget
+          keep-last: 3
+          continue-on-error: true
+        - command: prune
+        - command: check
+          read-data: false
+          read-data-subset: 5%
+    check:
+      steps:
+        - command: check
+    empty: {}
+    unknown:
+      steps:
+        - command: maintenance
+`
+	c, err := Load(bytes.NewBufferString(testConfig), FormatYAML)
+	require.NoError(t, err)
+	profile, err := c.GetProfile("profile")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"maintenance"}, slices.Sorted(maps.Keys(profile.Commands)))
+	assert.Len(t, c.issues.failedSection, 3)
+	assert.Contains(t, c.issues.failedSection["commands.check"].Error(), "already the name of a restic command")
+	assert.Contains(t, c.issues.failedSection["commands.unknown"].Error(), `unknown restic command "maintenance" in step 1`)
+
+	maintenance, found := profile.GetCompositeCommand("maintenance")
+	require.True(t, found)
+	assert.Equal(t, "weekly maintenance", maintenance.Description)
+	require.Len(t, maintenance.Steps, 3)
+	assert.True(t, maintenance.Steps[0].ContinueOnError)
+	assert.False(t, maintenance.Steps[1].ContinueOnError)
+
+	flags := profile.GetCommandStepFlags(&maintenance.Steps[0]).ToMap()
+	assert.Equal(t, []string{"3"}, flags["keep-last"])
+	assert.NotContains(t, flags, "command")
+	assert.NotContains(t, flags, "continue-on-error")
+
+	flags = profile.GetCommandStepFlags(&maintenance.Steps[2]).ToMap()
+	assert.Equal(t, []string{"5%"}, flags["read-data-subset"])
+	assert.NotContains(t, flags, "read-data")
+
+	assert.Contains(t, profile.DefinedCommands(), "maintenance")
+	assert.Contains(t, profile.SchedulableCommands(), "maintenance")
+	require.Contains(t, profile.Schedules(), "maintenance")
+	assert.Equal(t, []string{"weekly"}, profile.Schedules()["maintenance"].Schedules)
+
+	_, commands := profile.GetRunShellCommandsSections("maintenance")
+	assert.Equal(t, []string{"echo done"}, commands.RunAfter)
+
+	_, found = profile.GetCompositeCommand("check")
+	assert.False(t, found)
+}
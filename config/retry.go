@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/constants"
+)
+
+// Retryable provides access to the retry policy of a section
+type Retryable interface {
+	GetRetry() *RetrySection
+}
+
+// RetrySection configures how a restic command is retried after a transient failure
+type RetrySection struct {
+	ErrorPatterns []string      `mapstructure:"error-patterns" examples:"connection reset by peer;(?i)503 service unavailable;i/o timeout" description:"Retry when one of the regular expressions matches the error output (stderr) of the failed restic command"`
+	ExitCodes     []int         `mapstructure:"exit-codes" examples:"1;3" description:"Retry when the restic command fails with one of the exit codes"`
+	MaxAttempts   int           `mapstructure:"max-attempts" default:"3" range:"[1:]" description:"Maximum number of attempts, including the first run of the command"`
+	Delay         time.Duration `mapstructure:"delay" default:"30s" examples:"10s;30s;1m" description:"Time to wait before the first retry. The delay doubles after each attempt"`
+	MaxDelay      time.Duration `mapstructure:"max-delay" default:"10m" examples:"5m;10m;30m" description:"Maximum time to wait between two attempts"`
+	UseLockWait   bool          `mapstructure:"use-lock-wait" default:"false" description:"Only retry while the lock wait budget (\"--lock-wait\" or \"schedule-lock-wait\") has time left for the next delay"`
+
+	patterns []*regexp.Regexp
+}
+
+func (r *RetrySection) GetRetry() *RetrySection { return r }
+
+// IsEmpty returns true when the command is never retried (no error pattern and no exit code)
+func (r *RetrySection) IsEmpty() bool {
+	return r == nil || (len(r.ErrorPatterns) == 0 && len(r.ExitCodes) == 0)
+}
+
+// GetMaxAttempts returns the maximum number of attempts (at least 1)
+func (r *RetrySection) GetMaxAttempts() int {
+	if r.MaxAttempts < 1 {
+		return constants.DefaultRetryMaxAttempts
+	}
+	return r.MaxAttempts
+}
+
+// GetDelay returns the time to wait after the failed attempt (starting at 1): the delay doubles after each attempt, up to the max-delay
+func (r *RetrySection) GetDelay(attempt int) time.Duration {
+	delay, maxDelay := r.Delay, r.MaxDelay
+	if delay <= 0 {
+		delay = constants.DefaultRetryDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = constants.DefaultRetryMaxDelay
+	}
+	for ; attempt > 1 && delay < maxDelay; attempt-- {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// Matches returns true when the exit code or the error output of a failed command is a reason to retry
+func (r *RetrySection) Matches(exitCode int, stderr string) (bool, error) {
+	if r.IsEmpty() {
+		return false, nil
+	}
+	for _, code := range r.ExitCodes {
+		if code == exitCode {
+			return true, nil
+		}
+	}
+	if err := r.compile(); err != nil {
+		return false, err
+	}
+	for _, expression := range r.patterns {
+		if expression.MatchString(stderr) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compile parses the error patterns once. It returns an error when a pattern is not a valid regular expression
+func (r *RetrySection) compile() error {
+	if r == nil || r.patterns != nil {
+		return nil
+	}
+	patterns := make([]*regexp.Regexp, 0, len(r.ErrorPatterns))
+	for _, pattern := range r.ErrorPatterns {
+		expression, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid retry error pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, expression)
+	}
+	r.patterns = patterns
+	return nil
+}
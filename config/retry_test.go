@@ -0,0 +1,120 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryFromConfig(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  backup:
+    retry:
+      error-patterns:
+        - "connection reset by peer"
+      exit-codes: [1]
+      max-attempts: 5
+      delay: 1m
+      max-delay: 5m
+      use-lock-wait: true
+  check:
+    retry:
+      max-attempts: 5
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "profile")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+
+	retry := profile.GetRetry(constants.CommandBackup)
+	require.NotNil(t, retry)
+	assert.Equal(t, []string{"connection reset by peer"}, retry.ErrorPatterns)
+	assert.Equal(t, []int{1}, retry.ExitCodes)
+	assert.Equal(t, 5, retry.GetMaxAttempts())
+	assert.Equal(t, time.Minute, retry.Delay)
+	assert.Equal(t, 5*time.Minute, retry.MaxDelay)
+	assert.True(t, retry.UseLockWait)
+
+	// no pattern and no exit code
+	assert.Nil(t, profile.GetRetry(constants.CommandCheck))
+	assert.Nil(t, profile.GetRetry(constants.CommandForget))
+}
+
+func TestRetryDelay(t *testing.T) {
+	testCases := []struct {
+		retry    RetrySection
+		attempt  int
+		expected time.Duration
+	}{
+		{RetrySection{}, 1, constants.DefaultRetryDelay},
+		{RetrySection{}, 2, 2 * constants.DefaultRetryDelay},
+		{RetrySection{}, 100, constants.DefaultRetryMaxDelay},
+		{RetrySection{Delay: time.Second, MaxDelay: time.Minute}, 1, time.Second},
+		{RetrySection{Delay: time.Second, MaxDelay: time.Minute}, 3, 4 * time.Second},
+		{RetrySection{Delay: time.Second, MaxDelay: time.Minute}, 7, time.Minute},
+		{RetrySection{Delay: time.Hour, MaxDelay: time.Minute}, 1, time.Minute},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, tc.retry.GetDelay(tc.attempt))
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	assert.Equal(t, constants.DefaultRetryMaxAttempts, (&RetrySection{}).GetMaxAttempts())
+	assert.Equal(t, 1, (&RetrySection{MaxAttempts: 1}).GetMaxAttempts())
+}
+
+func TestRetryMatches(t *testing.T) {
+	retry := &RetrySection{
+		ErrorPatterns: []string{"connection reset", "(?i)503 service unavailable"},
+		ExitCodes:     []int{3},
+	}
+	testCases := []struct {
+		exitCode int
+		stderr   string
+		expected bool
+	}{
+		{3, "", true},
+		{1, "", false},
+		{1, "read tcp: connection reset by peer", true},
+		{1, "server response: 503 Service Unavailable", true},
+		{1, "wrong password or no key found", false},
+	}
+
+	for _, tc := range testCases {
+		matches, err := retry.Matches(tc.exitCode, tc.stderr)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, matches, "exit code %d, stderr %q", tc.exitCode, tc.stderr)
+	}
+
+	var empty *RetrySection
+	matches, err := empty.Matches(1, "connection reset")
+	assert.NoError(t, err)
+	assert.False(t, matches)
+}
+
+func TestRetryInvalidPattern(t *testing.T) {
+	retry := &RetrySection{ErrorPatterns: []string{"(invalid"}}
+	matches, err := retry.Matches(1, "(invalid")
+	assert.Error(t, err)
+	assert.False(t, matches)
+}
+
+func TestRetryInvalidPatternInConfig(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  backup:
+    retry:
+      error-patterns:
+        - "(invalid"
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "profile")
+	assert.ErrorContains(t, err, `invalid configuration in profile 'profile': backup: invalid retry error pattern "(invalid"`)
+	assert.Nil(t, profile)
+}
@@ -0,0 +1,24 @@
+package config
+
+import "time"
+
+// RetentionSafetySection configures the safety guard of the retention: the snapshots that would be removed are listed
+// first (with "forget --dry-run"), and the retention is refused when it would remove too many of them
+type RetentionSafetySection struct {
+	MaxRemovePercent  int           `mapstructure:"max-remove-percent" range:"[0:100]" examples:"20;50" description:"Refuse the retention when it would remove more than this percentage of the snapshots (0 to disable)"`
+	MaxRemovePerGroup int           `mapstructure:"max-remove-per-group" range:"[0:]" examples:"5;10" description:"Refuse the retention when it would remove more than this number of snapshots from a group of snapshots (0 to disable)"`
+	KeepNewerThan     time.Duration `mapstructure:"keep-newer-than" examples:"24h;72h;168h" description:"Refuse the retention when no snapshot newer than this duration would be kept (0 to disable)"`
+}
+
+// IsEmpty returns true when no safety threshold is configured
+func (s *RetentionSafetySection) IsEmpty() bool {
+	return s == nil || (s.MaxRemovePercent <= 0 && s.MaxRemovePerGroup <= 0 && s.KeepNewerThan <= 0)
+}
+
+// GetSafety returns the safety guard of the retention, or nil when no safety threshold is configured
+func (r *RetentionSection) GetSafety() *RetentionSafetySection {
+	if r == nil || r.Safety.IsEmpty() {
+		return nil
+	}
+	return r.Safety
+}
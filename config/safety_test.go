@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionSafetyFromConfig(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  retention:
+    keep-daily: 7
+    safety:
+      max-remove-percent: 30
+      max-remove-per-group: 10
+      keep-newer-than: 48h
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "profile")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+
+	safety := profile.Retention.GetSafety()
+	require.NotNil(t, safety)
+	assert.Equal(t, 30, safety.MaxRemovePercent)
+	assert.Equal(t, 10, safety.MaxRemovePerGroup)
+	assert.Equal(t, 48*time.Hour, safety.KeepNewerThan)
+
+	// the safety is not a flag of the forget command
+	flags := profile.GetRetentionFlags().ToMap()
+	assert.Contains(t, flags, "keep-daily")
+	assert.NotContains(t, flags, "safety")
+}
+
+func TestRetentionSafetyIsEmpty(t *testing.T) {
+	var retention *RetentionSection
+	assert.Nil(t, retention.GetSafety())
+	assert.Nil(t, (&RetentionSection{}).GetSafety())
+	assert.Nil(t, (&RetentionSection{Safety: &RetentionSafetySection{}}).GetSafety())
+	assert.NotNil(t, (&RetentionSection{Safety: &RetentionSafetySection{KeepNewerThan: time.Hour}}).GetSafety())
+}
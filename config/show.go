@@ -19,10 +19,18 @@ func ShowStruct(w io.Writer, orig any, name string) error {
 	if err != nil {
 		return err
 	}
+	if origin, ok := orig.(commandLineOrigin); ok {
+		display.setOrigin(origin.CommandLineKeys(), "command line")
+	}
 	display.Flush()
 	return nil
 }
 
+// commandLineOrigin is implemented by objects that may contain settings defined on the command line
+type commandLineOrigin interface {
+	CommandLineKeys() []string
+}
+
 func showSubStruct(stack []string, display *Display, orig any) (err error) {
 	return showSubStructValue(stack, display, reflect.ValueOf(orig))
 }
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/creativeprojects/resticprofile/constants"
+)
+
+// Types of filesystem snapshot
+const (
+	SourceSnapshotBtrfs = "btrfs"
+	SourceSnapshotLVM   = "lvm"
+	SourceSnapshotZFS   = "zfs"
+)
+
+// SourceSnapshotSection configures a read-only filesystem snapshot of the volume containing the backup sources
+type SourceSnapshotSection struct {
+	Type         string `mapstructure:"type" enum:"btrfs;lvm;zfs" description:"Type of filesystem snapshot"`
+	Volume       string `mapstructure:"volume" examples:"/home;vg0/home;tank/home" description:"Volume to snapshot: path of the btrfs subvolume, LVM logical volume (\"vg/lv\") or ZFS dataset"`
+	Path         string `mapstructure:"path" examples:"/home" description:"Path where the volume is mounted. Defaults to the volume for btrfs"`
+	Name         string `mapstructure:"name" description:"Name of the LVM or ZFS snapshot. Defaults to \"resticprofile-\" followed by the profile name"`
+	Mount        string `mapstructure:"mount" description:"Directory where the snapshot is made available during the backup. Defaults to a directory in the btrfs subvolume, or in the temporary directory for LVM and ZFS"`
+	Size         string `mapstructure:"size" default:"1G" examples:"1G;500M;10%ORIGIN" description:"Size of the LVM snapshot, storing the changes made to the volume during the backup"`
+	MountOptions string `mapstructure:"mount-options" default:"ro" examples:"ro;ro,nouuid" description:"Options used to mount the LVM snapshot (\"nouuid\" is needed for XFS)"`
+}
+
+func (s *SourceSnapshotSection) setRootPath(_ *Profile, rootPath string) {
+	if s.Type == SourceSnapshotBtrfs {
+		s.Volume = fixPath(s.Volume, expandEnv, expandUserHome, absolutePrefix(rootPath))
+	}
+	s.Path = fixPath(s.Path, expandEnv, expandUserHome, absolutePrefix(rootPath))
+	s.Mount = fixPath(s.Mount, expandEnv, expandUserHome, absolutePrefix(rootPath))
+}
+
+// GetPath returns the path where the volume is mounted
+func (s *SourceSnapshotSection) GetPath() string {
+	if s.Path == "" && s.Type == SourceSnapshotBtrfs {
+		return s.Volume
+	}
+	return s.Path
+}
+
+// GetName returns the name of the snapshot
+func (s *SourceSnapshotSection) GetName(profileName string) string {
+	if s.Name == "" {
+		return "resticprofile-" + profileName
+	}
+	return s.Name
+}
+
+// GetMount returns the directory where the snapshot is made available
+func (s *SourceSnapshotSection) GetMount(profileName string) string {
+	if s.Mount != "" {
+		return s.Mount
+	}
+	if s.Type == SourceSnapshotBtrfs {
+		// a btrfs snapshot must be in the same filesystem as its subvolume
+		return filepath.Join(s.Volume, ".resticprofile-"+profileName)
+	}
+	return filepath.Join(os.TempDir(), "resticprofile-"+profileName)
+}
+
+// GetSize returns the size of the LVM snapshot
+func (s *SourceSnapshotSection) GetSize() string {
+	if s.Size == "" {
+		return constants.DefaultSourceSnapshotSize
+	}
+	return s.Size
+}
+
+// GetMountOptions returns the options used to mount the LVM snapshot
+func (s *SourceSnapshotSection) GetMountOptions() string {
+	if s.MountOptions == "" {
+		return constants.DefaultSourceSnapshotOptions
+	}
+	return s.MountOptions
+}
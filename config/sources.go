@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/spf13/viper"
+)
+
+// addSources records the profiles and profile generators declared in the configuration loaded from source.
+// It returns an error when a profile is declared in two drop-in fragments.
+func (c *Config) addSources(source string, vp *viper.Viper) error {
+	var names []string
+	if c.GetVersion() <= Version01 {
+		names = profileNamesV1(vp.AllSettings())
+	} else {
+		names = slices.Collect(maps.Keys(vp.GetStringMap(constants.SectionConfigurationProfiles)))
+	}
+
+	for _, name := range names {
+		for _, previous := range c.sources.profiles[name] {
+			if previous != source && slices.Contains(c.dropInFiles, previous) && slices.Contains(c.dropInFiles, source) {
+				return fmt.Errorf("profile '%s' is declared in both drop-in files %q and %q", name, previous, source)
+			}
+		}
+		c.addProfileSource(name, source)
+	}
+
+	if c.GetVersion() >= Version02 {
+		for name := range vp.GetStringMap(constants.SectionConfigurationGenerateProfiles) {
+			if c.sources.generators == nil {
+				c.sources.generators = make(map[string]string)
+			}
+			c.sources.generators[name] = source
+		}
+	}
+	return nil
+}
+
+func (c *Config) addProfileSource(profileName, source string) {
+	if c.sources.profiles == nil {
+		c.sources.profiles = make(map[string][]string)
+	}
+	if !slices.Contains(c.sources.profiles[profileName], source) {
+		c.sources.profiles[profileName] = append(c.sources.profiles[profileName], source)
+	}
+}
+
+// GetProfileSources returns the configuration files (main file, includes and drop-in fragments) declaring the profile.
+// Generated profiles return the file declaring their generator.
+func (c *Config) GetProfileSources(profileName string) []string {
+	return slices.Clone(c.sources.profiles[profileName])
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o700))
+		require.NoError(t, os.WriteFile(name, []byte(content), 0o600))
+	}
+}
+
+func TestLoadDropInFragments(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"profiles.toml": `
+version = "2"
+[profiles.default]
+repository = "local:/backup"
+[profiles.app1]
+inherit = "default"
+description = "main"
+`,
+		"profiles.d/10-app1.yaml": `
+profiles:
+  app1:
+    description: "from drop-in"
+    backup:
+      source: /app1
+`,
+		"profiles.d/20-app2.json": `{"profiles": {"app2": {"inherit": "default", "backup": {"source": "/app2"}}}}`,
+		"profiles.d/ignored.txt":  `not a configuration`,
+	})
+
+	configFile := filepath.Join(dir, "profiles.toml")
+	c, err := LoadFile(configFile, "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"default", "app1", "app2"}, c.GetProfileNames())
+
+	profile, err := c.GetProfile("app1")
+	require.NoError(t, err)
+	assert.Equal(t, "from drop-in", profile.Description)
+	assert.Equal(t, "local:/backup", profile.Repository.String())
+	assert.Equal(t, []string{"/app1"}, profile.Backup.Source)
+
+	profile, err = c.GetProfile("app2")
+	require.NoError(t, err)
+	assert.Equal(t, "local:/backup", profile.Repository.String())
+
+	assert.Equal(t, []string{configFile}, c.GetProfileSources("default"))
+	assert.Equal(t, []string{configFile, filepath.Join(dir, "profiles.d", "10-app1.yaml")}, c.GetProfileSources("app1"))
+	assert.Equal(t, []string{filepath.Join(dir, "profiles.d", "20-app2.json")}, c.GetProfileSources("app2"))
+	assert.Empty(t, c.GetProfileSources("unknown"))
+}
+
+func TestLoadDropInDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"profiles.d/01-common.yaml": `
+version: "2"
+global:
+  default-command: version
+profiles:
+  default:
+    repository: "local:/backup"
+generate-profiles:
+  apps:
+    items: [ one ]
+    profile:
+      inherit: default
+`,
+		"profiles.d/10-app.toml": `
+[profiles.app]
+inherit = "default"
+`,
+	})
+
+	dropInDir := filepath.Join(dir, "profiles.d")
+	c, err := LoadFile(dropInDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, dropInDir, c.GetConfigFile())
+	assert.Equal(t, Version02, c.GetVersion())
+	assert.ElementsMatch(t, []string{"default", "app", "apps-one"}, c.GetProfileNames())
+
+	global, err := c.GetGlobalSection()
+	require.NoError(t, err)
+	assert.Equal(t, "version", global.DefaultCommand)
+
+	profile, err := c.GetProfile("app")
+	require.NoError(t, err)
+	assert.Equal(t, "local:/backup", profile.Repository.String())
+
+	assert.Equal(t, []string{filepath.Join(dropInDir, "01-common.yaml")}, c.GetProfileSources("apps-one"))
+	assert.Equal(t, []string{filepath.Join(dropInDir, "10-app.toml")}, c.GetProfileSources("app"))
+
+	_, err = LoadFile(filepath.Join(t.TempDir(), "empty.d"), "")
+	assert.Error(t, err)
+}
+
+func TestLoadDropInConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"profiles.yaml": `
+version: "2"
+profiles:
+  app:
+    repository: "local:/backup"
+`,
+		"profiles.d/app.yaml": `
+profiles:
+  app:
+    backup:
+      source: /app
+`,
+		"profiles.d/other.conf": `
+[profiles.app]
+repository = "local:/other"
+`,
+	})
+
+	_, err := LoadFile(filepath.Join(dir, "profiles.yaml"), "")
+	assert.EqualError(t, err, `profile 'app' is declared in both drop-in files "`+
+		filepath.Join(dir, "profiles.d", "app.yaml")+`" and "`+filepath.Join(dir, "profiles.d", "other.conf")+`"`)
+}
@@ -0,0 +1,24 @@
+package config
+
+import "fmt"
+
+// StdinSourceSection is a named source of a backup: the output of its commands is saved in a snapshot of its own
+type StdinSourceSection struct {
+	Name            string   `mapstructure:"name" examples:"postgres;mysql" description:"Name of the source, used in the logs and in the summary of the backup. Defaults to \"stdin-\" followed by the position of the source"`
+	Command         []string `mapstructure:"command" examples:"pg_dumpall;mysqldump --all-databases" description:"Shell command(s) generating the content to back up"`
+	Filename        string   `mapstructure:"filename" examples:"postgres.sql;mysql.sql" description:"File name of the content in the snapshot (\"stdin-filename\" flag). Defaults to the name of the source"`
+	Tag             []string `mapstructure:"tag" description:"Tags of the snapshot, added to the tags of the backup section"`
+	ContinueOnError bool     `mapstructure:"continue-on-error" description:"Back up the next sources when this one fails. The backup still fails once all sources ran"`
+}
+
+func (b *BackupSection) resolveStdinSources() {
+	for index := range b.StdinSources {
+		source := &b.StdinSources[index]
+		if source.Name == "" {
+			source.Name = fmt.Sprintf("stdin-%d", index+1)
+		}
+		if source.Filename == "" {
+			source.Filename = source.Name
+		}
+	}
+}
@@ -18,6 +18,7 @@ type TemplateData struct {
 	Profile   ProfileTemplateData
 	Schedule  ScheduleTemplateData
 	ConfigDir string
+	Vars      map[string]string
 }
 
 // ProfileTemplateData contains profile data
@@ -48,6 +49,7 @@ func newTemplateData(configFile, profileName, scheduleName string) TemplateData
 			Name: scheduleName,
 		},
 		ConfigDir: configDir,
+		Vars:      make(map[string]string),
 	}
 }
 
@@ -426,6 +426,72 @@ profile:
 	}
 }
 
+func TestTemplateErrorPointsToConfigurationLine(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "profiles.toml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+[profile]
+repository = "/mnt/backup"
+password-file = "{{ .Env.TEST_UNDEFINED_PASSWORD_FILE | required "please set TEST_UNDEFINED_PASSWORD_FILE" }}"
+`), 0o600))
+
+	_, err := LoadFile(configFile, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "template: "+configFile+":4:")
+	assert.Contains(t, err.Error(), "please set TEST_UNDEFINED_PASSWORD_FILE")
+	assert.NotContains(t, err.Error(), "__config:")
+}
+
+func TestResolveSemverCompare(t *testing.T) {
+	testConfig := `
+[profile]
+{{ if semverCompare ">= 0.17" }}
+repository = "/mnt/new"
+{{ else }}
+repository = "/mnt/old"
+{{ end }}
+`
+	c, err := Load(strings.NewReader(testConfig), "toml")
+	require.NoError(t, err)
+
+	profile, err := c.GetProfile("profile")
+	require.NoError(t, err)
+	assert.Equal(t, "/mnt/new", profile.Repository.String(), "assumes the latest known restic version")
+
+	require.NoError(t, c.SetResticVersion("0.16.4"))
+	profile, err = c.GetProfile("profile")
+	require.NoError(t, err)
+	assert.Equal(t, "/mnt/old", profile.Repository.String())
+
+	require.NoError(t, c.SetResticVersion("0.17.0"))
+	profile, err = c.GetProfile("profile")
+	require.NoError(t, err)
+	assert.Equal(t, "/mnt/new", profile.Repository.String())
+}
+
+func TestSemverCompareOnFirstLoad(t *testing.T) {
+	testConfig := `
+{{ if semverCompare ">= 0.17" }}
+[new]
+{{ else }}
+[old]
+{{ end }}
+repository = "/mnt"
+`
+	c, err := Load(strings.NewReader(testConfig), "toml")
+	require.NoError(t, err)
+	assert.True(t, c.ResticVersionAssumed())
+	assert.Equal(t, []string{"new"}, c.GetProfileNames())
+
+	// the templates are evaluated again with the restic version
+	require.NoError(t, c.SetResticVersion("0.16.4"))
+	assert.False(t, c.ResticVersionAssumed())
+	assert.Equal(t, []string{"old"}, c.GetProfileNames())
+
+	c, err = Load(strings.NewReader("[profile]\nrepository = \"/mnt\"\n"), "toml")
+	require.NoError(t, err)
+	assert.False(t, c.ResticVersionAssumed(), "semverCompare is not used")
+}
+
 func TestInfoData(t *testing.T) {
 	data := NewTemplateInfoData(restic.AnyVersion)
 
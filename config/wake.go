@@ -0,0 +1,40 @@
+package config
+
+import (
+	"time"
+
+	"github.com/creativeprojects/resticprofile/constants"
+)
+
+// WakeSection wakes up a remote host (Wake-on-LAN), and waits until it is reachable
+type WakeSection struct {
+	MAC       string        `mapstructure:"mac" examples:"00:11:22:33:44:55" description:"MAC address of the host to wake up"`
+	Broadcast string        `mapstructure:"broadcast" default:"255.255.255.255:9" examples:"192.168.1.255;192.168.1.255:7" description:"Broadcast address (and UDP port) receiving the Wake-on-LAN magic packet"`
+	Host      string        `mapstructure:"host" examples:"nas.local:8000;nas.local:22" description:"Host and TCP port (e.g. rest-server or SSH) polled until the host is reachable"`
+	Timeout   time.Duration `mapstructure:"timeout" default:"2m" description:"Maximum time to wait until the host is reachable"`
+	Interval  time.Duration `mapstructure:"interval" default:"5s" description:"Time between two connections to the host"`
+}
+
+// GetBroadcast returns the broadcast address receiving the magic packet
+func (w *WakeSection) GetBroadcast() string {
+	if w.Broadcast == "" {
+		return constants.DefaultWakeBroadcast
+	}
+	return w.Broadcast
+}
+
+// GetTimeout returns the maximum time to wait until the host is reachable
+func (w *WakeSection) GetTimeout() time.Duration {
+	if w.Timeout <= 0 {
+		return constants.DefaultWakeTimeout
+	}
+	return w.Timeout
+}
+
+// GetInterval returns the time between two connections to the host
+func (w *WakeSection) GetInterval() time.Duration {
+	if w.Interval <= 0 {
+		return constants.DefaultWakeInterval
+	}
+	return w.Interval
+}
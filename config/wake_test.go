@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWakeFromConfig(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  wake:
+    mac: "00:11:22:33:44:55"
+    host: nas.local:8000
+    timeout: 5m
+default:
+  wake:
+    mac: "00:11:22:33:44:55"
+    broadcast: 192.168.1.255:7
+    interval: 1s
+`
+	profile, err := getResolvedProfile("yaml", testConfig, "profile")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+
+	wake := profile.Wake
+	require.NotNil(t, wake)
+	assert.Equal(t, "00:11:22:33:44:55", wake.MAC)
+	assert.Equal(t, "nas.local:8000", wake.Host)
+	assert.Equal(t, constants.DefaultWakeBroadcast, wake.GetBroadcast())
+	assert.Equal(t, 5*time.Minute, wake.GetTimeout())
+	assert.Equal(t, constants.DefaultWakeInterval, wake.GetInterval())
+
+	profile, err = getResolvedProfile("yaml", testConfig, "default")
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+
+	wake = profile.Wake
+	require.NotNil(t, wake)
+	assert.Equal(t, "192.168.1.255:7", wake.GetBroadcast())
+	assert.Equal(t, constants.DefaultWakeTimeout, wake.GetTimeout())
+	assert.Equal(t, time.Second, wake.GetInterval())
+}
@@ -4,23 +4,40 @@ import "time"
 
 // Configuration defaults
 const (
-	DefaultConfigurationFile    = "profiles"
-	DefaultProfileName          = "default"
-	DefaultCommand              = "snapshots"
-	DefaultFilterResticFlags    = true
-	DefaultResticLockRetryAfter = 60 * time.Second
-	DefaultResticStaleLockAge   = 1 * time.Hour
-	DefaultTheme                = "light"
-	DefaultIONiceFlag           = false
-	DefaultIONiceClass          = 2
-	DefaultStandardNiceFlag     = 0
-	DefaultBackgroundNiceFlag   = 5
-	DefaultVerboseFlag          = false
-	DefaultQuietFlag            = false
-	DefaultMinMemory            = 100
-	DefaultCommandOutput        = "auto"
-	DefaultSenderTimeout        = 30 * time.Second
-	DefaultPrometheusPushFormat = "text"
-	BatteryFull                 = 100
-	LocalLockRetryDelay         = 5 * time.Second
+	DefaultConfigurationFile     = "profiles"
+	DefaultDropInDirectory       = "profiles.d"
+	DefaultProfileName           = "default"
+	DefaultCommand               = "snapshots"
+	DefaultFilterResticFlags     = true
+	DefaultResticLockRetryAfter  = 60 * time.Second
+	DefaultResticStaleLockAge    = 1 * time.Hour
+	DefaultTheme                 = "light"
+	DefaultIONiceFlag            = false
+	DefaultIONiceClass           = 2
+	DefaultStandardNiceFlag      = 0
+	DefaultBackgroundNiceFlag    = 5
+	DefaultVerboseFlag           = false
+	DefaultQuietFlag             = false
+	DefaultMinMemory             = 100
+	DefaultCommandOutput         = "auto"
+	DefaultSenderTimeout         = 30 * time.Second
+	DefaultPrometheusPushFormat  = "text"
+	BatteryFull                  = 100
+	LocalLockRetryDelay          = 5 * time.Second
+	DefaultRetryMaxAttempts      = 3
+	DefaultRetryDelay            = 30 * time.Second
+	DefaultRetryMaxDelay         = 10 * time.Minute
+	DefaultPreflightMinFreeSpace = 1024
+	DefaultAnomalyHistory        = 10
+	DefaultAnomalyMinHistory     = 3
+	DefaultSourceSnapshotSize    = "1G"
+	DefaultSourceSnapshotOptions = "ro"
+	DefaultMountWaitTimeout      = 30 * time.Second
+	MountReadyRetryDelay         = time.Second
+	UnmountRetryDelay            = time.Second
+	UnmountMaxAttempts           = 3
+	DefaultWakeBroadcast         = "255.255.255.255:9"
+	DefaultWakeTimeout           = 2 * time.Minute
+	DefaultWakeInterval          = 5 * time.Second
+	WakeOnLANPort                = "9"
 )
@@ -3,10 +3,18 @@ package constants
 const (
 	EnvProfileName      = "PROFILE_NAME"
 	EnvProfileCommand   = "PROFILE_COMMAND"
+	EnvAttempts         = "ATTEMPTS"
 	EnvError            = "ERROR"
 	EnvErrorMessage     = "ERROR_MESSAGE"
 	EnvErrorCommandLine = "ERROR_COMMANDLINE"
 	EnvErrorExitCode    = "ERROR_EXIT_CODE"
 	EnvErrorStderr      = "ERROR_STDERR"
+	EnvRetentionRemove  = "RETENTION_REMOVE"
+	EnvRetentionTotal   = "RETENTION_TOTAL"
+	EnvAnomaly          = "ANOMALY"
 	EnvScheduleId       = "RESTICPROFILE_SCHEDULE_ID"
+	EnvPluginProfile    = "RESTICPROFILE_PROFILE"
+	EnvPluginConfig     = "RESTICPROFILE_CONFIG"
+	EnvPluginBinary     = "RESTICPROFILE_BINARY"
+	EnvPluginRestic     = "RESTICPROFILE_RESTIC_BINARY"
 )
@@ -25,4 +25,9 @@ const (
 	ParameterPasswordFile    = "password-file"
 	ParameterPasswordCommand = "password-command"
 	ParameterKeyHint         = "key-hint"
+	ParameterStdin           = "stdin"
+	ParameterStdinFilename   = "stdin-filename"
+	ParameterStdinCommand    = "stdin-from-command"
+	ParameterLimitUpload     = "limit-upload"
+	ParameterLimitDownload   = "limit-download"
 )
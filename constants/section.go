@@ -2,18 +2,21 @@ package constants
 
 // Section
 const (
-	SectionConfigurationDescription = "description"
-	SectionConfigurationGlobal      = "global"
-	SectionConfigurationRetention   = "retention"
-	SectionConfigurationEnvironment = "env"
-	SectionConfigurationGroups      = "groups"
-	SectionConfigurationIncludes    = "includes"
-	SectionConfigurationInherit     = "inherit"
-	SectionConfigurationProfiles    = "profiles"
-	SectionConfigurationMixins      = "mixins"
-	SectionConfigurationMixinUse    = "use"
-	SectionConfigurationSchedule    = "schedule"
-	SectionConfigurationRemotes     = "remotes"
+	SectionConfigurationDescription      = "description"
+	SectionConfigurationGlobal           = "global"
+	SectionConfigurationRetention        = "retention"
+	SectionConfigurationEnvironment      = "env"
+	SectionConfigurationGroups           = "groups"
+	SectionConfigurationIncludes         = "includes"
+	SectionConfigurationInherit          = "inherit"
+	SectionConfigurationAbstract         = "abstract"
+	SectionConfigurationProfiles         = "profiles"
+	SectionConfigurationMixins           = "mixins"
+	SectionConfigurationMixinUse         = "use"
+	SectionConfigurationGenerateProfiles = "generate-profiles"
+	SectionConfigurationSchedule         = "schedule"
+	SectionConfigurationRemotes          = "remotes"
+	SectionConfigurationCommands         = "commands"
 
 	SectionDefinitionCommon = "common"
 	SectionDefinitionForget = "forget"
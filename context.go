@@ -36,6 +36,7 @@ type Context struct {
 	noLock        bool             // skip profile lock file
 	lockWait      time.Duration    // wait up to duration to acquire a lock
 	legacyArgs    bool             // I'm not even sure it's been used by anyone?
+	parallel      bool             // running at the same time as other profiles, with the profile resolved beforehand
 	terminal      *term.Terminal
 }
 
@@ -0,0 +1,53 @@
+package crypt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+const ageBinaryHeader = "age-encryption.org/v1"
+
+func isAge(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(ageBinaryHeader)) ||
+		bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header))
+}
+
+func decryptAge(data []byte, identities []age.Identity) ([]byte, error) {
+	var input io.Reader = bytes.NewReader(data)
+	if !bytes.HasPrefix(data, []byte(ageBinaryHeader)) {
+		input = armor.NewReader(bytes.NewReader(bytes.TrimSpace(data)))
+	}
+	reader, err := age.Decrypt(input, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt age file: %w", err)
+	}
+	plain, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt age file: %w", err)
+	}
+	return plain, nil
+}
+
+// encryptAge always generates an armored file so it can be stored in text based repositories
+func encryptAge(data []byte, recipients []age.Recipient) ([]byte, error) {
+	output := &bytes.Buffer{}
+	armored := armor.NewWriter(output)
+	writer, err := age.Encrypt(armored, recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	if err = armored.Close(); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
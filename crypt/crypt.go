@@ -0,0 +1,144 @@
+package crypt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Kind of encryption detected in a configuration file
+type Kind int
+
+const (
+	KindNone Kind = iota // plain text
+	KindAge              // whole file encrypted with age (binary or armored)
+	KindSops             // SOPS file where values are encrypted with a data key (key wrapped with age)
+)
+
+const (
+	// EnvSopsAgeKeyFile is the environment variable used by SOPS to locate the age key file
+	EnvSopsAgeKeyFile = "SOPS_AGE_KEY_FILE"
+	// EnvSopsAgeKey is the environment variable used by SOPS to pass the age keys directly
+	EnvSopsAgeKey = "SOPS_AGE_KEY"
+)
+
+var (
+	ErrNoIdentity  = errors.New("no age identity available to decrypt the file")
+	ErrNoRecipient = errors.New("no age recipient available to encrypt the file")
+)
+
+// String returns the name of the encryption
+func (k Kind) String() string {
+	switch k {
+	case KindAge:
+		return "age"
+	case KindSops:
+		return "sops"
+	default:
+		return "none"
+	}
+}
+
+// Detect returns the kind of encryption used in data
+func Detect(data []byte) Kind {
+	if isAge(data) {
+		return KindAge
+	}
+	if isSops(data) {
+		return KindSops
+	}
+	return KindNone
+}
+
+// Decrypt returns the plain text content of data. Format is the file format (yaml, json, etc.) and is only used for SOPS files.
+// Data that is not encrypted is returned unchanged.
+func Decrypt(data []byte, format string, identities []age.Identity) ([]byte, error) {
+	kind := Detect(data)
+	if kind == KindNone {
+		return data, nil
+	}
+	if len(identities) == 0 {
+		return nil, ErrNoIdentity
+	}
+	switch kind {
+	case KindAge:
+		return decryptAge(data, identities)
+	case KindSops:
+		return decryptSops(data, format, identities)
+	default:
+		return nil, fmt.Errorf("unsupported encryption %q", kind)
+	}
+}
+
+// Encrypt returns data encrypted for all the recipients. Format is the file format (yaml, json, etc.) and is only used for SOPS files.
+func Encrypt(data []byte, format string, kind Kind, recipients []age.Recipient) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, ErrNoRecipient
+	}
+	if Detect(data) != KindNone {
+		return nil, errors.New("data is already encrypted")
+	}
+	switch kind {
+	case KindAge:
+		return encryptAge(data, recipients)
+	case KindSops:
+		return encryptSops(data, format, recipients)
+	default:
+		return nil, fmt.Errorf("unsupported encryption %q", kind)
+	}
+}
+
+// LoadIdentities loads the age identities from keyFile.
+// When keyFile is empty, it falls back to the SOPS environment variables SOPS_AGE_KEY_FILE and SOPS_AGE_KEY.
+func LoadIdentities(keyFile string) ([]age.Identity, error) {
+	if keyFile == "" {
+		keyFile = strings.TrimSpace(os.Getenv(EnvSopsAgeKeyFile))
+	}
+	if keyFile != "" {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read age key file: %w", err)
+		}
+		identities, err := age.ParseIdentities(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid age key file %q: %w", keyFile, err)
+		}
+		return identities, nil
+	}
+	if keys := strings.TrimSpace(os.Getenv(EnvSopsAgeKey)); keys != "" {
+		identities, err := age.ParseIdentities(strings.NewReader(keys))
+		if err != nil {
+			return nil, fmt.Errorf("invalid age key in %s: %w", EnvSopsAgeKey, err)
+		}
+		return identities, nil
+	}
+	return nil, ErrNoIdentity
+}
+
+// ParseRecipients parses age public keys (age1...)
+func ParseRecipients(keys []string) ([]age.Recipient, error) {
+	recipients := make([]age.Recipient, 0, len(keys))
+	for _, key := range keys {
+		recipient, err := age.ParseX25519Recipient(strings.TrimSpace(key))
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// RecipientsFromIdentities returns the public keys matching the identities
+func RecipientsFromIdentities(identities []age.Identity) []age.Recipient {
+	recipients := make([]age.Recipient, 0, len(identities))
+	for _, identity := range identities {
+		if x25519, ok := identity.(*age.X25519Identity); ok {
+			recipients = append(recipients, x25519.Recipient())
+		}
+	}
+	return recipients
+}
@@ -0,0 +1,136 @@
+package crypt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIdentity(t *testing.T) *age.X25519Identity {
+	t.Helper()
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	return identity
+}
+
+func TestDetect(t *testing.T) {
+	identity := newIdentity(t)
+	encrypted, err := Encrypt([]byte("version: 2\n"), "yaml", KindAge, []age.Recipient{identity.Recipient()})
+	require.NoError(t, err)
+	sops, err := Encrypt([]byte("version: 2\n"), "yaml", KindSops, []age.Recipient{identity.Recipient()})
+	require.NoError(t, err)
+
+	fixtures := []struct {
+		data     []byte
+		expected Kind
+	}{
+		{data: []byte(""), expected: KindNone},
+		{data: []byte("version: 2\n"), expected: KindNone},
+		{data: []byte(`{"key": "ENC[AES256_GCM,data:abc,iv:abc,tag:abc,type:str]"}`), expected: KindNone},
+		{data: []byte("age-encryption.org/v1\n-> X25519 abc\n"), expected: KindAge},
+		{data: encrypted, expected: KindAge},
+		{data: append([]byte("\n\n"), encrypted...), expected: KindAge},
+		{data: sops, expected: KindSops},
+	}
+	for _, fixture := range fixtures {
+		t.Run(string(fixture.data), func(t *testing.T) {
+			assert.Equal(t, fixture.expected, Detect(fixture.data))
+		})
+	}
+}
+
+func TestAgeRoundTrip(t *testing.T) {
+	identity := newIdentity(t)
+	plain := []byte("version: 2\nprofiles:\n  default:\n    password-file: key\n")
+
+	encrypted, err := Encrypt(plain, "yaml", KindAge, []age.Recipient{identity.Recipient()})
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "password-file")
+
+	decrypted, err := Decrypt(encrypted, "yaml", []age.Identity{identity})
+	require.NoError(t, err)
+	assert.Equal(t, plain, decrypted)
+
+	_, err = Decrypt(encrypted, "yaml", []age.Identity{newIdentity(t)})
+	assert.Error(t, err)
+
+	_, err = Decrypt(encrypted, "yaml", nil)
+	assert.ErrorIs(t, err, ErrNoIdentity)
+}
+
+func TestPlainTextIsNotDecrypted(t *testing.T) {
+	plain := []byte("[global]\ninitialize = true\n")
+	decrypted, err := Decrypt(plain, "toml", nil)
+	require.NoError(t, err)
+	assert.Equal(t, plain, decrypted)
+}
+
+func TestCannotEncryptTwice(t *testing.T) {
+	identity := newIdentity(t)
+	encrypted, err := Encrypt([]byte("content"), "", KindAge, []age.Recipient{identity.Recipient()})
+	require.NoError(t, err)
+
+	_, err = Encrypt(encrypted, "", KindAge, []age.Recipient{identity.Recipient()})
+	assert.Error(t, err)
+
+	_, err = Encrypt([]byte("content"), "", KindAge, nil)
+	assert.ErrorIs(t, err, ErrNoRecipient)
+}
+
+func TestLoadIdentities(t *testing.T) {
+	identity := newIdentity(t)
+	keyFile := filepath.Join(t.TempDir(), "keys.txt")
+	require.NoError(t, os.WriteFile(keyFile, []byte("# comment\n"+identity.String()+"\n"), 0o600))
+
+	t.Run("from file", func(t *testing.T) {
+		identities, err := LoadIdentities(keyFile)
+		require.NoError(t, err)
+		require.Len(t, identities, 1)
+		assert.Equal(t, identity.String(), identities[0].(*age.X25519Identity).String())
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadIdentities(filepath.Join(t.TempDir(), "missing.txt"))
+		assert.Error(t, err)
+	})
+
+	t.Run("from sops key file", func(t *testing.T) {
+		t.Setenv(EnvSopsAgeKeyFile, keyFile)
+		identities, err := LoadIdentities("")
+		require.NoError(t, err)
+		assert.Len(t, identities, 1)
+	})
+
+	t.Run("from sops key", func(t *testing.T) {
+		t.Setenv(EnvSopsAgeKeyFile, "")
+		t.Setenv(EnvSopsAgeKey, identity.String())
+		identities, err := LoadIdentities("")
+		require.NoError(t, err)
+		assert.Len(t, identities, 1)
+	})
+
+	t.Run("no identity", func(t *testing.T) {
+		t.Setenv(EnvSopsAgeKeyFile, "")
+		t.Setenv(EnvSopsAgeKey, "")
+		_, err := LoadIdentities("")
+		assert.ErrorIs(t, err, ErrNoIdentity)
+	})
+}
+
+func TestRecipients(t *testing.T) {
+	identity := newIdentity(t)
+
+	recipients, err := ParseRecipients([]string{identity.Recipient().String()})
+	require.NoError(t, err)
+	assert.Equal(t, []age.Recipient{identity.Recipient()}, recipients)
+
+	_, err = ParseRecipients([]string{"invalid"})
+	assert.Error(t, err)
+
+	recipients = RecipientsFromIdentities([]age.Identity{identity})
+	assert.Equal(t, []age.Recipient{identity.Recipient()}, recipients)
+}
@@ -0,0 +1,459 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+// The SOPS file format is documented at https://github.com/getsops/sops
+// Only the age key management is supported. Compatibility is checked with files encrypted by sops in testdata.
+
+const (
+	sopsMetadataKey       = "sops"
+	sopsUnencryptedSuffix = "_unencrypted"
+	sopsVersion           = "3.9.0"
+	sopsNonceSize         = 32
+	sopsDataKeySize       = 32
+	sopsTimeFormat        = "2006-01-02T15:04:05Z"
+	sopsMarker            = "ENC[AES256_GCM,"
+)
+
+var sopsValuePattern = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+type sopsAgeKey struct {
+	Recipient string `yaml:"recipient" json:"recipient"`
+	Enc       string `yaml:"enc" json:"enc"`
+}
+
+type sopsMetadata struct {
+	Age               []sopsAgeKey `yaml:"age" json:"age"`
+	LastModified      string       `yaml:"lastmodified" json:"lastmodified"`
+	MAC               string       `yaml:"mac" json:"mac"`
+	MACOnlyEncrypted  bool         `yaml:"mac_only_encrypted,omitempty" json:"mac_only_encrypted,omitempty"`
+	UnencryptedSuffix string       `yaml:"unencrypted_suffix,omitempty" json:"unencrypted_suffix,omitempty"`
+	Version           string       `yaml:"version" json:"version"`
+}
+
+func isSops(data []byte) bool {
+	if !bytes.Contains(data, []byte(sopsMarker)) {
+		return false
+	}
+	document, err := parseSopsDocument(data)
+	if err != nil {
+		return false
+	}
+	_, metadata := findSopsMetadata(document)
+	return metadata != nil
+}
+
+func isSopsFormat(format string) bool {
+	return slices.Contains([]string{"yaml", "yml", "json"}, strings.ToLower(format))
+}
+
+func parseSopsDocument(data []byte) (*yaml.Node, error) {
+	document := &yaml.Node{}
+	if err := yaml.Unmarshal(data, document); err != nil {
+		return nil, err
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) != 1 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("sops file must contain a single document with a mapping at the root")
+	}
+	return document, nil
+}
+
+// findSopsMetadata returns the index of the sops key in the root mapping and its value
+func findSopsMetadata(document *yaml.Node) (int, *yaml.Node) {
+	root := document.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == sopsMetadataKey && root.Content[i+1].Kind == yaml.MappingNode {
+			return i, root.Content[i+1]
+		}
+	}
+	return -1, nil
+}
+
+// walkSopsTree calls visit on all scalar values (except the metadata) with the path made of the keys leading to the value
+func walkSopsTree(node *yaml.Node, path []string, visit func(node *yaml.Node, path []string) error) error {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := walkSopsTree(child, path, visit); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if len(path) == 0 && key.Value == sopsMetadataKey {
+				continue
+			}
+			key.HeadComment, key.LineComment, key.FootComment = "", "", ""
+			if err := walkSopsTree(value, append(slices.Clone(path), key.Value), visit); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return nil
+		}
+		return visit(node, path)
+	}
+	return nil
+}
+
+func decryptSops(data []byte, format string, identities []age.Identity) ([]byte, error) {
+	if !isSopsFormat(format) {
+		return nil, fmt.Errorf("sops encryption is not supported for %q files", format)
+	}
+	document, err := parseSopsDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sops file: %w", err)
+	}
+	index, metadataNode := findSopsMetadata(document)
+	if metadataNode == nil {
+		return nil, errors.New("invalid sops file: missing metadata")
+	}
+	metadata := sopsMetadata{}
+	if err = metadataNode.Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("invalid sops metadata: %w", err)
+	}
+
+	dataKey, err := metadata.dataKey(identities)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha512.New()
+	err = walkSopsTree(document, nil, func(node *yaml.Node, path []string) error {
+		encrypted := sopsValuePattern.MatchString(node.Value)
+		if encrypted {
+			value, valueType, err := sopsDecryptValue(node.Value, dataKey, sopsPath(path))
+			if err != nil {
+				return fmt.Errorf("cannot decrypt value of %q: %w", strings.Join(path, "."), err)
+			}
+			setSopsValue(node, value, valueType)
+		}
+		if encrypted || !metadata.MACOnlyEncrypted {
+			hash.Write(sopsMACBytes(node))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mac, _, err := sopsDecryptValue(metadata.MAC, dataKey, metadata.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt sops MAC: %w", err)
+	}
+	if !strings.EqualFold(mac, fmt.Sprintf("%X", hash.Sum(nil))) {
+		return nil, errors.New("sops MAC mismatch: the file has been modified")
+	}
+
+	// remove metadata from the decrypted document
+	root := document.Content[0]
+	root.Content = slices.Delete(root.Content, index, index+2)
+
+	return marshalSopsDocument(document, format)
+}
+
+func encryptSops(data []byte, format string, recipients []age.Recipient) ([]byte, error) {
+	if !isSopsFormat(format) {
+		return nil, fmt.Errorf("sops encryption is not supported for %q files", format)
+	}
+	document, err := parseSopsDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s file: %w", format, err)
+	}
+	if _, metadata := findSopsMetadata(document); metadata != nil {
+		return nil, fmt.Errorf("%q is a reserved key at the root of a sops file", sopsMetadataKey)
+	}
+
+	dataKey := make([]byte, sopsDataKeySize)
+	if _, err = rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	metadata := sopsMetadata{
+		LastModified:      time.Now().UTC().Format(sopsTimeFormat),
+		UnencryptedSuffix: sopsUnencryptedSuffix,
+		Version:           sopsVersion,
+	}
+	for _, recipient := range recipients {
+		key, err := sopsWrapDataKey(dataKey, recipient)
+		if err != nil {
+			return nil, err
+		}
+		metadata.Age = append(metadata.Age, key)
+	}
+
+	hash := sha512.New()
+	err = walkSopsTree(document, nil, func(node *yaml.Node, path []string) error {
+		hash.Write(sopsMACBytes(node))
+		if node.Value == "" || slices.ContainsFunc(path, func(key string) bool { return strings.HasSuffix(key, sopsUnencryptedSuffix) }) {
+			return nil
+		}
+		value, err := sopsEncryptValue(sopsPlainText(node), sopsValueType(node), dataKey, sopsPath(path))
+		if err != nil {
+			return err
+		}
+		node.Value, node.Tag, node.Style = value, "!!str", 0
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metadata.MAC, err = sopsEncryptValue(fmt.Sprintf("%X", hash.Sum(nil)), "str", dataKey, metadata.LastModified)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataNode := &yaml.Node{}
+	if err = metadataNode.Encode(metadata); err != nil {
+		return nil, err
+	}
+	root := document.Content[0]
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: sopsMetadataKey}, metadataNode)
+
+	return marshalSopsDocument(document, format)
+}
+
+func (m sopsMetadata) dataKey(identities []age.Identity) ([]byte, error) {
+	if len(m.Age) == 0 {
+		return nil, errors.New("sops file has no age key: only age is supported")
+	}
+	var err error
+	for _, key := range m.Age {
+		var reader io.Reader
+		reader, err = age.Decrypt(armor.NewReader(strings.NewReader(key.Enc)), identities...)
+		if err != nil {
+			continue
+		}
+		var dataKey []byte
+		if dataKey, err = io.ReadAll(reader); err == nil {
+			return dataKey, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot decrypt sops data key: %w", err)
+}
+
+func sopsWrapDataKey(dataKey []byte, recipient age.Recipient) (sopsAgeKey, error) {
+	name, ok := recipient.(fmt.Stringer)
+	if !ok {
+		return sopsAgeKey{}, fmt.Errorf("unsupported age recipient %T", recipient)
+	}
+	encrypted, err := encryptAge(dataKey, []age.Recipient{recipient})
+	if err != nil {
+		return sopsAgeKey{}, err
+	}
+	return sopsAgeKey{Recipient: name.String(), Enc: string(encrypted)}, nil
+}
+
+// sopsPath returns the additional data used to authenticate a value
+func sopsPath(path []string) string {
+	return strings.Join(path, ":") + ":"
+}
+
+func sopsDecryptValue(value string, key []byte, additionalData string) (string, string, error) {
+	if value == "" {
+		return "", "str", nil
+	}
+	matches := sopsValuePattern.FindStringSubmatch(value)
+	if matches == nil {
+		return "", "", errors.New("invalid encrypted value")
+	}
+	encrypted, err := base64.StdEncoding.DecodeString(matches[1])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid data: %w", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(matches[2])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid iv: %w", err)
+	}
+	tag, err := base64.StdEncoding.DecodeString(matches[3])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid tag: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", "", err
+	}
+	plain, err := gcm.Open(nil, iv, append(encrypted, tag...), []byte(additionalData))
+	if err != nil {
+		return "", "", err
+	}
+	return string(plain), matches[4], nil
+}
+
+func sopsEncryptValue(value, valueType string, key []byte, additionalData string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, sopsNonceSize)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, sopsNonceSize)
+	if _, err = rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, []byte(value), []byte(additionalData))
+	encrypted, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(encrypted),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+		valueType,
+	), nil
+}
+
+func sopsValueType(node *yaml.Node) string {
+	switch node.Tag {
+	case "!!int":
+		return "int"
+	case "!!float":
+		return "float"
+	case "!!bool":
+		return "bool"
+	default:
+		return "str"
+	}
+}
+
+func setSopsValue(node *yaml.Node, value, valueType string) {
+	node.Value, node.Style = value, 0
+	switch valueType {
+	case "int", "float", "bool":
+		node.Tag = "!!" + valueType
+	default:
+		node.Tag = "!!str"
+	}
+}
+
+// sopsPlainText returns the normalized value that gets encrypted
+func sopsPlainText(node *yaml.Node) string {
+	if node.Tag == "!!bool" {
+		return strings.ToLower(string(sopsMACBytes(node)))
+	}
+	return string(sopsMACBytes(node))
+}
+
+// sopsMACBytes returns the representation of a value used in the message authentication code (same as SOPS)
+func sopsMACBytes(node *yaml.Node) []byte {
+	switch node.Tag {
+	case "!!int":
+		if value, err := strconv.ParseInt(node.Value, 0, 64); err == nil {
+			return []byte(strconv.FormatInt(value, 10))
+		}
+	case "!!float":
+		if value, err := strconv.ParseFloat(node.Value, 64); err == nil {
+			return []byte(strconv.FormatFloat(value, 'f', -1, 64))
+		}
+	case "!!bool":
+		var value bool
+		if err := node.Decode(&value); err == nil {
+			if value {
+				return []byte("True")
+			}
+			return []byte("False")
+		}
+	}
+	return []byte(node.Value)
+}
+
+func marshalSopsDocument(document *yaml.Node, format string) ([]byte, error) {
+	if strings.ToLower(format) != "json" {
+		return yaml.Marshal(document)
+	}
+	output := &bytes.Buffer{}
+	if err := writeJSONNode(output, document.Content[0]); err != nil {
+		return nil, err
+	}
+	formatted := &bytes.Buffer{}
+	if err := json.Indent(formatted, output.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	formatted.WriteString("\n")
+	return formatted.Bytes(), nil
+}
+
+// writeJSONNode writes the node as JSON, keeping the order of the keys (which is needed for the MAC)
+func writeJSONNode(output *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		output.WriteString("{")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				output.WriteString(",")
+			}
+			writeJSONString(output, node.Content[i].Value)
+			output.WriteString(":")
+			if err := writeJSONNode(output, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		output.WriteString("}")
+	case yaml.SequenceNode:
+		output.WriteString("[")
+		for i, child := range node.Content {
+			if i > 0 {
+				output.WriteString(",")
+			}
+			if err := writeJSONNode(output, child); err != nil {
+				return err
+			}
+		}
+		output.WriteString("]")
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case "!!null":
+			output.WriteString("null")
+		case "!!int", "!!float", "!!bool":
+			var value any
+			if err := node.Decode(&value); err != nil {
+				return err
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			output.Write(encoded)
+		default:
+			writeJSONString(output, node.Value)
+		}
+	default:
+		return fmt.Errorf("unsupported yaml node in json file: %v", node.Kind)
+	}
+	return nil
+}
+
+func writeJSONString(output *bytes.Buffer, value string) {
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	output.Truncate(output.Len() - 1) // remove new line added by the encoder
+}
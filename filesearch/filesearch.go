@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -27,6 +28,9 @@ var (
 		"yml",
 	}
 
+	// encryptedExtension is added to the configuration extension when the file is encrypted with age
+	encryptedExtension = "age"
+
 	defaultConfigurationLocationsUnix = []string{
 		"/usr/local/etc/",
 		"/usr/local/etc/restic/",
@@ -79,10 +83,16 @@ func NewFinder() Finder {
 }
 
 // FindConfigurationFile returns the path of the configuration file
-// If the file doesn't have an extension, it will search for all possible extensions
+// If the file doesn't have an extension, it will search for all possible extensions.
+// When the default configuration file cannot be found, it searches for a drop-in directory in the same locations.
+// A path to a drop-in directory (with a ".d" extension) is returned as is: the configuration is then made of the fragments in the directory.
 func (f Finder) FindConfigurationFile(configFile string) (string, error) {
 	var found, displayFile string
 
+	if f.IsDropInDirectory(configFile) {
+		return configFile, nil
+	}
+
 	extension := filepath.Ext(configFile)
 
 	if extension != "" {
@@ -91,14 +101,20 @@ func (f Finder) FindConfigurationFile(configFile string) (string, error) {
 		found = f.findConfigurationFileWithExtension(configFile)
 	} else {
 		displayFile = fmt.Sprintf("'%s' with extensions %s", configFile, strings.Join(configurationExtensions, ", "))
-		// Search all extensions one by one
+		// Search all extensions one by one (plain file first, then encrypted file)
 		for _, ext := range configurationExtensions {
 			found = f.findConfigurationFileWithExtension(configFile + "." + ext)
+			if found == "" {
+				found = f.findConfigurationFileWithExtension(configFile + "." + ext + "." + encryptedExtension)
+			}
 			if found != "" {
 				break
 			}
 		}
 	}
+	if found == "" && configFile == constants.DefaultConfigurationFile {
+		found = f.findDropInDirectory()
+	}
 	if found != "" {
 		return found, nil
 	}
@@ -128,6 +144,61 @@ func (f Finder) findConfigurationFileWithExtension(configFile string) string {
 	return ""
 }
 
+// findDropInDirectory returns the first drop-in directory containing configuration fragments
+// from the current folder or from the list of locations
+func (f Finder) findDropInDirectory() string {
+	paths := append([]string{"."}, getSearchConfigurationLocations()...)
+	for _, configPath := range paths {
+		dir := filepath.Join(configPath, constants.DefaultDropInDirectory)
+		if fragments, err := f.FindConfigurationFragments(dir); err == nil && len(fragments) > 0 {
+			return dir
+		}
+	}
+	return ""
+}
+
+// IsDropInDirectory returns true when path is an existing directory with a ".d" extension
+func (f Finder) IsDropInDirectory(path string) bool {
+	return filepath.Ext(path) == ".d" && dirExists(f.fs, path)
+}
+
+// FindConfigurationDropIns returns the configuration fragments (in lexical order) from the drop-in directory
+// next to the configuration file. If configFile is a drop-in directory, the fragments are loaded from this directory instead.
+func (f Finder) FindConfigurationDropIns(configFile string) ([]string, error) {
+	dir := configFile
+	if !f.IsDropInDirectory(dir) {
+		dir = filepath.Join(filepath.Dir(configFile), constants.DefaultDropInDirectory)
+	}
+	return f.FindConfigurationFragments(dir)
+}
+
+// FindConfigurationFragments returns the files with a configuration extension (optionally encrypted) from dir,
+// sorted by name. It returns no error when dir doesn't exist.
+func (f Finder) FindConfigurationFragments(dir string) ([]string, error) {
+	entries, err := afero.ReadDir(f.fs, dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot read drop-in directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		extension := strings.TrimPrefix(filepath.Ext(strings.TrimSuffix(name, "."+encryptedExtension)), ".")
+		if slices.Contains(configurationExtensions, extension) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	clog.Tracef("drop-in directory %q: %s", dir, strings.Join(files, ", "))
+	return files, nil
+}
+
 // FindConfigurationIncludes finds includes (glob patterns) relative to the configuration file.
 func (f Finder) FindConfigurationIncludes(configFile string, includes []string) ([]string, error) {
 	if !filepath.IsAbs(configFile) {
@@ -277,6 +348,11 @@ func fileExists(fs afero.Fs, filename string) bool {
 	return err == nil && !info.IsDir()
 }
 
+func dirExists(fs afero.Fs, dirname string) bool {
+	info, err := fs.Stat(dirname)
+	return err == nil && info.IsDir()
+}
+
 func addRootToRelativePaths(home string, paths []string) []string {
 	if platform.IsWindows() {
 		return paths
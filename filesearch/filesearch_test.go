@@ -99,6 +99,12 @@ func testLocations(t *testing.T) []testLocation {
 			searchPath: "",
 			searchFile: "profiles",
 		},
+		{
+			realPath:   "",
+			realFile:   "profiles.yaml.age",
+			searchPath: "",
+			searchFile: "profiles",
+		},
 		{
 			realPath:   "unittest-config",
 			realFile:   "profiles.spec",
@@ -333,3 +339,62 @@ func TestFindConfigurationIncludes(t *testing.T) {
 		})
 	}
 }
+
+func TestFindConfigurationDropIns(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	dropInDir := filepath.Join("config", "profiles.d")
+	require.NoError(t, fs.MkdirAll(filepath.Join(dropInDir, "subdir.yaml"), 0o700))
+	for _, name := range []string{"20-app.toml", "10-app.yaml", "30-secret.yaml.age", ".hidden.yaml", "README.md", "backup.yaml~"} {
+		require.NoError(t, afero.WriteFile(fs, filepath.Join(dropInDir, name), []byte{}, iofs.ModePerm))
+	}
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("config", "profiles.yaml"), []byte{}, iofs.ModePerm))
+	expected := []string{
+		filepath.Join(dropInDir, "10-app.yaml"),
+		filepath.Join(dropInDir, "20-app.toml"),
+		filepath.Join(dropInDir, "30-secret.yaml.age"),
+	}
+
+	finder := Finder{fs: fs}
+
+	t.Run("next to configuration file", func(t *testing.T) {
+		files, err := finder.FindConfigurationDropIns(filepath.Join("config", "profiles.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, expected, files)
+	})
+
+	t.Run("drop-in directory", func(t *testing.T) {
+		assert.True(t, finder.IsDropInDirectory(dropInDir))
+		files, err := finder.FindConfigurationDropIns(dropInDir)
+		require.NoError(t, err)
+		assert.Equal(t, expected, files)
+
+		found, err := finder.FindConfigurationFile(dropInDir)
+		require.NoError(t, err)
+		assert.Equal(t, dropInDir, found)
+	})
+
+	t.Run("no drop-in directory", func(t *testing.T) {
+		assert.False(t, finder.IsDropInDirectory("config"))
+		files, err := finder.FindConfigurationDropIns("profiles.yaml")
+		assert.NoError(t, err)
+		assert.Empty(t, files)
+	})
+}
+
+func TestFindDropInDirectoryInPlaceOfConfigurationFile(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	finder := Finder{fs: fs}
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("profiles.d", "app.conf"), []byte{}, iofs.ModePerm))
+
+	found, err := finder.FindConfigurationFile("profiles")
+	require.NoError(t, err)
+	assert.Equal(t, "profiles.d", found)
+
+	// only for the default configuration file
+	_, err = finder.FindConfigurationFile("other")
+	assert.Error(t, err)
+}
@@ -36,9 +36,13 @@ type commandLineFlags struct {
 	stderr          bool
 	parentPort      int
 	noPriority      bool
+	overrideSafety  bool
 	ignoreOnBattery int
 	usagesHelp      string
-	remote          string // url of the remote server to download configuration files from
+	remote          string   // url of the remote server to download configuration files from
+	ageKeyFile      string   // age identity file used to decrypt configuration files
+	settings        []string // profile settings overridden from the command line (profile.section.key=value)
+	vars            []string // template variables defined from the command line (NAME=value)
 }
 
 func envValueOverride[T any](defaultValue T, keys ...string) T {
@@ -91,9 +95,11 @@ func loadFlags(args []string) (*pflag.FlagSet, commandLineFlags, error) {
 		noAnsi:          envValueOverride(false, "RESTICPROFILE_NO_ANSI"),
 		theme:           envValueOverride(constants.DefaultTheme, "RESTICPROFILE_THEME"),
 		noPriority:      envValueOverride(false, "RESTICPROFILE_NO_PRIORITY"),
+		overrideSafety:  envValueOverride(false, "RESTICPROFILE_OVERRIDE_SAFETY"),
 		wait:            envValueOverride(false, "RESTICPROFILE_WAIT"),
 		ignoreOnBattery: envValueOverride(0, "RESTICPROFILE_IGNORE_ON_BATTERY"),
 		remote:          envValueOverride("", "RESTICPROFILE_REMOTE"),
+		ageKeyFile:      envValueOverride("", "RESTICPROFILE_AGE_KEY_FILE"),
 	}
 
 	flagset.BoolVarP(&flags.help, "help", "h", flags.help, "display this help")
@@ -112,9 +118,13 @@ func loadFlags(args []string) (*pflag.FlagSet, commandLineFlags, error) {
 	flagset.BoolVar(&flags.noAnsi, "no-ansi", flags.noAnsi, "disable ansi control characters (disable console colouring)")
 	flagset.StringVar(&flags.theme, "theme", flags.theme, "console colouring theme (dark, light, none)")
 	flagset.BoolVar(&flags.noPriority, "no-prio", flags.noPriority, "don't change the process priority: used when started from a service that has already set the priority")
+	flagset.BoolVar(&flags.overrideSafety, "override-safety", flags.overrideSafety, "apply the retention even when it would remove more snapshots than allowed by the retention safety")
 	flagset.BoolVarP(&flags.wait, "wait", "w", flags.wait, "wait at the end until the user presses the enter key")
 	flagset.IntVar(&flags.ignoreOnBattery, "ignore-on-battery", flags.ignoreOnBattery, "don't start the profile when the computer is running on battery. You can specify a value to ignore only when the % charge left is less or equal than the value")
 	flagset.Lookup("ignore-on-battery").NoOptDefVal = "100" // 0 is flag not set, 100 is for a flag with no value (meaning just battery discharge)
+	flagset.StringVar(&flags.ageKeyFile, "age-key-file", flags.ageKeyFile, "age key file to decrypt configuration files encrypted with age or SOPS (defaults to SOPS_AGE_KEY_FILE or SOPS_AGE_KEY)")
+	flagset.StringArrayVar(&flags.settings, "set", flags.settings, "override a profile setting (syntax \"profile.section.key=value\"), can be used multiple times")
+	flagset.StringArrayVar(&flags.vars, "var", flags.vars, "define a template variable available as {{ .Vars.NAME }} (syntax \"NAME=value\"), can be used multiple times")
 	flagset.StringVarP(&flags.remote, "remote", "r", flags.remote, "remote server to download configuration files from")
 	// keep the "remote" flag hidden for now
 	_ = flagset.MarkHidden("remote")
@@ -43,6 +43,17 @@ func TestBackupProfileName(t *testing.T) {
 	assert.Equal(t, flags.resticArgs, []string{"backup", "-v"})
 }
 
+func TestSetAndVarFlags(t *testing.T) {
+	_, flags, err := loadFlags([]string{
+		"--set", "profile1.backup.source=/a,/b", "--var", "REPO=local:/backup",
+		"--set", "profile1.verbose=2", "backup", "--set", "restic-flag",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"profile1.backup.source=/a,/b", "profile1.verbose=2"}, flags.settings)
+	assert.Equal(t, []string{"REPO=local:/backup"}, flags.vars)
+	assert.Equal(t, []string{"backup", "--set", "restic-flag"}, flags.resticArgs)
+}
+
 func TestEnvOverrides(t *testing.T) {
 	var envNames []string
 	t.Cleanup(func() {
@@ -74,8 +85,10 @@ func TestEnvOverrides(t *testing.T) {
 		noAnsi:          setEnv(true, "RESTICPROFILE_NO_ANSI").(bool),
 		theme:           setEnv("custom-theme", "RESTICPROFILE_THEME").(string),
 		noPriority:      setEnv(true, "RESTICPROFILE_NO_PRIORITY").(bool),
+		overrideSafety:  setEnv(true, "RESTICPROFILE_OVERRIDE_SAFETY").(bool),
 		wait:            setEnv(true, "RESTICPROFILE_WAIT").(bool),
 		ignoreOnBattery: setEnv(50, "RESTICPROFILE_IGNORE_ON_BATTERY").(int),
+		ageKeyFile:      setEnv("custom-key.txt", "RESTICPROFILE_AGE_KEY_FILE").(string),
 	}
 
 	load := func(t *testing.T, args ...string) commandLineFlags {
@@ -3,6 +3,7 @@ module github.com/creativeprojects/resticprofile
 go 1.26.2
 
 require (
+	filippo.io/age v1.2.1
 	github.com/Masterminds/semver/v3 v3.4.0
 	github.com/adrg/xdg v0.5.3
 	github.com/coreos/go-systemd/v22 v22.6.0
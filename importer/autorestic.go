@@ -0,0 +1,289 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/creativeprojects/resticprofile/restic"
+	"gopkg.in/yaml.v3"
+)
+
+// stringList is a list of strings that can also be written as a single string
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = []string{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// autoresticOptions are restic flags by command ("all" for every command)
+type autoresticOptions map[string]map[string]any
+
+const autoresticAllCommands = "all"
+
+// mergeAutoresticOptions merges the options of each level, flag by flag
+func mergeAutoresticOptions(levels ...autoresticOptions) autoresticOptions {
+	merged := make(autoresticOptions)
+	for _, options := range levels {
+		for command, flags := range options {
+			if merged[command] == nil {
+				merged[command] = make(map[string]any, len(flags))
+			}
+			maps.Copy(merged[command], flags)
+		}
+	}
+	return merged
+}
+
+type autoresticConfig struct {
+	Version   int                           `yaml:"version"`
+	Global    autoresticOptions             `yaml:"global"`
+	Extras    any                           `yaml:"extras"`
+	Locations map[string]autoresticLocation `yaml:"locations"`
+	Backends  map[string]autoresticBackend  `yaml:"backends"`
+	Other     map[string]any                `yaml:",inline"`
+}
+
+type autoresticLocation struct {
+	From    stringList            `yaml:"from"`
+	To      stringList            `yaml:"to"`
+	Type    string                `yaml:"type"`
+	Cron    string                `yaml:"cron"`
+	Forget  string                `yaml:"forget"`
+	Hooks   autoresticHooks       `yaml:"hooks"`
+	Options autoresticOptions     `yaml:"options"`
+	Copy    map[string]stringList `yaml:"copy"`
+	Other   map[string]any        `yaml:",inline"`
+}
+
+type autoresticHooks struct {
+	Dir     string         `yaml:"dir"`
+	Before  stringList     `yaml:"before"`
+	After   stringList     `yaml:"after"`
+	Success stringList     `yaml:"success"`
+	Failure stringList     `yaml:"failure"`
+	Other   map[string]any `yaml:",inline"`
+}
+
+type autoresticBackend struct {
+	Type       string            `yaml:"type"`
+	Path       string            `yaml:"path"`
+	Key        string            `yaml:"key"`
+	Env        map[string]string `yaml:"env"`
+	RequireKey bool              `yaml:"requireKey"`
+	Options    autoresticOptions `yaml:"options"`
+	Other      map[string]any    `yaml:",inline"`
+}
+
+// autoresticHookFlags maps the autorestic hooks to the resticprofile flags of the backup section
+var autoresticHookFlags = []struct {
+	flag  string
+	hooks func(autoresticHooks) []string
+}{
+	{"run-before", func(h autoresticHooks) []string { return h.Before }},
+	{"run-after", func(h autoresticHooks) []string { return h.Success }},
+	{"run-after-fail", func(h autoresticHooks) []string { return h.Failure }},
+	{"run-finally", func(h autoresticHooks) []string { return h.After }},
+}
+
+// Autorestic converts an autorestic configuration file (version 2) into a resticprofile configuration.
+// Each location becomes a profile inheriting from the profile of its backend. A location saved into
+// more than one backend becomes a group of profiles.
+func Autorestic(input io.Reader, source string) (*Config, error) {
+	ar := autoresticConfig{}
+	decoder := yaml.NewDecoder(input)
+	if err := decoder.Decode(&ar); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("cannot read autorestic configuration: %w", err)
+	}
+	if len(ar.Locations) == 0 && len(ar.Backends) == 0 {
+		return nil, fmt.Errorf("no location or backend found in autorestic configuration")
+	}
+
+	c := newConfig("autorestic configuration " + source)
+	if ar.Version != 0 && ar.Version != 2 {
+		c.warnf("autorestic configuration version %d: only version 2 is supported", ar.Version)
+	}
+	c.warnUnknown("configuration", ar.Other)
+
+	backends := make(map[string]string, len(ar.Backends))
+	for _, name := range slices.Sorted(maps.Keys(ar.Backends)) {
+		backends[name] = c.addAutoresticBackend(name, ar.Backends[name], ar.Global)
+	}
+	for _, name := range slices.Sorted(maps.Keys(ar.Locations)) {
+		c.addAutoresticLocation(name, ar.Locations[name], ar, backends)
+	}
+	return c, nil
+}
+
+func (c *Config) addAutoresticBackend(name string, backend autoresticBackend, global autoresticOptions) string {
+	profile := newSection()
+	profile.set("description", fmt.Sprintf("autorestic backend %q", name))
+
+	switch backend.Type {
+	case "":
+		c.warnf("backend %q: missing type", name)
+	case "local":
+		profile.set("repository", backend.Path)
+	default:
+		profile.set("repository", backend.Type+":"+backend.Path)
+	}
+
+	env := newSection()
+	for key, value := range backend.Env {
+		env.set(strings.ToUpper(key), value)
+	}
+	slices.Sort(env.keys)
+	if backend.Key != "" {
+		env.set("RESTIC_PASSWORD", backend.Key)
+		c.warnf("backend %q: the repository key is copied into the environment (RESTIC_PASSWORD), consider moving it to a password-file", name)
+	} else {
+		c.warnf("backend %q: no key found, set the repository password-file or password-command", name)
+	}
+	if len(env.keys) > 0 {
+		profile.set("env", env)
+	}
+
+	// options of the commands are set in the locations, as lists of inherited sections would be merged
+	all := mergeAutoresticOptions(global, backend.Options)[autoresticAllCommands]
+	c.addAutoresticOptions(profile, fmt.Sprintf("backend %q", name), autoresticOptions{autoresticAllCommands: all})
+	c.warnUnknown(fmt.Sprintf("backend %q", name), backend.Other)
+
+	target := c.uniqueProfileName("backend-" + name)
+	c.profiles.set(target, profile)
+	return target
+}
+
+func (c *Config) addAutoresticLocation(name string, location autoresticLocation, ar autoresticConfig, backends map[string]string) {
+	context := fmt.Sprintf("location %q", name)
+	if location.Type != "" && location.Type != "local" {
+		c.warnf("%s: location type %q is not supported, location ignored", context, location.Type)
+		return
+	}
+	if len(location.To) == 0 {
+		c.warnf("%s: no backend, location ignored", context)
+		return
+	}
+	for backend, targets := range location.Copy {
+		c.warnf("%s: copy from backend %q to %s is not converted, use a copy section", context, backend, strings.Join(targets, ", "))
+	}
+	c.warnUnknown(context, location.Other)
+	c.warnUnknown(context+" hooks", location.Hooks.Other)
+
+	schedule := ""
+	if location.Cron != "" {
+		var warning string
+		var err error
+		schedule, warning, err = cronToSchedule(location.Cron)
+		if err != nil {
+			c.warnf("%s: %s", context, err)
+		}
+		if warning != "" {
+			c.warnf("%s: %s", context, warning)
+		}
+	}
+
+	names := make([]string, 0, len(location.To))
+	for _, backend := range location.To {
+		parent, found := backends[backend]
+		if !found {
+			c.warnf("%s: unknown backend %q", context, backend)
+			continue
+		}
+		profile := newSection()
+		profile.set("inherit", parent)
+		options := mergeAutoresticOptions(ar.Global, ar.Backends[backend].Options)
+		delete(options, autoresticAllCommands) // already in the backend profile
+		c.addAutoresticOptions(profile, context, mergeAutoresticOptions(options, location.Options))
+
+		backup := profile.child("backup")
+		backup.set("source", []string(location.From))
+		if schedule != "" {
+			backup.set("schedule", schedule)
+		}
+		for _, hook := range autoresticHookFlags {
+			commands := slices.Clone(hook.hooks(location.Hooks))
+			if len(commands) == 0 {
+				continue
+			}
+			if location.Hooks.Dir != "" {
+				for index, command := range commands {
+					commands[index] = fmt.Sprintf("cd %q && %s", location.Hooks.Dir, command)
+				}
+			}
+			backup.set(hook.flag, commands)
+		}
+
+		switch strings.ToLower(location.Forget) {
+		case "", "no", "false":
+		case "yes", "true":
+			profile.child("retention").set("after-backup", true)
+		case "prune":
+			retention := profile.child("retention")
+			retention.set("after-backup", true)
+			retention.set("prune", true)
+		default:
+			c.warnf("%s: unknown forget value %q", context, location.Forget)
+		}
+
+		target := name
+		if len(location.To) > 1 {
+			target = name + "-" + backend
+		}
+		target = c.uniqueProfileName(target)
+		c.profiles.set(target, profile)
+		names = append(names, target)
+	}
+	if len(location.To) > 1 && len(names) > 0 {
+		c.groups.set(profileName(name), names)
+	}
+}
+
+// addAutoresticOptions adds the restic flags to the profile: options for "all" commands are set in the
+// profile, options for "forget" in the retention section and options of other commands in their section
+func (c *Config) addAutoresticOptions(profile *section, context string, options autoresticOptions) {
+	for _, command := range slices.Sorted(maps.Keys(options)) {
+		target := profile
+		switch command {
+		case autoresticAllCommands:
+		case "forget":
+			target = profile.child("retention")
+		default:
+			if _, found := restic.GetCommand(command); !found {
+				c.warnf("%s: options of unknown command %q are ignored", context, command)
+				continue
+			}
+			target = profile.child(command)
+		}
+		flags := options[command]
+		for _, flag := range slices.Sorted(maps.Keys(flags)) {
+			target.set(flag, flags[flag])
+		}
+	}
+}
+
+func (c *Config) warnUnknown(context string, items map[string]any) {
+	for _, key := range slices.Sorted(maps.Keys(items)) {
+		c.warnf("%s: %q is not supported", context, key)
+	}
+}
+
+// uniqueProfileName returns a valid profile name that is not already used
+func (c *Config) uniqueProfileName(name string) string {
+	name = profileName(name)
+	unique := name
+	for index := 2; c.profiles.has(unique) || c.groups.has(unique); index++ {
+		unique = fmt.Sprintf("%s-%d", name, index)
+	}
+	return unique
+}
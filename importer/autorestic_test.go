@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const autoresticTestConfig = `
+version: 2
+global:
+  all:
+    verbose: 1
+  forget:
+    keep-daily: 7
+locations:
+  home:
+    from: /home
+    to: [remote, hdd]
+    cron: '0 3 * * *'
+    forget: prune
+    hooks:
+      dir: /opt/scripts
+      before:
+        - ./pre.sh
+      after: cleanup.sh
+      failure: notify.sh
+    options:
+      backup:
+        exclude: ['*.tmp']
+  etc:
+    from: [/etc, /usr/local/etc]
+    to: hdd
+    copy:
+      hdd: [remote]
+  docker:
+    type: volume
+    from: data
+    to: hdd
+backends:
+  remote:
+    type: b2
+    path: 'bucket:/backup'
+    key: secret
+    env:
+      b2_account_id: id
+  hdd:
+    type: local
+    path: /mnt/hdd/restic
+    requireKey: true
+    unknown: value
+`
+
+func TestAutorestic(t *testing.T) {
+	imported, err := Autorestic(strings.NewReader(autoresticTestConfig), "test.yml")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"backend-hdd", "backend-remote", "etc", "home-remote", "home-hdd"}, imported.ProfileNames())
+	assert.Equal(t, []string{"home-remote", "home-hdd"}, imported.Group("home"))
+
+	assert.Equal(t, map[string]any{
+		"description": `autorestic backend "remote"`,
+		"repository":  "b2:bucket:/backup",
+		"env":         map[string]any{"B2_ACCOUNT_ID": "id", "RESTIC_PASSWORD": "secret"},
+		"verbose":     1,
+	}, imported.Profile("backend-remote"))
+
+	assert.Equal(t, map[string]any{
+		"inherit": "backend-remote",
+		"backup": map[string]any{
+			"source":         []string{"/home"},
+			"exclude":        []any{"*.tmp"},
+			"schedule":       "*-*-* 03:00",
+			"run-before":     []string{`cd "/opt/scripts" && ./pre.sh`},
+			"run-after-fail": []string{`cd "/opt/scripts" && notify.sh`},
+			"run-finally":    []string{`cd "/opt/scripts" && cleanup.sh`},
+		},
+		"retention": map[string]any{"keep-daily": 7, "after-backup": true, "prune": true},
+	}, imported.Profile("home-remote"))
+
+	assert.Equal(t, map[string]any{
+		"inherit":   "backend-hdd",
+		"retention": map[string]any{"keep-daily": 7},
+		"backup":    map[string]any{"source": []string{"/etc", "/usr/local/etc"}},
+	}, imported.Profile("etc"))
+
+	assert.ElementsMatch(t, []string{
+		`backend "hdd": no key found, set the repository password-file or password-command`,
+		`backend "hdd": "unknown" is not supported`,
+		`backend "remote": the repository key is copied into the environment (RESTIC_PASSWORD), consider moving it to a password-file`,
+		`location "docker": location type "volume" is not supported, location ignored`,
+		`location "etc": copy from backend "hdd" to remote is not converted, use a copy section`,
+	}, imported.Warnings)
+
+	// the generated configuration can be loaded
+	buffer := &bytes.Buffer{}
+	require.NoError(t, imported.Write(buffer))
+	assert.Contains(t, buffer.String(), `# WARNING: location "docker": location type "volume" is not supported`)
+	cfg, err := config.Load(buffer, "yaml")
+	require.NoError(t, err)
+	profile, err := cfg.GetProfile("home-hdd")
+	require.NoError(t, err)
+	assert.Equal(t, "/mnt/hdd/restic", profile.Repository.Value())
+	assert.Equal(t, []string{"/home"}, profile.Backup.Source)
+	assert.True(t, cfg.HasProfileGroup("home"))
+}
+
+func TestAutoresticWithoutLocation(t *testing.T) {
+	_, err := Autorestic(strings.NewReader("version: 2\n"), "test.yml")
+	assert.Error(t, err)
+
+	_, err = Autorestic(strings.NewReader("locations: [invalid"), "test.yml")
+	assert.Error(t, err)
+}
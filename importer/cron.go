@@ -0,0 +1,151 @@
+package importer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/creativeprojects/resticprofile/calendar"
+)
+
+// cronKeywords maps the cron special strings to resticprofile schedule keywords
+var cronKeywords = map[string]string{
+	"@yearly":   "yearly",
+	"@annually": "yearly",
+	"@monthly":  "monthly",
+	"@weekly":   "weekly",
+	"@daily":    "daily",
+	"@midnight": "daily",
+	"@hourly":   "hourly",
+}
+
+var (
+	cronMonths   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	cronWeekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	weekdayNames = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+)
+
+// cronField describes the values accepted in a field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // names of the values, starting at min
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: cronMonths},
+	{name: "day of week", min: 0, max: 7, names: cronWeekdays},
+}
+
+// cronToSchedule converts a cron expression (5 fields or special string) into a resticprofile schedule.
+// The warning is not empty when the schedule doesn't exactly match the cron expression.
+func cronToSchedule(expression string) (schedule, warning string, err error) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "@") {
+		keyword, found := cronKeywords[strings.ToLower(expression)]
+		if !found {
+			return "", "", fmt.Errorf("unsupported cron expression %q", expression)
+		}
+		return keyword, "", nil
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return "", "", fmt.Errorf("invalid cron expression %q: expected %d fields", expression, len(cronFields))
+	}
+	values := make([]string, len(fields))
+	for index, field := range fields {
+		values[index], err = convertCronField(field, cronFields[index])
+		if err != nil {
+			return "", "", fmt.Errorf("invalid cron expression %q: %w", expression, err)
+		}
+	}
+	minute, hour, day, month, weekday := values[0], values[1], values[2], values[3], values[4]
+	if day != "*" && weekday != "*" {
+		warning = fmt.Sprintf("cron expression %q runs when either the day of month or the day of week matches, the schedule %%q runs when both match", expression)
+	}
+
+	schedule = fmt.Sprintf("*-%s-%s %s:%s", month, day, hour, minute)
+	if weekday != "*" {
+		schedule = weekday + " " + schedule
+	}
+	if err = calendar.NewEvent().Parse(schedule); err != nil {
+		return "", "", fmt.Errorf("cannot convert cron expression %q: %w", expression, err)
+	}
+	if warning != "" {
+		warning = fmt.Sprintf(warning, schedule)
+	}
+	return schedule, warning, nil
+}
+
+// convertCronField converts one field of a cron expression into the calendar event syntax
+func convertCronField(field string, def cronField) (string, error) {
+	if field == "*" || field == "?" {
+		return "*", nil
+	}
+	parts := make([]string, 0)
+	for _, item := range strings.Split(field, ",") {
+		rangeValue, stepValue, hasStep := strings.Cut(item, "/")
+		start, end := def.min, def.max
+		if def.name == "day of week" {
+			end = 6
+		}
+		if rangeValue != "*" {
+			from, to, isRange := strings.Cut(rangeValue, "-")
+			var err error
+			if start, err = parseCronValue(from, def); err != nil {
+				return "", err
+			}
+			end = start
+			if isRange {
+				if end, err = parseCronValue(to, def); err != nil {
+					return "", err
+				}
+			} else if hasStep {
+				end = def.max
+			}
+			if end < start {
+				return "", fmt.Errorf("invalid range %q in %s", rangeValue, def.name)
+			}
+		}
+		if !hasStep {
+			if start == end {
+				parts = append(parts, formatCronValue(start, def))
+			} else {
+				parts = append(parts, formatCronValue(start, def)+".."+formatCronValue(end, def))
+			}
+			continue
+		}
+		step, err := strconv.Atoi(stepValue)
+		if err != nil || step < 1 {
+			return "", fmt.Errorf("invalid step %q in %s", stepValue, def.name)
+		}
+		for value := start; value <= end; value += step {
+			parts = append(parts, formatCronValue(value, def))
+		}
+	}
+	return strings.Join(parts, ","), nil
+}
+
+func parseCronValue(value string, def cronField) (int, error) {
+	for index, name := range def.names {
+		if strings.EqualFold(value, name) {
+			return index + def.min, nil
+		}
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < def.min || number > def.max {
+		return 0, fmt.Errorf("invalid %s %q", def.name, value)
+	}
+	return number, nil
+}
+
+func formatCronValue(value int, def cronField) string {
+	if def.name == "day of week" {
+		return weekdayNames[value]
+	}
+	return fmt.Sprintf("%02d", value)
+}
@@ -0,0 +1,44 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronToSchedule(t *testing.T) {
+	testCases := []struct {
+		cron     string
+		schedule string
+		warning  bool
+	}{
+		{cron: "@daily", schedule: "daily"},
+		{cron: "@annually", schedule: "yearly"},
+		{cron: "30 2 * * *", schedule: "*-*-* 02:30"},
+		{cron: "0 4 * * 0", schedule: "Sun *-*-* 04:00"},
+		{cron: "0 4 * * 7", schedule: "Sun *-*-* 04:00"},
+		{cron: "0 22 * * mon-fri", schedule: "Mon..Fri *-*-* 22:00"},
+		{cron: "*/15 8-18 * * *", schedule: "*-*-* 08..18:00,15,30,45"},
+		{cron: "0 0 1,15 jan,jul *", schedule: "*-01,07-01,15 00:00"},
+		{cron: "5 1-10/3 * * *", schedule: "*-*-* 01,04,07,10:05"},
+		{cron: "0 0 1 * 1", schedule: "Mon *-*-01 00:00", warning: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.cron, func(t *testing.T) {
+			schedule, warning, err := cronToSchedule(testCase.cron)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.schedule, schedule)
+			assert.Equal(t, testCase.warning, warning != "")
+		})
+	}
+}
+
+func TestInvalidCronToSchedule(t *testing.T) {
+	for _, cron := range []string{"@reboot", "* * * *", "60 * * * *", "0 5-1 * * *", "*/0 * * * *", "0 0 * foo *"} {
+		t.Run(cron, func(t *testing.T) {
+			_, _, err := cronToSchedule(cron)
+			assert.Error(t, err)
+		})
+	}
+}
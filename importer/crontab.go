@@ -0,0 +1,391 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/restic"
+)
+
+var cronEnvPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+
+// cronVariables are the crontab variables used by cron, not by restic
+var cronVariables = []string{"MAILTO", "MAILFROM", "SHELL", "HOME", "LOGNAME", "CRON_TZ", "RANDOM_DELAY", "START_HOURS_RANGE"}
+
+// repositoryFlag is the name of the repository flag in a profile
+const repositoryFlag = "repository"
+
+// envFlags are the environment variables of restic converted into profile flags
+var envFlags = map[string]string{
+	"RESTIC_REPOSITORY":       repositoryFlag,
+	"RESTIC_REPOSITORY_FILE":  constants.ParameterRepositoryFile,
+	"RESTIC_PASSWORD_FILE":    constants.ParameterPasswordFile,
+	"RESTIC_PASSWORD_COMMAND": constants.ParameterPasswordCommand,
+}
+
+// resticCommand is a restic command line found in a crontab entry
+type resticCommand struct {
+	command     string
+	globalFlags *section
+	flags       *section
+	args        []string
+	env         map[string]string // variables set before restic in the command line
+	before      []string          // shell commands running before restic
+	after       []string          // shell commands running after restic
+	log         string
+}
+
+// Crontab converts the crontab entries running restic into a resticprofile configuration.
+// Entries using the same repository are converted into the same profile, with a schedule for each command.
+// With system set, the entries have a user name after the schedule (like in /etc/crontab or /etc/cron.d).
+func Crontab(input io.Reader, source string, system bool) (*Config, error) {
+	c := newConfig("crontab " + source)
+	env := make(map[string]string)
+	repositories := make(map[string]string) // profile name by repository
+	found := false
+
+	scanner := bufio.NewScanner(input)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if match := cronEnvPattern.FindStringSubmatch(line); match != nil {
+			if match[1] == "CRON_TZ" {
+				c.warnf("line %d: schedules in time zone %s are not converted", lineNumber, match[2])
+			}
+			if !slices.Contains(cronVariables, match[1]) {
+				env[match[1]] = strings.Trim(match[2], `"'`)
+			}
+			continue
+		}
+		context := fmt.Sprintf("line %d", lineNumber)
+
+		fieldCount := len(cronFields)
+		if strings.HasPrefix(line, "@") {
+			fieldCount = 1
+		}
+		if system {
+			fieldCount++
+		}
+		fields, commandLine := cutFields(line, fieldCount)
+		if commandLine == "" {
+			c.warnf("%s: invalid crontab entry", context)
+			continue
+		}
+		user := ""
+		if system {
+			user = fields[len(fields)-1]
+			fields = fields[:len(fields)-1]
+		}
+		commands := c.parseCronCommandLine(context, commandLine)
+		if len(commands) == 0 {
+			c.warnf("%s: no restic command, entry ignored", context)
+			continue
+		}
+
+		schedule, warning, err := cronToSchedule(strings.Join(fields, " "))
+		if err != nil {
+			c.warnf("%s: %s, entry ignored", context, err)
+			continue
+		}
+		if warning != "" {
+			c.warnf("%s: %s", context, warning)
+		}
+		found = true
+		if strings.ContainsRune(strings.ReplaceAll(commandLine, `\%`, ""), '%') {
+			c.warnf("%s: the %% character of crontab (new line) is not converted", context)
+		}
+
+		for index, command := range commands {
+			entryEnv := maps.Clone(env)
+			maps.Copy(entryEnv, command.env)
+
+			// a forget running after a backup on the same repository is the retention of the backup
+			if index > 0 && command.command == constants.CommandForget && commands[index-1].command == constants.CommandBackup &&
+				command.repository(entryEnv) == commands[index-1].repository(entryEnv) {
+				c.addRetention(repositories, command, entryEnv)
+				continue
+			}
+			section := c.addResticCommand(context, repositories, command, entryEnv)
+			if section == nil {
+				continue
+			}
+			if schedule != "" {
+				section.set("schedule", schedule)
+			}
+			if user == "root" {
+				section.set("schedule-permission", "system")
+			} else if user != "" {
+				c.warnf("%s: the command runs as user %q, set the schedule-permission", context, user)
+			}
+			if command.log != "" {
+				section.set("schedule-log", command.log)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read crontab: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("no crontab entry running restic")
+	}
+	return c, nil
+}
+
+// parseCronCommandLine returns the restic commands of the command line
+func (c *Config) parseCronCommandLine(context, commandLine string) []*resticCommand {
+	commands := make([]*resticCommand, 0, 1)
+	pending := make([]string, 0) // shell commands before the next restic command
+	for _, shell := range splitCommandLine(commandLine) {
+		if shell.operator != "" && shell.operator != "&&" && shell.operator != ";" {
+			c.warnf("%s: operator %q is converted as a sequence of commands", context, shell.operator)
+		}
+		env := make(map[string]string)
+		words := shell.words
+		for len(words) > 0 {
+			match := cronEnvPattern.FindStringSubmatch(words[0])
+			if match == nil {
+				break
+			}
+			env[match[1]] = match[2]
+			words = words[1:]
+		}
+		binary := slices.IndexFunc(words, isResticBinary)
+		if binary < 0 {
+			pending = append(pending, shell.raw)
+			continue
+		}
+		if binary > 0 {
+			c.warnf("%s: %q before restic is ignored", context, strings.Join(words[:binary], " "))
+		}
+		command := c.parseResticCommand(context, words[binary+1:])
+		if command == nil {
+			continue
+		}
+		command.env = env
+		command.before = pending
+		pending = make([]string, 0)
+		for _, redirect := range shell.redirects {
+			if redirect != "/dev/null" {
+				command.log = redirect
+			}
+		}
+		commands = append(commands, command)
+	}
+	if len(commands) > 0 && len(pending) > 0 {
+		commands[len(commands)-1].after = pending
+	}
+	return commands
+}
+
+func isResticBinary(word string) bool {
+	name := strings.ToLower(filepath.Base(word))
+	return name == "restic" || name == "restic.exe"
+}
+
+// parseResticCommand reads the command, flags and arguments of a restic command line
+func (c *Config) parseResticCommand(context string, words []string) *resticCommand {
+	command := &resticCommand{globalFlags: newSection(), flags: newSection()}
+	globals := make(map[string]restic.Option)
+	for _, option := range restic.GetDefaultOptions() {
+		globals[option.Name] = option
+	}
+
+	for index := 0; index < len(words); index++ {
+		word := words[index]
+		if word == "--" {
+			command.args = append(command.args, words[index+1:]...)
+			break
+		}
+		if !strings.HasPrefix(word, "-") || word == "-" {
+			if command.command == "" {
+				command.command = word
+			} else {
+				command.args = append(command.args, word)
+			}
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		if !strings.HasPrefix(word, "--") && len(name) > 1 {
+			if strings.Trim(name, "v") == "" {
+				command.globalFlags.set("verbose", len(name))
+				continue
+			}
+			name, value, hasValue = name[:1], name[1:], true
+		}
+		option, found := lookupOption(command.command, name, globals)
+		if !found {
+			c.warnf("%s: unknown restic flag %q", context, word)
+			option.Name = name
+		}
+		target := command.flags
+		if _, global := globals[option.Name]; global || command.command == "" {
+			target = command.globalFlags
+		}
+		flag := option.Name
+		if flag == constants.ParameterRepository {
+			flag = repositoryFlag
+		}
+
+		switch {
+		case option.Name == "verbose" && !hasValue:
+			count, _ := target.values[flag].(int)
+			target.set(flag, count+1)
+		case option.Default == "false" || (!found && !hasValue):
+			target.set(flag, value != "false")
+		default:
+			if !hasValue && index+1 < len(words) {
+				index++
+				value = words[index]
+			}
+			if number, err := strconv.Atoi(value); err == nil {
+				target.set(flag, number)
+				continue
+			}
+			if previous, exists := target.values[flag].(string); exists {
+				target.set(flag, []string{previous, value})
+			} else if _, exists = target.values[flag].([]string); exists {
+				target.appendValue(flag, value)
+			} else {
+				target.set(flag, value)
+			}
+		}
+	}
+
+	if command.command == "" {
+		c.warnf("%s: restic without command is ignored", context)
+		return nil
+	}
+	if _, found := restic.GetCommand(command.command); !found {
+		c.warnf("%s: unknown restic command %q is ignored", context, command.command)
+		return nil
+	}
+	return command
+}
+
+// lookupOption finds a restic flag by name or alias
+func lookupOption(command, name string, globals map[string]restic.Option) (restic.Option, bool) {
+	options := slices.Collect(maps.Values(globals))
+	if cmd, found := restic.GetCommand(command); found {
+		options = append(options, cmd.GetOptions()...)
+	}
+	for _, option := range options {
+		if option.Name == name || (len(name) == 1 && option.Alias == name) {
+			return option, true
+		}
+	}
+	return restic.Option{}, false
+}
+
+// repository returns the repository (or repository file) of the command
+func (r *resticCommand) repository(env map[string]string) string {
+	for _, flag := range []string{repositoryFlag, constants.ParameterRepositoryFile} {
+		if value, found := r.globalFlags.values[flag].(string); found {
+			return value
+		}
+	}
+	for _, name := range []string{"RESTIC_REPOSITORY", "RESTIC_REPOSITORY_FILE"} {
+		if value, found := env[name]; found {
+			return value
+		}
+	}
+	return ""
+}
+
+// getProfile returns the profile of the repository, creating it when needed
+func (c *Config) getProfile(repositories map[string]string, command *resticCommand, env map[string]string) *section {
+	repository := command.repository(env)
+	name, found := repositories[repository]
+	if !found {
+		name = c.uniqueProfileName(repositoryName(repository))
+		repositories[repository] = name
+		c.profiles.set(name, newSection())
+	}
+	profile := c.profiles.values[name].(*section)
+
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		if flag, isFlag := envFlags[key]; isFlag {
+			if !profile.has(flag) {
+				profile.set(flag, env[key])
+			}
+			continue
+		}
+		profile.child("env").set(key, env[key])
+	}
+	for _, key := range command.globalFlags.keys {
+		profile.set(key, command.globalFlags.values[key])
+	}
+	return profile
+}
+
+// addResticCommand adds the section of the command to the profile of its repository
+func (c *Config) addResticCommand(context string, repositories map[string]string, command *resticCommand, env map[string]string) *section {
+	profile := c.getProfile(repositories, command, env)
+	if profile.has(command.command) {
+		// the same command with another schedule or other flags: use a new profile with the same flags
+		// (not inherited, as lists of the inherited sections would be merged)
+		parent := repositories[command.repository(env)]
+		name := c.uniqueProfileName(parent + "-" + command.command)
+		flags := newSection()
+		for _, key := range profile.keys {
+			if _, isSection := profile.values[key].(*section); !isSection || key == "env" {
+				flags.set(key, profile.values[key])
+			}
+		}
+		profile = flags
+		c.profiles.set(name, profile)
+	}
+	section := profile.child(command.command)
+	for _, key := range command.flags.keys {
+		section.set(key, command.flags.values[key])
+	}
+	if len(command.args) > 0 {
+		if command.command == constants.CommandBackup {
+			section.appendValue("source", command.args...)
+		} else {
+			c.warnf("%s: arguments %q of restic %s are ignored", context, strings.Join(command.args, " "), command.command)
+		}
+	}
+	if len(command.before) > 0 {
+		section.set("run-before", command.before)
+	}
+	if len(command.after) > 0 {
+		section.set("run-after", command.after)
+	}
+	return section
+}
+
+// addRetention adds the forget command as the retention policy of the backup
+func (c *Config) addRetention(repositories map[string]string, command *resticCommand, env map[string]string) {
+	profile := c.getProfile(repositories, command, env)
+	retention := profile.child(constants.SectionConfigurationRetention)
+	retention.set("after-backup", true)
+	for _, key := range command.flags.keys {
+		retention.set(key, command.flags.values[key])
+	}
+	if len(command.after) > 0 {
+		profile.child(constants.CommandBackup).set("run-after", command.after)
+	}
+}
+
+// repositoryName returns a profile name from the last element of the repository path
+func repositoryName(repository string) string {
+	repository = strings.TrimRight(filepath.ToSlash(repository), "/:")
+	if index := strings.LastIndexAny(repository, "/:"); index >= 0 {
+		repository = repository[index+1:]
+	}
+	if repository == "" {
+		return constants.DefaultProfileName
+	}
+	return repository
+}
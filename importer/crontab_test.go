@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const crontabTest = `
+# restic backups
+MAILTO=admin@example.com
+RESTIC_PASSWORD_FILE=/etc/restic/key
+30 2 * * * /usr/local/bin/restic -r /srv/repo backup /home /etc --exclude-caches --tag nightly >> /var/log/restic.log 2>&1 && restic -r /srv/repo forget --keep-daily 7 --prune
+0 4 * * 0 pg_dump db > /tmp/db.sql && RESTIC_REPOSITORY=s3:host/bucket/db nice restic -vv backup /tmp/db.sql; rm /tmp/db.sql
+0 6 * * * restic -r /srv/repo check --read-data-subset 10
+0 7 * * * restic -r /srv/repo backup /var --unknown-flag
+@reboot restic -r /srv/repo unlock
+0 1 1 * * echo hello
+`
+
+func TestCrontab(t *testing.T) {
+	imported, err := Crontab(strings.NewReader(crontabTest), "crontab", false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"repo", "db", "repo-backup"}, imported.ProfileNames())
+	assert.Equal(t, map[string]any{
+		"password-file": "/etc/restic/key",
+		"repository":    "/srv/repo",
+		"backup": map[string]any{
+			"exclude-caches": true,
+			"tag":            "nightly",
+			"source":         []string{"/home", "/etc"},
+			"schedule":       "*-*-* 02:30",
+			"schedule-log":   "/var/log/restic.log",
+		},
+		"retention": map[string]any{"after-backup": true, "keep-daily": 7, "prune": true},
+		"check":     map[string]any{"read-data-subset": 10, "schedule": "*-*-* 06:00"},
+	}, imported.Profile("repo"))
+
+	assert.Equal(t, map[string]any{
+		"password-file": "/etc/restic/key",
+		"repository":    "s3:host/bucket/db",
+		"verbose":       2,
+		"backup": map[string]any{
+			"source":     []string{"/tmp/db.sql"},
+			"run-before": []string{"pg_dump db > /tmp/db.sql"},
+			"run-after":  []string{"rm /tmp/db.sql"},
+			"schedule":   "Sun *-*-* 04:00",
+		},
+	}, imported.Profile("db"))
+
+	assert.Equal(t, map[string]any{
+		"password-file": "/etc/restic/key",
+		"repository":    "/srv/repo",
+		"backup": map[string]any{
+			"unknown-flag": true,
+			"source":       []string{"/var"},
+			"schedule":     "*-*-* 07:00",
+		},
+	}, imported.Profile("repo-backup"))
+
+	assert.Equal(t, []string{
+		`line 6: "nice" before restic is ignored`,
+		`line 8: unknown restic flag "--unknown-flag"`,
+		`line 9: unsupported cron expression "@reboot", entry ignored`,
+		`line 10: no restic command, entry ignored`,
+	}, imported.Warnings)
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, imported.Write(buffer))
+	cfg, err := config.Load(buffer, "yaml")
+	require.NoError(t, err)
+	profile, err := cfg.GetProfile("repo-backup")
+	require.NoError(t, err)
+	assert.Equal(t, "/srv/repo", profile.Repository.Value())
+	assert.Equal(t, []string{"/var"}, profile.Backup.Source)
+}
+
+func TestSystemCrontab(t *testing.T) {
+	imported, err := Crontab(strings.NewReader("0 3 * * * root restic -r /backup backup /\n0 4 * * * user restic -r /backup check\n"), "/etc/crontab", true)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"repository": "/backup",
+		"backup":     map[string]any{"source": []string{"/"}, "schedule": "*-*-* 03:00", "schedule-permission": "system"},
+		"check":      map[string]any{"schedule": "*-*-* 04:00"},
+	}, imported.Profile("backup"))
+	assert.Equal(t, []string{`line 2: the command runs as user "user", set the schedule-permission`}, imported.Warnings)
+}
+
+func TestCrontabWithoutRestic(t *testing.T) {
+	_, err := Crontab(strings.NewReader("0 1 * * * echo hello\n"), "crontab", false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+// Package importer converts the configuration of other backup tools into a resticprofile configuration
+package importer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is a resticprofile configuration (version 1) generated from another tool
+type Config struct {
+	source   string
+	profiles *section
+	groups   *section
+	Warnings []string // items that could not be converted
+}
+
+func newConfig(source string) *Config {
+	return &Config{
+		source:   source,
+		profiles: newSection(),
+		groups:   newSection(),
+	}
+}
+
+func (c *Config) warnf(format string, args ...any) {
+	c.Warnings = append(c.Warnings, fmt.Sprintf(format, args...))
+}
+
+// ProfileNames returns the names of the generated profiles, in order
+func (c *Config) ProfileNames() []string {
+	return append([]string(nil), c.profiles.keys...)
+}
+
+// Profile returns the generated profile as a map of flags and sections
+func (c *Config) Profile(name string) map[string]any {
+	if profile, found := c.profiles.values[name].(*section); found {
+		return profile.toMap()
+	}
+	return nil
+}
+
+// Group returns the profiles of the generated group
+func (c *Config) Group(name string) []string {
+	profiles, _ := c.groups.values[name].([]string)
+	return profiles
+}
+
+// Write writes the configuration in YAML format, with the warnings as comments
+func (c *Config) Write(output io.Writer) error {
+	_, _ = fmt.Fprintf(output, "# Generated by resticprofile from %s\n", c.source)
+	for _, warning := range c.Warnings {
+		_, _ = fmt.Fprintf(output, "# WARNING: %s\n", strings.ReplaceAll(warning, "\n", " "))
+	}
+	_, _ = fmt.Fprintln(output)
+
+	root := newSection()
+	root.set("version", "1")
+	for _, name := range c.profiles.keys {
+		root.set(name, c.profiles.values[name])
+	}
+	if len(c.groups.keys) > 0 {
+		root.set("groups", c.groups)
+	}
+	encoder := yaml.NewEncoder(output)
+	encoder.SetIndent(2)
+	defer encoder.Close()
+	return encoder.Encode(root)
+}
+
+// section is a configuration section keeping the order of its keys
+type section struct {
+	keys   []string
+	values map[string]any
+}
+
+func newSection() *section {
+	return &section{values: make(map[string]any)}
+}
+
+func (s *section) set(key string, value any) {
+	if _, found := s.values[key]; !found {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = value
+}
+
+func (s *section) has(key string) bool {
+	_, found := s.values[key]
+	return found
+}
+
+// child returns the sub-section with this name, creating it when missing
+func (s *section) child(key string) *section {
+	if child, found := s.values[key].(*section); found {
+		return child
+	}
+	child := newSection()
+	s.set(key, child)
+	return child
+}
+
+// appendValue adds the value to a list
+func (s *section) appendValue(key string, values ...string) {
+	list, _ := s.values[key].([]string)
+	s.set(key, append(list, values...))
+}
+
+func (s *section) toMap() map[string]any {
+	result := make(map[string]any, len(s.keys))
+	for _, key := range s.keys {
+		if child, ok := s.values[key].(*section); ok {
+			result[key] = child.toMap()
+		} else {
+			result[key] = s.values[key]
+		}
+	}
+	return result
+}
+
+// MarshalYAML encodes the section as a mapping with the keys in order
+func (s *section) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range s.keys {
+		value := &yaml.Node{}
+		if err := value.Encode(s.values[key]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	}
+	return node, nil
+}
+
+var invalidNameCharacters = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// profileName returns a valid profile name from any string
+func profileName(name string) string {
+	name = strings.Trim(invalidNameCharacters.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" {
+		return "profile"
+	}
+	return name
+}
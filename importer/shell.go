@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// shellCommand is a simple command of a shell command line
+type shellCommand struct {
+	raw       string   // command as written in the command line
+	operator  string   // operator before the command ("" for the first one)
+	words     []string // words of the command, without quotes
+	redirects []string // files receiving the standard output
+}
+
+type shellWord struct {
+	value  string
+	quoted bool
+}
+
+var redirectPattern = regexp.MustCompile(`^([0-9&]?)(>>?|<)(&[0-9])?(.*)$`)
+
+// splitCommandLine splits a shell command line into simple commands separated by "&&", "||", "|" or ";".
+// It supports quotes and escapes, but no substitution or expansion.
+func splitCommandLine(line string) []shellCommand {
+	commands := make([]shellCommand, 0, 1)
+	words := make([]shellWord, 0)
+	word := strings.Builder{}
+	inWord, quoted := false, false
+	var quote rune
+	start := 0
+	operator := ""
+
+	endWord := func() {
+		if inWord {
+			words = append(words, shellWord{value: word.String(), quoted: quoted})
+		}
+		word.Reset()
+		inWord, quoted = false, false
+	}
+	endCommand := func(end int) {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, newShellCommand(strings.TrimSpace(line[start:end]), operator, words))
+		}
+		words = make([]shellWord, 0)
+	}
+
+	runes := []rune(line)
+	position := 0 // byte offset of the current rune
+	for index := 0; index < len(runes); index++ {
+		r := runes[index]
+		size := len(string(r))
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' && index+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[index+1]) {
+				index++
+				word.WriteRune(runes[index])
+				size += len(string(runes[index]))
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord, quoted = true, true
+		case r == '\\' && index+1 < len(runes):
+			index++
+			word.WriteRune(runes[index])
+			size += len(string(runes[index]))
+			inWord = true
+		case r == ' ' || r == '\t':
+			endWord()
+		case r == ';' || r == '|' || (r == '&' && index+1 < len(runes) && runes[index+1] == '&'):
+			op := string(r)
+			if index+1 < len(runes) && runes[index+1] == r && r != ';' {
+				op += string(r)
+			}
+			endCommand(position)
+			index += len(op) - 1
+			size = len(op)
+			operator = op
+			start = position + size
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+		position += size
+	}
+	endCommand(len(line))
+	return commands
+}
+
+func newShellCommand(raw, operator string, words []shellWord) shellCommand {
+	command := shellCommand{raw: raw, operator: operator}
+	for index := 0; index < len(words); index++ {
+		word := words[index]
+		if !word.quoted {
+			if match := redirectPattern.FindStringSubmatch(word.value); match != nil {
+				target := match[4]
+				if target == "" && match[3] == "" && index+1 < len(words) {
+					index++
+					target = words[index].value
+				}
+				if match[2] != "<" && match[1] != "2" && match[3] == "" {
+					command.redirects = append(command.redirects, target)
+				}
+				continue
+			}
+		}
+		command.words = append(command.words, word.value)
+	}
+	return command
+}
+
+// cutFields returns the first count fields of the line separated by blanks, and the rest of the line
+func cutFields(line string, count int) (fields []string, rest string) {
+	rest = strings.TrimLeft(line, " \t")
+	for len(fields) < count && rest != "" {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		fields = append(fields, rest[:end])
+		rest = strings.TrimLeft(rest[end:], " \t")
+	}
+	return
+}
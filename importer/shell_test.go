@@ -0,0 +1,23 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	commands := splitCommandLine(`pg_dump "my db" > /tmp/db.sql && FOO=bar restic backup 'a b' c\ d >>/var/log/x 2>&1; rm /tmp/db.sql || echo "a && b"`)
+	assert.Equal(t, []shellCommand{
+		{raw: `pg_dump "my db" > /tmp/db.sql`, words: []string{"pg_dump", "my db"}, redirects: []string{"/tmp/db.sql"}},
+		{raw: `FOO=bar restic backup 'a b' c\ d >>/var/log/x 2>&1`, operator: "&&", words: []string{"FOO=bar", "restic", "backup", "a b", "c d"}, redirects: []string{"/var/log/x"}},
+		{raw: `rm /tmp/db.sql`, operator: ";", words: []string{"rm", "/tmp/db.sql"}},
+		{raw: `echo "a && b"`, operator: "||", words: []string{"echo", "a && b"}},
+	}, commands)
+}
+
+func TestCutFields(t *testing.T) {
+	fields, rest := cutFields("  30 2\t* * *  restic backup  /home ", 5)
+	assert.Equal(t, []string{"30", "2", "*", "*", "*"}, fields)
+	assert.Equal(t, "restic backup  /home ", rest)
+}
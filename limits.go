@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/priority"
+	"github.com/creativeprojects/resticprofile/shell"
+)
+
+// getLimits returns the first limits of the profile with a window containing the time, or nil when no window matches
+func (r *resticWrapper) getLimits(now time.Time) *config.LimitsSection {
+	for index := range r.profile.Limits {
+		limits := &r.profile.Limits[index]
+		matches, err := limits.Matches(now)
+		if err != nil {
+			clog.Warningf("profile '%s': limits: %s", r.profile.Name, err)
+			continue
+		}
+		if matches {
+			return limits
+		}
+	}
+	return nil
+}
+
+// applyLimits overrides the bandwidth flags of the restic command with the limits of the window containing the time restic starts
+func (r *resticWrapper) applyLimits(args *shell.Args, now time.Time) {
+	limits := r.getLimits(now)
+	if limits == nil {
+		return
+	}
+	clog.Debugf("profile '%s': applying limits of window %q", r.profile.Name, limits.Window)
+	r.addLimitFlag(args, constants.ParameterLimitUpload, limits.LimitUpload)
+	r.addLimitFlag(args, constants.ParameterLimitDownload, limits.LimitDownload)
+}
+
+// applyLimitsPriority sets the priority of the window containing the time the main command starts.
+// The priority is shared by the whole process group: it is not set when profiles run in parallel
+func (r *resticWrapper) applyLimitsPriority(now time.Time) {
+	if r.setLimitsPriority == nil || r.dryRun {
+		return
+	}
+	limits := r.getLimits(now)
+	if limits == nil || (limits.Nice == nil && !limits.IONice) {
+		return
+	}
+	if r.ctx.parallel {
+		clog.Warningf("profile '%s': the priority of window %q is not applied when running profiles in parallel", r.profile.Name, limits.Window)
+		return
+	}
+	r.setLimitsPriority(limits)
+}
+
+// addLimitFlag sets the flag of a bandwidth limit, unless the limit is not set in the window or the flag is on the command line
+func (r *resticWrapper) addLimitFlag(args *shell.Args, name string, limit int) {
+	if limit <= 0 {
+		return
+	}
+	if slices.ContainsFunc(r.moreArgs, func(arg string) bool { return strings.HasPrefix(arg, "--"+name) }) {
+		return
+	}
+	args.AddFlag(name, shell.NewArg(strconv.Itoa(limit), shell.ArgConfigEscape))
+}
+
+// setPriorityFromLimits sets the priority of resticprofile and its child processes from the limits
+func setPriorityFromLimits(limits *config.LimitsSection) {
+	if limits.Nice != nil {
+		if err := priority.SetNice(*limits.Nice); err != nil {
+			clog.Warning(fmt.Errorf("limits of window %q: %w", limits.Window, err))
+		}
+	}
+	if limits.IONice {
+		if err := priority.SetIONice(limits.GetIONiceClass(), limits.IONiceLevel); err != nil {
+			clog.Warning(fmt.Errorf("limits of window %q: %w", limits.Window, err))
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/term"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLimitsWrapper(t *testing.T, arguments ...string) (*resticWrapper, *[]string) {
+	t.Helper()
+	profile := config.NewProfile(nil, "name")
+	profile.SetOtherFlag(constants.ParameterLimitUpload, 100)
+	profile.Limits = []config.LimitsSection{
+		{Window: "invalid"},
+		{Window: "Mon..Fri 08:00..18:00", LimitUpload: 2000, Nice: new(10)},
+		{Window: "Sat,Sun", LimitDownload: 5000},
+	}
+	wrapper, _ := newTestWrapper(t, profile, constants.CommandBackup, mockBinary, arguments...)
+	applied := &[]string{}
+	wrapper.setLimitsPriority = func(limits *config.LimitsSection) {
+		*applied = append(*applied, limits.Window)
+	}
+	return wrapper, applied
+}
+
+func TestGetLimits(t *testing.T) {
+	t.Parallel()
+
+	wrapper, _ := newLimitsWrapper(t)
+	// 2024-01-01 is a Monday
+	assert.Nil(t, wrapper.getLimits(time.Date(2024, time.January, 1, 7, 0, 0, 0, time.Local)))
+	limits := wrapper.getLimits(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.Local))
+	require.NotNil(t, limits)
+	assert.Equal(t, 2000, limits.LimitUpload)
+	limits = wrapper.getLimits(time.Date(2024, time.January, 6, 7, 0, 0, 0, time.Local))
+	require.NotNil(t, limits)
+	assert.Equal(t, 5000, limits.LimitDownload)
+}
+
+func TestApplyLimits(t *testing.T) {
+	t.Parallel()
+
+	monday := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.Local)
+	saturday := time.Date(2024, time.January, 6, 12, 0, 0, 0, time.Local)
+
+	t.Run("outside windows", func(t *testing.T) {
+		wrapper, applied := newLimitsWrapper(t)
+		args := wrapper.profile.GetCommandFlags(constants.CommandBackup)
+		wrapper.applyLimits(args, monday.Add(-6*time.Hour))
+		assert.Contains(t, args.GetAll(), "--limit-upload=100")
+		assert.Empty(t, *applied)
+	})
+
+	t.Run("override profile flag", func(t *testing.T) {
+		wrapper, applied := newLimitsWrapper(t)
+		args := wrapper.profile.GetCommandFlags(constants.CommandBackup)
+		wrapper.applyLimits(args, monday)
+		assert.Contains(t, args.GetAll(), "--limit-upload=2000")
+		assert.NotContains(t, args.GetAll(), "--limit-download=5000")
+
+		wrapper.applyLimits(args, saturday)
+		assert.Contains(t, args.GetAll(), "--limit-download=5000")
+		// the priority is not set by the flags of a command
+		assert.Empty(t, *applied)
+	})
+
+	t.Run("command line wins", func(t *testing.T) {
+		wrapper, _ := newLimitsWrapper(t, "--limit-upload=10")
+		args := wrapper.profile.GetCommandFlags(constants.CommandBackup)
+		wrapper.applyLimits(args, monday)
+		assert.Contains(t, args.GetAll(), "--limit-upload=100")
+	})
+
+}
+
+func TestApplyLimitsPriority(t *testing.T) {
+	t.Parallel()
+
+	monday := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.Local)
+	saturday := time.Date(2024, time.January, 6, 12, 0, 0, 0, time.Local)
+
+	wrapper, applied := newLimitsWrapper(t)
+	wrapper.applyLimitsPriority(monday)
+	assert.Equal(t, []string{"Mon..Fri 08:00..18:00"}, *applied)
+
+	// no priority in the window
+	wrapper, applied = newLimitsWrapper(t)
+	wrapper.applyLimitsPriority(saturday)
+	assert.Empty(t, *applied)
+
+	wrapper, applied = newLimitsWrapper(t)
+	wrapper.dryRun = true
+	wrapper.applyLimitsPriority(monday)
+	assert.Empty(t, *applied)
+
+	// the priority of the process group is shared by the profiles running in parallel
+	wrapper, applied = newLimitsWrapper(t)
+	wrapper.ctx.parallel = true
+	wrapper.applyLimitsPriority(monday)
+	assert.Empty(t, *applied)
+}
+
+func TestLimitsPriorityOnMainCommand(t *testing.T) {
+	t.Parallel()
+
+	wrapper, applied := newLimitsWrapper(t, "--args")
+	wrapper.profile.Limits = []config.LimitsSection{{Window: "Mon..Sun", Nice: new(10)}}
+	require.NoError(t, wrapper.runProfile())
+	assert.Equal(t, []string{"Mon..Sun"}, *applied)
+}
+
+func TestLimitsWithoutPriority(t *testing.T) {
+	t.Parallel()
+
+	wrapper := newResticWrapper(&Context{
+		profile:  config.NewProfile(nil, "name"),
+		flags:    commandLineFlags{noPriority: true},
+		terminal: term.NewTerminal(),
+	})
+	assert.Nil(t, wrapper.setLimitsPriority)
+}
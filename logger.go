@@ -12,6 +12,7 @@ import (
 	"github.com/creativeprojects/clog"
 	"github.com/creativeprojects/resticprofile/constants"
 	"github.com/creativeprojects/resticprofile/dial"
+	"github.com/creativeprojects/resticprofile/mask"
 	"github.com/creativeprojects/resticprofile/platform"
 	"github.com/creativeprojects/resticprofile/remote"
 	"github.com/creativeprojects/resticprofile/term"
@@ -170,9 +171,51 @@ func newFilteredLogger(flags commandLineFlags, handler clog.Handler) *clog.Logge
 		minLevel = clog.LevelDebug
 	}
 	// now create and return the logger
-	return clog.NewLogger(clog.NewLevelFilter(minLevel, handler))
+	return clog.NewLogger(clog.NewLevelFilter(minLevel, newMaskingHandler(handler)))
 }
 
+// maskingHandler is a log middleware masking confidential values in log messages
+type maskingHandler struct {
+	handler clog.Handler
+}
+
+func newMaskingHandler(destination clog.Handler) *maskingHandler {
+	return &maskingHandler{handler: destination}
+}
+
+// SetHandler sets a new destination handler
+func (h *maskingHandler) SetHandler(handler clog.Handler) {
+	h.handler = handler
+}
+
+// GetHandler returns the destination handler
+func (h *maskingHandler) GetHandler() clog.Handler {
+	return h.handler
+}
+
+// SetPrefix sets a prefix on every log message
+func (h *maskingHandler) SetPrefix(prefix string) clog.Handler {
+	if prefixer, ok := h.handler.(clog.Prefixer); ok {
+		prefixer.SetPrefix(prefix)
+	}
+	return h
+}
+
+// LogEntry masks the message of the log entry and sends it to the destination handler
+func (h *maskingHandler) LogEntry(logEntry clog.LogEntry) error {
+	if h.handler == nil {
+		return clog.ErrNoRegisteredHandler
+	}
+	return h.handler.LogEntry(clog.NewLogEntry(logEntry.Calldepth+1, logEntry.Level, mask.Text(logEntry.GetMessage())))
+}
+
+// Verify interface
+var (
+	_ clog.Handler           = &maskingHandler{}
+	_ clog.MiddlewareHandler = &maskingHandler{}
+	_ clog.Prefixer          = &maskingHandler{}
+)
+
 func changeLevelFilter(level clog.LogLevel) {
 	handler := clog.GetDefaultLogger().GetHandler()
 	filter, ok := handler.(*clog.LevelFilter)
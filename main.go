@@ -16,6 +16,7 @@ import (
 	"github.com/creativeprojects/resticprofile/config"
 	"github.com/creativeprojects/resticprofile/constants"
 	"github.com/creativeprojects/resticprofile/filesearch"
+	"github.com/creativeprojects/resticprofile/mask"
 	"github.com/creativeprojects/resticprofile/preventsleep"
 	"github.com/creativeprojects/resticprofile/priority"
 	"github.com/creativeprojects/resticprofile/remote"
@@ -94,6 +95,11 @@ func main() {
 
 	// help
 	if flags.help || errors.Is(flagErr, pflag.ErrHelp) {
+		var global *config.Global
+		if _, loaded, err := loadConfig(flags, true); err == nil {
+			global = loaded
+		}
+		registerPlugins(ownCommands, global)
 		_ = displayHelpCommand(commandContext{
 			ownCommands: ownCommands,
 			Context: Context{
@@ -202,6 +208,8 @@ func main() {
 			if err == nil {
 				ctx = ctx.WithConfig(cfg, global)
 			}
+			// plugins are listed in help and completion
+			registerPlugins(ownCommands, ctx.global)
 			closeLogger := setupLogging(ctx)
 			shutdown.AddHook(closeLogger)
 
@@ -295,8 +303,16 @@ func main() {
 		return
 	}
 	ctx = ctx.WithBinary(resticBinary)
+	if err = ctx.config.SetResticVersion(ctx.global.ResticVersion); err != nil {
+		clog.Error(err)
+		exitCode = constants.ExitGeneralError
+		return
+	}
 
 	// resticprofile own commands (with configuration file)
+	if isPluginCandidate(ownCommands, ctx.request.command) {
+		registerPlugins(ownCommands, ctx.global)
+	}
 	if ownCommands.Exists(ctx.request.command, true) {
 		err = ownCommands.Run(ctx)
 		if err != nil {
@@ -314,8 +330,14 @@ func main() {
 	// since it's not a resticprofile command, it's a restic command
 	ctx = ctx.WithCommand(ctx.request.command)
 
-	// it wasn't an internal command so we run a profile
-	err = startProfileOrGroup(ctx, runProfile)
+	// it wasn't an internal command so we run a profile, or all the selected profiles
+	selection, arguments, err := parseProfileSelection(ctx.request.arguments)
+	if err == nil && selection != nil {
+		ctx.request.arguments = arguments
+		err = runSelectedProfiles(ctx, selection, runProfile)
+	} else if err == nil {
+		err = startProfileOrGroup(ctx, runProfile)
+	}
 	if err != nil {
 		clog.Error(err)
 		if errors.Is(err, ErrProfileNotFound) {
@@ -345,10 +367,20 @@ func loadConfig(flags commandLineFlags, silent bool) (cfg *config.Config, global
 			clog.Infof("using configuration file: %s", configFile)
 		}
 
-		if cfg, err = config.LoadFile(configFile, flags.format); err == nil {
+		options := []func(cfg *config.Config){
+			config.WithAgeKeyFile(flags.ageKeyFile),
+			config.WithTemplateVars(flags.vars),
+			config.WithOverrides(flags.settings),
+		}
+		if cfg, err = config.LoadFile(configFile, flags.format, options...); err == nil {
 			global, err = cfg.GetGlobalSection()
+			if err == nil {
+				global, err = setTemplatesResticVersion(cfg, global)
+			}
 			if err != nil {
 				err = fmt.Errorf("cannot load global configuration: %w", err)
+			} else {
+				mask.Configure(global.GetMaskRules())
 			}
 		} else {
 			err = fmt.Errorf("cannot load configuration file: %w", err)
@@ -357,6 +389,31 @@ func loadConfig(flags commandLineFlags, silent bool) (cfg *config.Config, global
 	return
 }
 
+// setTemplatesResticVersion evaluates the templates again with the version of restic, when they compared
+// with the latest known version while the configuration was loading. It returns the global section reloaded
+func setTemplatesResticVersion(cfg *config.Config, global *config.Global) (*config.Global, error) {
+	if !cfg.ResticVersionAssumed() {
+		return global, nil
+	}
+	if len(global.ResticVersion) == 0 {
+		if _, err := detectResticBinary(global); err != nil {
+			clog.Debugf("templates use the latest known restic version: %s", err)
+			return global, nil
+		}
+	}
+	if err := cfg.SetResticVersion(global.ResticVersion); err != nil {
+		return nil, err
+	}
+	reloaded, err := cfg.GetGlobalSection()
+	if err != nil {
+		return nil, err
+	}
+	if len(reloaded.ResticVersion) == 0 {
+		reloaded.ResticVersion = global.ResticVersion
+	}
+	return reloaded, nil
+}
+
 // loadContext loads the configuration and creates a context.
 func loadContext(flags commandLineFlags) (*Context, error) {
 	cfg, global, err := loadConfig(flags, false)
@@ -130,3 +130,25 @@ func TestGetProfile(t *testing.T) {
 		assert.ErrorContains(t, err, `cannot change to base directory "`+dir+`" in profile "with-invalid-base": chdir `+dir+`: `)
 	})
 }
+
+func TestTemplatesResticVersion(t *testing.T) {
+	c, err := config.Load(strings.NewReader(`
+		[global]
+		 restic-version = "0.16.4"
+		{{ if semverCompare ">= 0.17" }}
+		[new]
+		{{ else }}
+		[old]
+		{{ end }}
+		 repository = "test-repo"
+	`), "toml")
+	require.NoError(t, err)
+	global, err := c.GetGlobalSection()
+	require.NoError(t, err)
+	require.Equal(t, []string{"new"}, c.GetProfileNames())
+
+	global, err = setTemplatesResticVersion(c, global)
+	require.NoError(t, err)
+	assert.Equal(t, "0.16.4", global.ResticVersion)
+	assert.Equal(t, []string{"old"}, c.GetProfileNames())
+}
@@ -1,16 +1,143 @@
 package mask
 
-import "regexp"
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
 
 const maskReplacement = "×××"
 
+// minValueLength is the minimum length of a known confidential value to be masked in free text
+const minValueLength = 4
+
 var (
 	RepositoryConfidentialPart = regexp.MustCompile("[:/][^:/@]+?:([^:@]+?)@[^:/@]+?") // user:pass@host
-	// httpHeaderNames     = regexp.MustCompile("(?i)^(Authorization)$")
-	// urlEnvKeys          = regexp.MustCompile("(?i)^.+(_AUTH|_URL)$")
-	// hiddenEnvKeys       = regexp.MustCompile("(?i)^(.+_KEY|.+_TOKEN|.*PASSWORD.*|.*SECRET.*)$")
+	urlQueryParameter          = regexp.MustCompile(`[?&]([^=&#\s]+)=([^&#\s"']+)`)    // ?name=value or &name=value
 )
 
+// Rules declares the names of environment variables, HTTP headers and URL query parameters holding confidential values.
+// Names are case-insensitive glob patterns where "*" matches any sequence of characters.
+type Rules struct {
+	EnvKeys         []string // values are masked entirely
+	URLEnvKeys      []string // values are URLs where credentials are masked
+	HeaderNames     []string
+	QueryParameters []string
+}
+
+// DefaultRules are always applied, in addition to the rules passed to Configure
+var DefaultRules = Rules{
+	EnvKeys:         []string{"*_KEY", "*_TOKEN", "*PASSWORD*", "*SECRET*"},
+	URLEnvKeys:      []string{"*_AUTH", "*_URL"},
+	HeaderNames:     []string{"Authorization", "Proxy-Authorization", "Cookie", "*-Token", "*-Api-Key"},
+	QueryParameters: []string{"*token*", "*secret*", "*password*", "*key", "auth", "sig", "signature"},
+}
+
+type compiledRules struct {
+	envKeys, urlEnvKeys, headerNames, queryParameters *regexp.Regexp
+	envAssignment, headerLine                         *regexp.Regexp // env and header definitions in free text
+}
+
+var (
+	current = compile(DefaultRules)
+	values  []string // known confidential values, longest first
+	lock    sync.RWMutex
+)
+
+// Configure sets the rules applied in addition to DefaultRules
+func Configure(rules Rules) {
+	merged := Rules{
+		EnvKeys:         slices.Concat(DefaultRules.EnvKeys, rules.EnvKeys),
+		URLEnvKeys:      slices.Concat(DefaultRules.URLEnvKeys, rules.URLEnvKeys),
+		HeaderNames:     slices.Concat(DefaultRules.HeaderNames, rules.HeaderNames),
+		QueryParameters: slices.Concat(DefaultRules.QueryParameters, rules.QueryParameters),
+	}
+	compiled := compile(merged)
+
+	lock.Lock()
+	defer lock.Unlock()
+	current = compiled
+}
+
+// AddValues registers confidential values that are masked wherever they appear in Text
+func AddValues(confidentialValues ...string) {
+	lock.Lock()
+	defer lock.Unlock()
+	for _, value := range confidentialValues {
+		if len(value) >= minValueLength && !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	// replace longer values first when a value contains another one
+	slices.SortStableFunc(values, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+}
+
+// Reset removes the known confidential values and restores DefaultRules
+func Reset() {
+	lock.Lock()
+	defer lock.Unlock()
+	values = nil
+	current = compile(DefaultRules)
+}
+
+func getRules() *compiledRules {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current
+}
+
+// IsConfidentialEnv returns true when the value of the environment variable name must be masked entirely
+func IsConfidentialEnv(name string) bool {
+	return getRules().envKeys.MatchString(name)
+}
+
+// IsURLEnv returns true when the value of the environment variable name is a URL which may contain credentials
+func IsURLEnv(name string) bool {
+	return getRules().urlEnvKeys.MatchString(name)
+}
+
+// IsConfidentialHeader returns true when the value of the HTTP header name must be masked entirely
+func IsConfidentialHeader(name string) bool {
+	return getRules().headerNames.MatchString(name)
+}
+
+// URLSecrets returns the confidential parts of a URL (password and query parameters)
+func URLSecrets(value string) (secrets []string) {
+	for _, span := range urlSecretSpans(getRules(), value) {
+		secrets = append(secrets, value[span[0]:span[1]])
+	}
+	return
+}
+
+// URL masks the password and the confidential query parameters of a URL
+func URL(value string) string {
+	return replaceSpans(value, urlSecretSpans(getRules(), value))
+}
+
+// Text masks all the confidential values found in a free text (a log message, the output of a command, etc.):
+// known confidential values, credentials in URLs, and values of environment variables or HTTP headers with confidential names
+func Text(text string) string {
+	if text == "" {
+		return text
+	}
+	rules := getRules()
+
+	lock.RLock()
+	for _, value := range values {
+		text = strings.ReplaceAll(text, value, maskReplacement)
+	}
+	lock.RUnlock()
+
+	text = replaceSubmatches(RepositoryConfidentialPart, text, nil)
+	text = replaceSubmatches(urlQueryParameter, text, rules.queryParameters)
+	text = replaceSubmatches(rules.envAssignment, text, nil)
+	text = replaceSubmatches(rules.headerLine, text, nil)
+	return text
+}
+
+// Submatches masks all the submatches of pattern in value
 func Submatches(pattern *regexp.Regexp, value string) string {
 	if matches := pattern.FindStringSubmatchIndex(value); len(matches) > 2 {
 		for i := len(matches) - 2; i > 1; i -= 2 {
@@ -22,3 +149,76 @@ func Submatches(pattern *regexp.Regexp, value string) string {
 	}
 	return value
 }
+
+// urlSecretSpans returns the start and end positions of the confidential parts of a URL
+func urlSecretSpans(rules *compiledRules, value string) (spans [][2]int) {
+	if matches := RepositoryConfidentialPart.FindStringSubmatchIndex(value); len(matches) > 3 {
+		spans = append(spans, [2]int{matches[2], matches[3]})
+	}
+	for _, matches := range urlQueryParameter.FindAllStringSubmatchIndex(value, -1) {
+		if rules.queryParameters.MatchString(value[matches[2]:matches[3]]) {
+			spans = append(spans, [2]int{matches[4], matches[5]})
+		}
+	}
+	return
+}
+
+// replaceSpans replaces the spans (in ascending order) of value
+func replaceSpans(value string, spans [][2]int) string {
+	for i := len(spans) - 1; i >= 0; i-- {
+		value = value[:spans[i][0]] + maskReplacement + value[spans[i][1]:]
+	}
+	return value
+}
+
+// replaceSubmatches masks the last submatch of every match of pattern in text.
+// When nameFilter is set, the first submatch must match nameFilter.
+func replaceSubmatches(pattern *regexp.Regexp, text string, nameFilter *regexp.Regexp) string {
+	var spans [][2]int
+	for _, matches := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		last := len(matches) - 2
+		if last < 2 || matches[last] < 0 {
+			continue
+		}
+		if nameFilter != nil && !nameFilter.MatchString(text[matches[2]:matches[3]]) {
+			continue
+		}
+		spans = append(spans, [2]int{matches[last], matches[last+1]})
+	}
+	return replaceSpans(text, spans)
+}
+
+func compile(rules Rules) *compiledRules {
+	return &compiledRules{
+		envKeys:         namesPattern(rules.EnvKeys),
+		urlEnvKeys:      namesPattern(rules.URLEnvKeys),
+		headerNames:     namesPattern(rules.HeaderNames),
+		queryParameters: namesPattern(rules.QueryParameters),
+		envAssignment:   regexp.MustCompile(`(?i)\b(` + globsToRegexp(rules.EnvKeys, `\w*`) + `)=([^\s"']+)`),
+		headerLine:      regexp.MustCompile(`(?i)\b(` + globsToRegexp(rules.HeaderNames, `[\w-]*`) + `)\s*:\s*([^\r\n]+)`),
+	}
+}
+
+// namesPattern returns a case-insensitive pattern matching any of the names (glob patterns)
+func namesPattern(globs []string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)^(` + globsToRegexp(globs, `.*`) + `)$`)
+}
+
+// globsToRegexp converts glob patterns to a regular expression alternation, using wildcard in place of "*"
+func globsToRegexp(globs []string, wildcard string) string {
+	alternatives := make([]string, 0, len(globs))
+	for _, glob := range globs {
+		if glob = strings.TrimSpace(glob); glob == "" {
+			continue
+		}
+		parts := strings.Split(glob, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		alternatives = append(alternatives, strings.Join(parts, wildcard))
+	}
+	if len(alternatives) == 0 {
+		return `\b\B` // never matches
+	}
+	return strings.Join(alternatives, "|")
+}
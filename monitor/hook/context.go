@@ -1,6 +1,11 @@
 package hook
 
-import "github.com/creativeprojects/resticprofile/util/templates"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/creativeprojects/resticprofile/util/templates"
+)
 
 type Context struct {
 	templates.DefaultData
@@ -9,6 +14,24 @@ type Context struct {
 	ProfileCommand string
 	Error          ErrorContext
 	Stdout         string
+	Steps          []StepContext
+	Sources        []SourceContext
+	Attempts       int
+	Retention      RetentionContext
+	Anomalies      []string
+}
+
+// attempts returns the number of attempts as text, or an empty string when the command has no retry policy
+func (c Context) attempts() string {
+	if c.Attempts > 0 {
+		return strconv.Itoa(c.Attempts)
+	}
+	return ""
+}
+
+// anomaly returns the anomalies detected in the backup summary as text
+func (c Context) anomaly() string {
+	return strings.Join(c.Anomalies, ", ")
 }
 
 type ErrorContext struct {
@@ -17,3 +40,39 @@ type ErrorContext struct {
 	ExitCode    string
 	Stderr      string
 }
+
+// RetentionContext is set when the retention safety refused to remove the snapshots
+type RetentionContext struct {
+	Remove int // number of snapshots the retention would remove
+	Total  int // number of snapshots before the retention
+}
+
+// StepContext is the result of a step of a composite command
+type StepContext struct {
+	Command  string
+	Success  bool
+	Error    string
+	Duration string
+}
+
+// SourceContext is the result of a stdin source of the backup
+type SourceContext struct {
+	Name     string
+	Success  bool
+	Error    string
+	Duration string
+}
+
+func (c RetentionContext) remove() string {
+	if c.Total > 0 {
+		return strconv.Itoa(c.Remove)
+	}
+	return ""
+}
+
+func (c RetentionContext) total() string {
+	if c.Total > 0 {
+		return strconv.Itoa(c.Total)
+	}
+	return ""
+}
@@ -218,6 +218,18 @@ func resolveBody(body string, ctx Context, env *util.Environment) string {
 		case constants.EnvProfileCommand:
 			return ctx.ProfileCommand
 
+		case constants.EnvAttempts:
+			return ctx.attempts()
+
+		case constants.EnvRetentionRemove:
+			return ctx.Retention.remove()
+
+		case constants.EnvRetentionTotal:
+			return ctx.Retention.total()
+
+		case constants.EnvAnomaly:
+			return ctx.anomaly()
+
 		case constants.EnvError:
 			return ctx.Error.Message
 
@@ -248,6 +260,18 @@ func resolveURL(url string, ctx Context, env *util.Environment) string {
 		case constants.EnvProfileCommand:
 			return ctx.ProfileCommand
 
+		case constants.EnvAttempts:
+			return ctx.attempts()
+
+		case constants.EnvRetentionRemove:
+			return ctx.Retention.remove()
+
+		case constants.EnvRetentionTotal:
+			return ctx.Retention.total()
+
+		case constants.EnvAnomaly:
+			return urlpkg.QueryEscape(ctx.anomaly())
+
 		case constants.EnvError:
 			return urlpkg.QueryEscape(ctx.Error.Message)
 
@@ -326,6 +326,32 @@ func TestURLEncoding(t *testing.T) {
 	assert.Equal(t, 1, calls)
 }
 
+func TestURLEncodingOfAttemptsAndAnomalies(t *testing.T) {
+	ctx := Context{
+		Attempts:  2,
+		Anomalies: []string{"files & size", "total"},
+	}
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "2", query.Get("attempts"))
+		assert.Equal(t, "files & size, total", query.Get("anomaly"))
+		calls++
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_MONITOR_URL", server.URL)
+	serverURL := fmt.Sprintf("$TEST_MONITOR_URL/?attempts=$%s&anomaly=$%s", constants.EnvAttempts, constants.EnvAnomaly)
+
+	sender := NewSender(nil, "", 300*time.Millisecond, false)
+	err := sender.Send(config.SendMonitoringSection{
+		URL: config.NewConfidentialValue(serverURL),
+	}, ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
 func TestConfidentialHeader(t *testing.T) {
 	clog.SetTestLog(t)
 	defer clog.CloseTestLog()
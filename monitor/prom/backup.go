@@ -10,6 +10,7 @@ const (
 	StatusFailed Status = iota
 	StatusWarning
 	StatusSuccess
+	StatusSkipped
 )
 
 type BackupMetrics struct {
@@ -100,7 +101,7 @@ func newBackupMetrics(labels []string) BackupMetrics {
 			Namespace: namespace,
 			Subsystem: backup,
 			Name:      "status",
-			Help:      "Backup status: 0=fail, 1=warning, 2=success.",
+			Help:      "Backup status: 0=fail, 1=warning, 2=success, 3=skipped (no change in the sources).",
 		}, labels),
 		time: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Namespace: namespace,
@@ -99,6 +99,12 @@ func (p *Metrics) BackupResults(status Status, summary monitor.Summary) {
 	p.backup.time.With(p.labels).Set(float64(time.Now().Unix()))
 }
 
+// BackupSkipped only updates the status and the time of the backup: the counters of the previous backup are kept
+func (p *Metrics) BackupSkipped() {
+	p.backup.status.With(p.labels).Set(float64(StatusSkipped))
+	p.backup.time.With(p.labels).Set(float64(time.Now().Unix()))
+}
+
 func (p *Metrics) SaveTo(filename string) error {
 	return prometheus.WriteToTextfile(filename, p.registry)
 }
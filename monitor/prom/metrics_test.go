@@ -1,6 +1,7 @@
 package prom
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -41,3 +42,15 @@ func TestSaveBackupGroup(t *testing.T) {
 	err := p.SaveTo(filepath.Join(t.TempDir(), "test_group.prom"))
 	require.NoError(t, err)
 }
+
+func TestSaveSkippedBackup(t *testing.T) {
+	p := NewMetrics("test", "", "", "", nil)
+	p.BackupSkipped()
+	filename := filepath.Join(t.TempDir(), "test_skipped.prom")
+	err := p.SaveTo(filename)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.Contains(t, string(content), `resticprofile_backup_status{profile="test"} 3`)
+}
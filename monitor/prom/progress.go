@@ -38,18 +38,22 @@ func (p *Progress) Summary(command string, summary monitor.Summary, stderr strin
 	if command != constants.CommandBackup {
 		return
 	}
-	var status Status
-	switch {
-	case monitor.IsSuccess(result):
-		status = StatusSuccess
+	if summary.Skipped {
+		p.metrics.BackupSkipped()
+	} else {
+		var status Status
+		switch {
+		case monitor.IsSuccess(result):
+			status = StatusSuccess
 
-	case monitor.IsWarning(result):
-		status = StatusWarning
+		case monitor.IsWarning(result):
+			status = StatusWarning
 
-	case monitor.IsError(result):
-		status = StatusFailed
+		case monitor.IsError(result):
+			status = StatusFailed
+		}
+		p.metrics.BackupResults(status, summary)
 	}
-	p.metrics.BackupResults(status, summary)
 
 	if p.profile.PrometheusSaveToFile != "" {
 		err := p.metrics.SaveTo(p.profile.PrometheusSaveToFile)
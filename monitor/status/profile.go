@@ -4,14 +4,16 @@ import (
 	"math"
 	"time"
 
+	"github.com/creativeprojects/resticprofile/mask"
 	"github.com/creativeprojects/resticprofile/monitor"
 )
 
 // Profile status
 type Profile struct {
-	Backup    *BackupStatus  `json:"backup,omitempty"`
-	Retention *CommandStatus `json:"retention,omitempty"`
-	Check     *CommandStatus `json:"check,omitempty"`
+	Backup    *BackupStatus               `json:"backup,omitempty"`
+	Retention *CommandStatus              `json:"retention,omitempty"`
+	Check     *CommandStatus              `json:"check,omitempty"`
+	Commands  map[string]*CompositeStatus `json:"commands,omitempty"`
 }
 
 func newProfile() *Profile {
@@ -25,6 +27,22 @@ type CommandStatus struct {
 	Error    string    `json:"error"`
 	Stderr   string    `json:"stderr"`
 	Duration int64     `json:"duration"`
+	Attempts int       `json:"attempts,omitempty"`
+}
+
+// CompositeStatus is the last status of a composite command, with the status of each step that ran
+type CompositeStatus struct {
+	CommandStatus
+
+	Steps []StepStatus `json:"steps"`
+}
+
+// StepStatus is the status of a step of a composite command
+type StepStatus struct {
+	Command  string `json:"command"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration"`
 }
 
 // BackupStatus contains the last backup status
@@ -41,6 +59,36 @@ type BackupStatus struct {
 	BytesAdded       uint64 `json:"bytes_added"`
 	BytesAddedPacked uint64 `json:"bytes_added_packed"`
 	BytesTotal       uint64 `json:"bytes_total"`
+
+	Skipped   bool           `json:"skipped,omitempty"`
+	Anomaly   bool           `json:"anomaly,omitempty"`
+	Anomalies []string       `json:"anomalies,omitempty"`
+	Sources   []SourceStatus `json:"sources,omitempty"`
+}
+
+// SourceStatus is the status of a stdin source of the backup
+type SourceStatus struct {
+	Name       string `json:"name"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+	Duration   int64  `json:"duration"`
+	BytesAdded uint64 `json:"bytes_added"`
+}
+
+func newSourcesStatus(sources []monitor.SourceSummary) (status []SourceStatus) {
+	for _, source := range sources {
+		sourceStatus := SourceStatus{
+			Name:       source.Name,
+			Success:    source.Error == nil,
+			Duration:   int64(math.Ceil(source.Duration.Seconds())),
+			BytesAdded: source.BytesAdded,
+		}
+		if source.Error != nil {
+			sourceStatus.Error = mask.Text(source.Error.Error())
+		}
+		status = append(status, sourceStatus)
+	}
+	return
 }
 
 // BackupSuccess indicates the last backup was successful
@@ -50,7 +98,8 @@ func (p *Profile) BackupSuccess(summary monitor.Summary, stderr string) *Profile
 			Success:  true,
 			Time:     time.Now(),
 			Duration: int64(math.Ceil(summary.Duration.Seconds())),
-			Stderr:   stderr,
+			Attempts: summary.Attempts,
+			Stderr:   mask.Text(stderr),
 		},
 		FilesNew:         summary.FilesNew,
 		FilesChanged:     summary.FilesChanged,
@@ -62,7 +111,28 @@ func (p *Profile) BackupSuccess(summary monitor.Summary, stderr string) *Profile
 		BytesAdded:       summary.BytesAdded,
 		BytesAddedPacked: summary.BytesAddedPacked,
 		BytesTotal:       summary.BytesTotal,
+		Anomaly:          len(summary.Anomalies) > 0,
+		Anomalies:        summary.Anomalies,
+		Sources:          newSourcesStatus(summary.Sources),
+	}
+	return p
+}
+
+// BackupSkipped indicates the last backup was skipped as the sources did not change.
+// The counters of the previous backup are kept.
+func (p *Profile) BackupSkipped(summary monitor.Summary) *Profile {
+	if p.Backup == nil {
+		p.Backup = &BackupStatus{}
+	}
+	p.Backup.CommandStatus = CommandStatus{
+		Success:  true,
+		Time:     time.Now(),
+		Duration: int64(math.Ceil(summary.Duration.Seconds())),
 	}
+	p.Backup.Skipped = true
+	p.Backup.Anomaly = false
+	p.Backup.Anomalies = nil
+	p.Backup.Sources = nil
 	return p
 }
 
@@ -72,9 +142,10 @@ func (p *Profile) BackupError(err error, summary monitor.Summary, stderr string)
 		CommandStatus: CommandStatus{
 			Success:  false,
 			Time:     time.Now(),
-			Error:    err.Error(),
+			Error:    mask.Text(err.Error()),
 			Duration: int64(math.Ceil(summary.Duration.Seconds())),
-			Stderr:   stderr,
+			Attempts: summary.Attempts,
+			Stderr:   mask.Text(stderr),
 		},
 		FilesNew:         0,
 		FilesChanged:     0,
@@ -86,49 +157,86 @@ func (p *Profile) BackupError(err error, summary monitor.Summary, stderr string)
 		BytesAdded:       0,
 		BytesAddedPacked: 0,
 		BytesTotal:       0,
+		Sources:          newSourcesStatus(summary.Sources),
 	}
 	return p
 }
 
 // RetentionSuccess indicates the last retention was successful
 func (p *Profile) RetentionSuccess(summary monitor.Summary, stderr string) *Profile {
-	p.Retention = newSuccess(summary.Duration, stderr)
+	p.Retention = newSuccess(summary, stderr)
 	return p
 }
 
 // RetentionError sets the error of the last retention
 func (p *Profile) RetentionError(err error, summary monitor.Summary, stderr string) *Profile {
-	p.Retention = newError(err, summary.Duration, stderr)
+	p.Retention = newError(err, summary, stderr)
 	return p
 }
 
 // CheckSuccess indicates the last check was successful
 func (p *Profile) CheckSuccess(summary monitor.Summary, stderr string) *Profile {
-	p.Check = newSuccess(summary.Duration, stderr)
+	p.Check = newSuccess(summary, stderr)
 	return p
 }
 
 // CheckError sets the error of the last check
 func (p *Profile) CheckError(err error, summary monitor.Summary, stderr string) *Profile {
-	p.Check = newError(err, summary.Duration, stderr)
+	p.Check = newError(err, summary, stderr)
 	return p
 }
 
-func newSuccess(duration time.Duration, stderr string) *CommandStatus {
+// CompositeSuccess indicates the last run of the composite command was successful
+func (p *Profile) CompositeSuccess(command string, summary monitor.Summary, stderr string) *Profile {
+	p.setComposite(command, newSuccess(summary, stderr), summary.Steps)
+	return p
+}
+
+// CompositeError sets the error of the last run of the composite command
+func (p *Profile) CompositeError(command string, err error, summary monitor.Summary, stderr string) *Profile {
+	p.setComposite(command, newError(err, summary, stderr), summary.Steps)
+	return p
+}
+
+func (p *Profile) setComposite(command string, status *CommandStatus, steps []monitor.StepSummary) {
+	composite := &CompositeStatus{
+		CommandStatus: *status,
+		Steps:         make([]StepStatus, 0, len(steps)),
+	}
+	for _, step := range steps {
+		stepStatus := StepStatus{
+			Command:  step.Command,
+			Success:  step.Error == nil,
+			Duration: int64(math.Ceil(step.Duration.Seconds())),
+		}
+		if step.Error != nil {
+			stepStatus.Error = mask.Text(step.Error.Error())
+		}
+		composite.Steps = append(composite.Steps, stepStatus)
+	}
+	if p.Commands == nil {
+		p.Commands = make(map[string]*CompositeStatus)
+	}
+	p.Commands[command] = composite
+}
+
+func newSuccess(summary monitor.Summary, stderr string) *CommandStatus {
 	return &CommandStatus{
 		Success:  true,
 		Time:     time.Now(),
-		Duration: int64(math.Ceil(duration.Seconds())),
-		Stderr:   stderr,
+		Duration: int64(math.Ceil(summary.Duration.Seconds())),
+		Attempts: summary.Attempts,
+		Stderr:   mask.Text(stderr),
 	}
 }
 
-func newError(err error, duration time.Duration, stderr string) *CommandStatus {
+func newError(err error, summary monitor.Summary, stderr string) *CommandStatus {
 	return &CommandStatus{
 		Success:  false,
 		Time:     time.Now(),
-		Error:    err.Error(),
-		Duration: int64(math.Ceil(duration.Seconds())),
-		Stderr:   stderr,
+		Error:    mask.Text(err.Error()),
+		Duration: int64(math.Ceil(summary.Duration.Seconds())),
+		Attempts: summary.Attempts,
+		Stderr:   mask.Text(stderr),
 	}
 }
@@ -61,7 +61,11 @@ func (p *Progress) success(command string, summary monitor.Summary, stderr strin
 	switch command {
 	case constants.CommandBackup:
 		status := p.getGenerator()
-		status.Profile(p.profile.Name).BackupSuccess(summary, stderr)
+		if summary.Skipped {
+			status.Profile(p.profile.Name).BackupSkipped(summary)
+		} else {
+			status.Profile(p.profile.Name).BackupSuccess(summary, stderr)
+		}
 		err = status.Save()
 	case constants.CommandCheck:
 		status := p.getGenerator()
@@ -71,6 +75,12 @@ func (p *Progress) success(command string, summary monitor.Summary, stderr strin
 		status := p.getGenerator()
 		status.Profile(p.profile.Name).RetentionSuccess(summary, stderr)
 		err = status.Save()
+	default:
+		if _, found := p.profile.GetCompositeCommand(command); found {
+			status := p.getGenerator()
+			status.Profile(p.profile.Name).CompositeSuccess(command, summary, stderr)
+			err = status.Save()
+		}
 	}
 	if err != nil {
 		// not important enough to throw an error here
@@ -93,6 +103,12 @@ func (p *Progress) error(command string, summary monitor.Summary, stderr string,
 		status := p.getGenerator()
 		status.Profile(p.profile.Name).RetentionError(fail, summary, stderr)
 		err = status.Save()
+	default:
+		if _, found := p.profile.GetCompositeCommand(command); found {
+			status := p.getGenerator()
+			status.Profile(p.profile.Name).CompositeError(command, fail, summary, stderr)
+			err = status.Save()
+		}
 	}
 	if err != nil {
 		// not important enough to throw an error here
@@ -16,6 +16,26 @@ type Summary struct {
 	BytesAddedPacked uint64
 	BytesTotal       uint64
 	OutputAnalysis   OutputAnalysis
+	Steps            []StepSummary
+	Attempts         int      // number of runs of the command with a retry policy (0 without retry policy)
+	Anomalies        []string // anomalies detected in the summary of a backup
+	Skipped          bool     // the backup did not run as the sources did not change
+	Sources          []SourceSummary
+}
+
+// StepSummary of a restic command run as a step of a composite command
+type StepSummary struct {
+	Command  string
+	Duration time.Duration
+	Error    error
+}
+
+// SourceSummary of a stdin source backed up in a snapshot of its own
+type SourceSummary struct {
+	Name       string
+	Duration   time.Duration
+	BytesAdded uint64
+	Error      error
 }
 
 // OutputAnalysis of the profile run
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// runnerWithMounts wraps the action with the mount of the volumes of the profile.
+// The volumes are unmounted by unmountVolumes, in the finally phase of the profile
+func (r *resticWrapper) runnerWithMounts(action func() error) func() error {
+	return func() error {
+		if err := r.mountVolumes(); err != nil {
+			return err
+		}
+		return action()
+	}
+}
+
+// mountVolumes mounts the volumes of the profile, and waits until they are ready
+func (r *resticWrapper) mountVolumes() error {
+	for index := range r.profile.Mounts {
+		mount := &r.profile.Mounts[index]
+		if err := r.mountVolume(mount); err != nil {
+			return fmt.Errorf("mount %q on profile '%s': %w", mount.MountPoint, r.profile.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *resticWrapper) mountVolume(mount *config.MountSection) error {
+	if mount.MountPoint == "" {
+		return errors.New("missing mount point")
+	}
+	mounted, err := r.isMounted(mount.MountPoint)
+	if err != nil {
+		return err
+	}
+	if mounted {
+		clog.Debugf("profile '%s': %s is already mounted", r.profile.Name, mount.MountPoint)
+	} else if mount.Mount.IsTrueOrUndefined() {
+		if mount.Device == "" {
+			return errors.New("missing device")
+		}
+		if err = os.MkdirAll(mount.MountPoint, 0o700); err != nil {
+			return err
+		}
+		args := make([]string, 0, 6)
+		if mount.Type != "" {
+			args = append(args, "-t", mount.Type)
+		}
+		if len(mount.Options) > 0 {
+			args = append(args, "-o", strings.Join(mount.Options, ","))
+		}
+		args = append(args, mount.Device, mount.MountPoint)
+
+		clog.Infof("profile '%s': mounting %s on %s", r.profile.Name, mount.Device, mount.MountPoint)
+		if err = r.commandRunner.Run("mount", args...); err != nil {
+			return err
+		}
+		if mount.UnmountAfter.IsTrueOrUndefined() {
+			r.mounted = append(r.mounted, mount.MountPoint)
+		}
+	}
+	if r.dryRun {
+		return nil
+	}
+	return r.waitForMount(mount)
+}
+
+// waitForMount waits until the volume is mounted and ready, or until the wait timeout
+func (r *resticWrapper) waitForMount(mount *config.MountSection) error {
+	deadline := time.Now().Add(mount.GetWaitTimeout())
+	for {
+		err := r.checkMountReady(mount)
+		if err == nil || !time.Now().Before(deadline) {
+			return err
+		}
+		clog.Debugf("profile '%s': waiting for %s: %s", r.profile.Name, mount.MountPoint, err)
+		if err = interruptibleSleep(constants.MountReadyRetryDelay, r.sigChan); err != nil {
+			return err
+		}
+	}
+}
+
+// checkMountReady returns an error when the volume is not mounted, not readable, or when its ready-file is missing
+func (r *resticWrapper) checkMountReady(mount *config.MountSection) error {
+	// reading the mount point first triggers an automount
+	if _, err := os.ReadDir(mount.MountPoint); err != nil {
+		return fmt.Errorf("volume is not readable: %w", err)
+	}
+	mounted, err := r.isMounted(mount.MountPoint)
+	if err != nil {
+		return err
+	}
+	if !mounted {
+		return errors.New("volume is not mounted")
+	}
+	if mount.ReadyFile != "" {
+		if _, err = os.Stat(filepath.Join(mount.MountPoint, mount.ReadyFile)); err != nil {
+			return fmt.Errorf("volume is not ready: %w", err)
+		}
+	}
+	return nil
+}
+
+// unmountVolumes unmounts the volumes mounted by resticprofile, in reverse order. It runs in the finally phase of the profile
+func (r *resticWrapper) unmountVolumes() {
+	for len(r.mounted) > 0 {
+		mountPoint := r.mounted[len(r.mounted)-1]
+		r.mounted = r.mounted[:len(r.mounted)-1]
+		if err := r.unmountVolume(mountPoint); err != nil {
+			clog.Errorf("profile '%s': cannot unmount %s: %s", r.profile.Name, mountPoint, err)
+		}
+	}
+}
+
+// unmountVolume unmounts the volume, retrying when the volume is busy. A volume still busy after the last attempt
+// is detached with a lazy unmount (forced unmount on other systems than linux), so no stale mount is left behind
+func (r *resticWrapper) unmountVolume(mountPoint string) (err error) {
+	clog.Infof("profile '%s': unmounting %s", r.profile.Name, mountPoint)
+	for attempt := 1; attempt <= constants.UnmountMaxAttempts; attempt++ {
+		if attempt > 1 {
+			clog.Debugf("profile '%s': unmount %s failed, attempt %d/%d", r.profile.Name, mountPoint, attempt, constants.UnmountMaxAttempts)
+			time.Sleep(constants.UnmountRetryDelay)
+		}
+		if err = r.commandRunner.Run("umount", mountPoint); err == nil {
+			return nil
+		}
+	}
+	flag := "-f"
+	if runtime.GOOS == "linux" {
+		flag = "-l"
+	}
+	clog.Warningf("profile '%s': %s is busy, using \"umount %s\"", r.profile.Name, mountPoint, flag)
+	return errors.Join(err, r.commandRunner.Run("umount", flag, mountPoint))
+}
+
+// isMountPoint returns true when the path is a mount point
+func isMountPoint(path string) (bool, error) {
+	partitions, err := disk.Partitions(true)
+	if err != nil {
+		return false, fmt.Errorf("cannot list mount points: %w", err)
+	}
+	path = filepath.Clean(path)
+	return slices.ContainsFunc(partitions, func(partition disk.PartitionStat) bool {
+		return filepath.Clean(partition.Mountpoint) == path
+	}), nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/util/maybe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMountsWrapper returns a wrapper where the mount and umount commands of the fake runner change the mounted state
+func newMountsWrapper(t *testing.T, binary string, mounts ...config.MountSection) (*resticWrapper, *fakeCommandRunner, *atomic.Bool) {
+	t.Helper()
+	profile := config.NewProfile(nil, "name")
+	profile.Mounts = mounts
+	wrapper, _ := newTestWrapper(t, profile, constants.CommandCheck, binary)
+	mounted := &atomic.Bool{}
+	runner := &fakeCommandRunner{onRun: func(commandLine string) {
+		mounted.Store(strings.HasPrefix(commandLine, "mount "))
+	}}
+	wrapper.commandRunner = runner
+	wrapper.isMounted = func(string) (bool, error) { return mounted.Load(), nil }
+	return wrapper, runner, mounted
+}
+
+func TestMountAndUnmount(t *testing.T) {
+	t.Parallel()
+
+	for _, binary := range []string{mockBinary, "exit"} {
+		mountPoint := filepath.Join(t.TempDir(), "nas")
+		wrapper, runner, _ := newMountsWrapper(t, binary, config.MountSection{
+			Device:     "nas:/backup",
+			MountPoint: mountPoint,
+			Type:       "nfs",
+			Options:    []string{"vers=4", "ro"},
+		})
+		err := wrapper.runProfile()
+		if binary == "exit" {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+		// the volume is unmounted even when restic failed
+		assert.Equal(t, []string{
+			"mount -t nfs -o vers=4,ro nas:/backup " + mountPoint,
+			"umount " + mountPoint,
+		}, runner.commands)
+		assert.DirExists(t, mountPoint)
+	}
+}
+
+func TestMountAlreadyMounted(t *testing.T) {
+	t.Parallel()
+
+	wrapper, runner, mounted := newMountsWrapper(t, mockBinary, config.MountSection{Device: "nas:/backup", MountPoint: t.TempDir()})
+	mounted.Store(true)
+	require.NoError(t, wrapper.runProfile())
+	assert.Empty(t, runner.commands)
+}
+
+func TestMountWithoutUnmount(t *testing.T) {
+	t.Parallel()
+
+	wrapper, runner, _ := newMountsWrapper(t, mockBinary, config.MountSection{
+		Device:       "nas:/backup",
+		MountPoint:   t.TempDir(),
+		UnmountAfter: maybe.False(),
+	})
+	require.NoError(t, wrapper.runProfile())
+	require.Len(t, runner.commands, 1)
+	assert.True(t, strings.HasPrefix(runner.commands[0], "mount "))
+}
+
+func TestMountFailure(t *testing.T) {
+	t.Parallel()
+
+	testFile := filepath.Join(t.TempDir(), "TestMountFailure.txt")
+	wrapper, runner, _ := newMountsWrapper(t, mockBinary, config.MountSection{Device: "nas:/backup", MountPoint: t.TempDir()})
+	wrapper.profile.RunAfterFail = []string{"echo failed > " + testFile}
+	runner.fail = "mount"
+
+	err := wrapper.runProfile()
+	assert.ErrorContains(t, err, "on profile 'name': failed")
+	assert.Len(t, runner.commands, 1)
+	assert.FileExists(t, testFile, "the run-after-fail script has not been running")
+}
+
+func TestMountWaitForReady(t *testing.T) {
+	t.Parallel()
+
+	mountPoint := t.TempDir()
+	wrapper, _, mounted := newMountsWrapper(t, mockBinary, config.MountSection{
+		MountPoint:  mountPoint,
+		Mount:       maybe.False(),
+		ReadyFile:   "ready",
+		WaitTimeout: 5 * time.Second,
+	})
+	// mounted by another service
+	timer := time.AfterFunc(200*time.Millisecond, func() {
+		mounted.Store(true)
+		_ = os.WriteFile(filepath.Join(mountPoint, "ready"), []byte{}, 0o600)
+	})
+	defer timer.Stop()
+
+	require.NoError(t, wrapper.runProfile())
+}
+
+func TestMountWaitTimeout(t *testing.T) {
+	t.Parallel()
+
+	wrapper, runner, _ := newMountsWrapper(t, mockBinary, config.MountSection{
+		MountPoint:  t.TempDir(),
+		Mount:       maybe.False(),
+		WaitTimeout: 100 * time.Millisecond,
+	})
+	err := wrapper.runProfile()
+	assert.ErrorContains(t, err, "volume is not mounted")
+	assert.Empty(t, runner.commands)
+}
+
+func TestMountWaitInterrupted(t *testing.T) {
+	t.Parallel()
+
+	wrapper, runner, _ := newMountsWrapper(t, mockBinary, config.MountSection{
+		Device:      "nas:/backup",
+		MountPoint:  t.TempDir(),
+		ReadyFile:   "ready",
+		WaitTimeout: time.Minute,
+	})
+	timer := time.AfterFunc(200*time.Millisecond, func() {
+		wrapper.sigChan <- os.Interrupt
+	})
+	defer timer.Stop()
+
+	start := time.Now()
+	err := wrapper.runProfile()
+	assert.ErrorIs(t, err, errInterrupt)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	// the volume mounted before the interruption is unmounted
+	require.Len(t, runner.commands, 2)
+	assert.True(t, strings.HasPrefix(runner.commands[1], "umount "))
+}
+
+func TestUnmountBusyVolume(t *testing.T) {
+	t.Parallel()
+
+	wrapper, runner, _ := newMountsWrapper(t, mockBinary)
+	runner.fail = "umount /mnt"
+	wrapper.mounted = []string{"/mnt/nas"}
+	wrapper.unmountVolumes()
+
+	lazy := "umount -l /mnt/nas"
+	if runtime.GOOS != "linux" {
+		lazy = "umount -f /mnt/nas"
+	}
+	assert.Equal(t, []string{"umount /mnt/nas", "umount /mnt/nas", "umount /mnt/nas", lazy}, runner.commands)
+	assert.Empty(t, wrapper.mounted)
+}
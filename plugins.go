@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/platform"
+	"github.com/creativeprojects/resticprofile/restic"
+	"github.com/creativeprojects/resticprofile/util"
+)
+
+// pluginPrefix is the prefix of the executables discovered as plugin commands
+const pluginPrefix = "resticprofile-"
+
+var validPluginName = regexp.MustCompile(`^\w[-\w]*$`).MatchString
+
+// windowsPluginExtensions are the extensions of executable files on Windows
+var windowsPluginExtensions = []string{".exe", ".com", ".bat", ".cmd"}
+
+// findPlugins searches the directories, then the PATH, for "resticprofile-<name>" executables.
+// It returns the path of each plugin by name. The first executable found for a name wins.
+func findPlugins(dirs []string) map[string]string {
+	plugins := make(map[string]string)
+	searchPath := append(slices.Clone(dirs), filepath.SplitList(os.Getenv("PATH"))...)
+	for _, dir := range searchPath {
+		if dir == "" {
+			continue
+		}
+		if absolute, err := filepath.Abs(dir); err == nil {
+			dir = absolute
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := getPluginName(dir, entry)
+			if !ok {
+				continue
+			}
+			if _, found := plugins[name]; found {
+				continue
+			}
+			plugins[name] = filepath.Join(dir, entry.Name())
+		}
+	}
+	return plugins
+}
+
+// getPluginName returns the name of the plugin when the directory entry is a plugin executable
+func getPluginName(dir string, entry os.DirEntry) (string, bool) {
+	name, found := strings.CutPrefix(strings.ToLower(entry.Name()), pluginPrefix)
+	if !found || entry.IsDir() {
+		return "", false
+	}
+	info, err := os.Stat(filepath.Join(dir, entry.Name())) // follows symlinks
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	if platform.IsWindows() {
+		extension := filepath.Ext(name)
+		if !slices.Contains(windowsPluginExtensions, extension) {
+			return "", false
+		}
+		name = strings.TrimSuffix(name, extension)
+	} else if info.Mode().Perm()&0o111 == 0 {
+		return "", false
+	}
+	return name, validPluginName(name)
+}
+
+// isPluginCandidate returns true when the command is neither an own command nor a restic command:
+// the plugins are only searched when running an unknown command
+func isPluginCandidate(commands *OwnCommands, command string) bool {
+	return command != "" && commands.find(command) == nil && !slices.Contains(restic.CommandNames(), command)
+}
+
+// registerPlugins adds the plugins found in the plugin directories and the PATH to the own commands.
+// Plugins cannot replace an own command or a restic command.
+func registerPlugins(commands *OwnCommands, global *config.Global) {
+	var dirs []string
+	if global != nil {
+		dirs = global.PluginDirs
+	}
+	plugins := findPlugins(dirs)
+	resticCommands := restic.CommandNames()
+
+	for _, name := range slices.Sorted(maps.Keys(plugins)) {
+		path := plugins[name]
+		if commands.find(name) != nil || slices.Contains(resticCommands, name) {
+			clog.Debugf("plugin %q ignored: %q is already a command", path, name)
+			continue
+		}
+		commands.Register([]ownCommand{
+			{
+				name:              name,
+				description:       "plugin " + filepath.Base(path),
+				longDescription:   fmt.Sprintf("The \"%s\" command runs the plugin executable %q with the selected profile", name, path),
+				action:            runPlugin(path),
+				needConfiguration: true,
+			},
+		})
+	}
+}
+
+// runPlugin returns the action running the plugin executable with the environment of the selected profile
+func runPlugin(path string) func(commandContext) error {
+	return func(ctx commandContext) error {
+		env, err := getPluginEnvironment(ctx)
+		if err != nil {
+			return err
+		}
+
+		cmd := exec.Command(path, ctx.request.arguments...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = ctx.terminal
+		cmd.Stderr = os.Stderr
+		cmd.Env = os.Environ()
+		for _, name := range slices.Sorted(maps.Keys(env)) {
+			cmd.Env = append(cmd.Env, name+"="+env[name])
+		}
+
+		clog.Debugf("starting plugin %q", path)
+		if err = cmd.Run(); err != nil {
+			err = fmt.Errorf("plugin %q: %w", ctx.request.command, err)
+			if exitError := new(exec.ExitError); errors.As(err, &exitError) && exitError.ExitCode() > 0 {
+				return newOwnCommandError(err, exitError.ExitCode())
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// getPluginEnvironment returns the environment given to a plugin: the environment of the selected profile
+// (like the "env" command), the profile or group as JSON and the paths of the configuration and binaries.
+func getPluginEnvironment(ctx commandContext) (map[string]string, error) {
+	env := make(map[string]string)
+	name := ctx.request.profile
+	var profileOrGroup config.Schedulable
+
+	if ctx.config.HasProfile(name) {
+		profile, cleanup, err := openProfile(ctx.config, name)
+		defer cleanup()
+		if err != nil {
+			return nil, fmt.Errorf("profile '%s': %w", name, err)
+		}
+		if profile.Abstract {
+			return nil, fmt.Errorf("profile '%s' is abstract: it can only be inherited", name)
+		}
+		env = getResticEnvironment(profile)
+		profileOrGroup = profile
+
+	} else if ctx.config.HasProfileGroup(name) {
+		group, err := ctx.config.GetProfileGroup(name)
+		if err != nil {
+			return nil, fmt.Errorf("group '%s': %w", name, err)
+		}
+		profileOrGroup = group
+
+	} else {
+		return nil, fmt.Errorf("profile or group '%s': %w", name, config.ErrNotFound)
+	}
+
+	content, err := json.Marshal(newShowOutput(profileOrGroup))
+	if err != nil {
+		return nil, fmt.Errorf("cannot serialize profile '%s': %w", name, err)
+	}
+	env[constants.EnvPluginProfile] = string(content)
+	env[constants.EnvProfileCommand] = ctx.request.command
+	env[constants.EnvPluginConfig] = ctx.config.GetConfigFile()
+	env[constants.EnvPluginRestic] = ctx.binary
+	if executable, err := util.Executable(); err == nil {
+		env[constants.EnvPluginBinary] = executable
+	}
+	return env, nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/shell"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// preflightCheck is a check running before the restic commands of a profile
+type preflightCheck struct {
+	name  string
+	mode  string
+	check func() error
+}
+
+var errPreflight = errors.New("preflight check failed")
+
+// runPreflight runs the preflight checks of the profile. A failed check in "warn" mode is logged,
+// and the failed checks in "fail" mode are returned as a single error.
+func (r *resticWrapper) runPreflight() error {
+	preflight := r.profile.Preflight
+	if preflight == nil {
+		return nil
+	}
+	checks := []preflightCheck{
+		{"sources", preflight.Sources, r.checkSources},
+		{"free-space", preflight.FreeSpace, func() error { return r.checkFreeSpace(preflight.GetMinFreeSpace()) }},
+		{"mounted", preflight.Mounted, func() error { return checkMountPoints(preflight.MountPoints) }},
+		{"password-files", preflight.PasswordFiles, r.checkPasswordFiles},
+		{"repository", preflight.Repository, r.checkRepository},
+	}
+
+	var failures []error
+	for _, check := range checks {
+		mode := config.PreflightMode(check.mode)
+		if mode == config.PreflightSkip {
+			continue
+		}
+		clog.Debugf("profile '%s': preflight check %s", r.profile.Name, check.name)
+		err := check.check()
+		if err == nil {
+			continue
+		}
+		if mode == config.PreflightWarn {
+			clog.Warningf("profile '%s': preflight check %s: %s", r.profile.Name, check.name, err)
+			continue
+		}
+		clog.Errorf("profile '%s': preflight check %s: %s", r.profile.Name, check.name, err)
+		failures = append(failures, fmt.Errorf("%s: %w", check.name, err))
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%w on profile '%s': %w", errPreflight, r.profile.Name, errors.Join(failures...))
+	}
+	return nil
+}
+
+// runsBackup returns true when the command is a backup, or a composite command with a backup step
+func (r *resticWrapper) runsBackup() bool {
+	if r.command == constants.CommandBackup {
+		return true
+	}
+	if composite, found := r.profile.GetCompositeCommand(r.command); found {
+		return slices.ContainsFunc(composite.Steps, func(step config.CommandStep) bool {
+			return step.Command == constants.CommandBackup
+		})
+	}
+	return false
+}
+
+// getBackupSources returns the backup sources, relative sources being resolved against "source-base"
+func (r *resticWrapper) getBackupSources() []string {
+	base := ""
+	if r.profile.Backup != nil && r.profile.Backup.SourceRelative {
+		base = r.profile.Backup.SourceBase
+	}
+	sources := slices.Clone(r.profile.GetBackupSource())
+	for index, source := range sources {
+		if base != "" && !filepath.IsAbs(source) {
+			sources[index] = filepath.Join(base, source)
+		}
+	}
+	return sources
+}
+
+// checkSources verifies the backup sources exist, and that the source directories are not empty
+func (r *resticWrapper) checkSources() error {
+	if !r.runsBackup() {
+		return nil
+	}
+	for _, source := range r.getBackupSources() {
+		info, err := os.Stat(source)
+		if err != nil {
+			return fmt.Errorf("source %q not found", source)
+		}
+		if info.IsDir() {
+			empty, err := isEmptyDir(source)
+			if err != nil {
+				return fmt.Errorf("cannot read source %q: %w", source, err)
+			}
+			if empty {
+				return fmt.Errorf("source %q is empty", source)
+			}
+		}
+	}
+	return nil
+}
+
+func isEmptyDir(dir string) (bool, error) {
+	file, err := os.Open(dir)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	_, err = file.Readdirnames(1)
+	if err == io.EOF {
+		return true, nil
+	}
+	return false, err
+}
+
+// checkFreeSpace verifies the free space of the restic cache directory and of the temporary directory
+func (r *resticWrapper) checkFreeSpace(minFreeSpace uint64) error {
+	const oneMB = 1048576
+	env := r.profile.GetEnvironment(true)
+
+	cacheDir := r.profile.CacheDir
+	if cacheDir == "" {
+		cacheDir = env.Get("RESTIC_CACHE_DIR")
+	}
+	if cacheDir == "" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			cacheDir = filepath.Join(dir, "restic")
+		}
+	}
+	tempDir := env.Get("TMPDIR")
+	if tempDir == "" {
+		tempDir = os.TempDir()
+	}
+
+	for _, dir := range []string{cacheDir, tempDir} {
+		if dir == "" {
+			continue
+		}
+		usage, err := disk.Usage(existingParent(dir))
+		if err != nil {
+			return fmt.Errorf("cannot get free space of %q: %w", dir, err)
+		}
+		free := usage.Free / oneMB
+		clog.Debugf("free space of %q: %vMB", dir, free)
+		if free < minFreeSpace {
+			return fmt.Errorf("free space of %q is %v MB, less than %v MB", dir, free, minFreeSpace)
+		}
+	}
+	return nil
+}
+
+// existingParent returns the path, or its nearest parent directory that exists
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// checkMountPoints verifies the paths are mount points
+func checkMountPoints(mountPoints []string) error {
+	for _, mountPoint := range mountPoints {
+		mounted, err := isMountPoint(mountPoint)
+		if err != nil {
+			return err
+		}
+		if !mounted {
+			return fmt.Errorf("%q is not mounted", filepath.Clean(mountPoint))
+		}
+	}
+	return nil
+}
+
+// checkPasswordFiles verifies the password files of the profile can be read
+func (r *resticWrapper) checkPasswordFiles() error {
+	files := []string{r.profile.PasswordFile}
+	if r.command == constants.CommandCopy && r.profile.Copy != nil {
+		files = append(files, r.profile.Copy.ToPasswordFile, r.profile.Copy.FromPasswordFile)
+	}
+	for _, filename := range files {
+		if filename == "" {
+			continue
+		}
+		file, err := os.Open(filename)
+		if err != nil {
+			return fmt.Errorf("cannot read password file: %w", err)
+		}
+		info, err := file.Stat()
+		file.Close()
+		if err == nil && info.Size() == 0 {
+			err = fmt.Errorf("password file %q is empty", filename)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRepository verifies the repository is reachable by reading its configuration.
+// The check is skipped when the repository may not exist yet: on "init", or when the profile initializes the repository
+func (r *resticWrapper) checkRepository() error {
+	if r.command == constants.CommandInit || r.global.Initialize || r.profile.Initialize {
+		clog.Debugf("profile '%s': repository is not checked before its initialization", r.profile.Name)
+		return nil
+	}
+	args := r.profile.GetCommandFlags(constants.CommandCat)
+	args.AddArg(shell.NewArg("config", shell.ArgConfigEscape))
+	rCommand := r.prepareCommand(constants.CommandCat, args, false)
+	rCommand.stdout = io.Discard
+	rCommand.stderr = nil
+	_, stderr, err := runShellCommand(rCommand)
+	if err != nil {
+		return newCommandError(rCommand, stderr, fmt.Errorf("repository is not reachable: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/term"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPreflightWrapper(t *testing.T, command string, preflight *config.PreflightSection) (*resticWrapper, *config.Profile) {
+	t.Helper()
+	profile := config.NewProfile(nil, "name")
+	profile.Preflight = preflight
+	wrapper, _ := newTestWrapper(t, profile, command, mockBinary)
+	return wrapper, profile
+}
+
+func TestPreflightSources(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	full := filepath.Join(dir, "full")
+	require.NoError(t, os.Mkdir(empty, 0o700))
+	require.NoError(t, os.Mkdir(full, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(full, "file"), []byte("content"), 0o600))
+
+	testCases := []struct {
+		command string
+		source  []string
+		mode    string
+		err     string
+	}{
+		{command: constants.CommandBackup, source: []string{full}, mode: config.PreflightFail},
+		{command: constants.CommandBackup, source: []string{filepath.Join(full, "file")}, mode: config.PreflightFail},
+		{command: constants.CommandBackup, source: []string{full, empty}, mode: config.PreflightFail, err: "is empty"},
+		{command: constants.CommandBackup, source: []string{filepath.Join(dir, "missing")}, mode: config.PreflightFail, err: "not found"},
+		{command: constants.CommandBackup, source: []string{empty}, mode: config.PreflightWarn},
+		{command: constants.CommandBackup, source: []string{empty}, mode: config.PreflightSkip},
+		{command: constants.CommandBackup, source: []string{empty}, mode: ""},
+		{command: constants.CommandCheck, source: []string{empty}, mode: config.PreflightFail},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.command+"-"+tc.mode+"-"+tc.err, func(t *testing.T) {
+			wrapper, profile := newPreflightWrapper(t, tc.command, &config.PreflightSection{Sources: tc.mode})
+			profile.Backup = &config.BackupSection{Source: tc.source}
+			err := wrapper.runPreflight()
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, errPreflight)
+				assert.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestPreflightSourcesInCompositeCommand(t *testing.T) {
+	t.Parallel()
+
+	wrapper, profile := newPreflightWrapper(t, "nightly", &config.PreflightSection{Sources: config.PreflightFail})
+	profile.Backup = &config.BackupSection{Source: []string{filepath.Join(t.TempDir(), "missing")}}
+	profile.Commands = map[string]*config.CommandSection{
+		"nightly": {Steps: []config.CommandStep{{Command: constants.CommandBackup}, {Command: constants.CommandCheck}}},
+	}
+	assert.ErrorIs(t, wrapper.runPreflight(), errPreflight)
+}
+
+func TestPreflightFreeSpace(t *testing.T) {
+	t.Parallel()
+
+	wrapper, profile := newPreflightWrapper(t, constants.CommandBackup, &config.PreflightSection{FreeSpace: config.PreflightFail, MinFreeSpace: 1})
+	profile.CacheDir = filepath.Join(t.TempDir(), "not", "created", "yet")
+	assert.NoError(t, wrapper.runPreflight())
+
+	profile.Preflight.MinFreeSpace = 1 << 40 // 1 EB
+	err := wrapper.runPreflight()
+	assert.ErrorIs(t, err, errPreflight)
+	assert.ErrorContains(t, err, "free-space")
+}
+
+func TestPreflightMountPoints(t *testing.T) {
+	t.Parallel()
+
+	wrapper, _ := newPreflightWrapper(t, constants.CommandBackup, &config.PreflightSection{
+		Mounted:     config.PreflightFail,
+		MountPoints: []string{t.TempDir()},
+	})
+	err := wrapper.runPreflight()
+	assert.ErrorIs(t, err, errPreflight)
+	assert.ErrorContains(t, err, "is not mounted")
+}
+
+func TestPreflightPasswordFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	emptyFile := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("secret"), 0o600))
+	require.NoError(t, os.WriteFile(emptyFile, []byte{}, 0o600))
+
+	testCases := []struct {
+		passwordFile string
+		err          string
+	}{
+		{passwordFile: ""},
+		{passwordFile: passwordFile},
+		{passwordFile: emptyFile, err: "is empty"},
+		{passwordFile: filepath.Join(dir, "missing"), err: "cannot read password file"},
+	}
+	for _, tc := range testCases {
+		t.Run(filepath.Base(tc.passwordFile), func(t *testing.T) {
+			wrapper, profile := newPreflightWrapper(t, constants.CommandBackup, &config.PreflightSection{PasswordFiles: config.PreflightFail})
+			profile.PasswordFile = tc.passwordFile
+			err := wrapper.runPreflight()
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestPreflightRepository(t *testing.T) {
+	t.Parallel()
+
+	wrapper, _ := newPreflightWrapper(t, constants.CommandBackup, &config.PreflightSection{Repository: config.PreflightFail})
+	assert.NoError(t, wrapper.runPreflight())
+
+	wrapper, _ = newPreflightWrapper(t, constants.CommandBackup, &config.PreflightSection{Repository: config.PreflightFail})
+	wrapper.ctx.binary = "exit" // "exit cat config" fails
+	err := wrapper.runPreflight()
+	assert.ErrorIs(t, err, errPreflight)
+	assert.ErrorContains(t, err, "repository is not reachable")
+
+	// the failed command is available to the hooks
+	ctx := wrapper.getErrorContext(err)
+	assert.Contains(t, ctx.CommandLine, "cat")
+	assert.NotEmpty(t, ctx.ExitCode)
+}
+
+func TestPreflightRepositoryBeforeInitialize(t *testing.T) {
+	t.Parallel()
+
+	// the repository does not exist yet
+	wrapper, _ := newPreflightWrapper(t, constants.CommandInit, &config.PreflightSection{Repository: config.PreflightFail})
+	wrapper.ctx.binary = "exit"
+	assert.NoError(t, wrapper.runPreflight())
+
+	wrapper, profile := newPreflightWrapper(t, constants.CommandBackup, &config.PreflightSection{Repository: config.PreflightFail})
+	wrapper.ctx.binary = "exit"
+	profile.Initialize = true
+	assert.NoError(t, wrapper.runPreflight())
+}
+
+func TestPreflightFailureRunsAfterFail(t *testing.T) {
+	t.Parallel()
+
+	testFile := filepath.Join(t.TempDir(), "TestPreflightFailureRunsAfterFail.txt")
+	profile := config.NewProfile(nil, "name")
+	profile.Preflight = &config.PreflightSection{Sources: config.PreflightFail}
+	profile.Backup = &config.BackupSection{Source: []string{filepath.Join(t.TempDir(), "missing")}}
+	profile.RunAfterFail = []string{"echo failed > " + testFile}
+	ctx := &Context{
+		binary:   "exit",
+		profile:  profile,
+		command:  constants.CommandBackup,
+		terminal: term.NewTerminal(),
+	}
+	wrapper := newResticWrapper(ctx)
+	err := wrapper.runProfile()
+	assert.ErrorIs(t, err, errPreflight)
+	assert.FileExistsf(t, testFile, "the run-after-fail script has not been running")
+}
@@ -65,9 +65,16 @@ func startProfileOrGroup(ctx *Context, runProfile func(ctx *Context) error) erro
 					clog.Warningf("interrupting group '%s' run", groupName)
 					return nil
 				}
-				clog.Debugf("[%d/%d] starting profile '%s' from group '%s'", i+1, len(group.Profiles), profileName, groupName)
 				ctx = ctx.WithProfile(profileName).WithGroup(groupName)
-				err = runProfile(ctx)
+				reason, err := checkActivation(ctx, nil, group.GetMemberActivation(profileName))
+				if err == nil && reason != "" {
+					clog.Infof("[%d/%d] skipping profile '%s' from group '%s': %s", i+1, len(group.Profiles), profileName, groupName, reason)
+					continue
+				}
+				if err == nil {
+					clog.Debugf("[%d/%d] starting profile '%s' from group '%s'", i+1, len(group.Profiles), profileName, groupName)
+					err = runProfile(ctx)
+				}
 				if err != nil {
 					if group.ContinueOnError.IsTrue() || (ctx.global.GroupContinueOnError && group.ContinueOnError.IsUndefined()) {
 						// keep going to the next profile
@@ -137,15 +144,22 @@ func openProfile(c *config.Config, profileName string) (profile *config.Profile,
 }
 
 func runProfile(ctx *Context) error {
-	profile, cleanup, err := openProfile(ctx.config, ctx.request.profile)
-	defer cleanup()
-	if err != nil {
-		return err
+	// the profile is already resolved when running in parallel with other profiles
+	if ctx.profile == nil {
+		profile, cleanup, err := openProfile(ctx.config, ctx.request.profile)
+		defer cleanup()
+		if err != nil {
+			return err
+		}
+		ctx.profile = profile
+		ctx.config.DisplayConfigurationIssues()
+	}
+	profile := ctx.profile
+	if profile.Abstract {
+		return fmt.Errorf("profile '%s' is abstract: it can only be inherited", profile.Name)
 	}
-	ctx.profile = profile
 
 	displayDeprecationNotices(profile)
-	ctx.config.DisplayConfigurationIssues()
 
 	// Send the quiet/verbose down to restic as well (override profile configuration)
 	if ctx.flags.quiet {
@@ -161,13 +175,29 @@ func runProfile(ctx *Context) error {
 		profile.Quiet = false
 	}
 
-	// change log filter according to profile settings
-	if profile.Quiet {
+	// change log filter according to profile settings (the logger is shared by the profiles running in parallel)
+	if profile.Quiet && !ctx.parallel {
 		changeLevelFilter(clog.LevelWarning)
-	} else if profile.Verbose > constants.VerbosityNone && !ctx.flags.veryVerbose {
+	} else if profile.Verbose > constants.VerbosityNone && !ctx.flags.veryVerbose && !ctx.parallel {
 		changeLevelFilter(clog.LevelDebug)
 	}
 
+	// skip the profile, or the section of the command, when the activation conditions are not met
+	for i, section := range profile.GetActivationSections(ctx.command) {
+		reason, err := checkActivation(ctx, profile, section)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			if i == 0 {
+				clog.Infof("skipping profile '%s': %s", profile.Name, reason)
+			} else {
+				clog.Infof("skipping %s on profile '%s': %s", ctx.command, profile.Name, reason)
+			}
+			return nil
+		}
+	}
+
 	// tell the profile what version of restic is in use
 	if e := profile.SetResticVersion(ctx.global.ResticVersion); e != nil {
 		clog.Warningf("restic version %q is no valid semver: %s", ctx.global.ResticVersion, e.Error())
@@ -209,6 +239,40 @@ func runProfile(ctx *Context) error {
 	return nil
 }
 
+// checkActivation evaluates the activation conditions, including the "run-if" shell commands.
+// It returns the reason why a condition is not met, or an empty string when all conditions are met.
+func checkActivation(ctx *Context, profile *config.Profile, sections ...*config.ActivationSection) (string, error) {
+	if reason := config.InactiveReason(sections); reason != "" {
+		return reason, nil
+	}
+	for _, section := range sections {
+		if section == nil || section.RunIf == "" {
+			continue
+		}
+		var env []string
+		if profile != nil {
+			env = profile.GetEnvironment(false).Values()
+		}
+		env = append(env,
+			fmt.Sprintf("%s=%s", constants.EnvProfileName, ctx.request.profile),
+			fmt.Sprintf("%s=%s", constants.EnvProfileCommand, ctx.command),
+		)
+		// the condition is evaluated even in dry-run mode: it must not change anything
+		rCommand := newShellCommand(section.RunIf, nil, env, getShell(ctx.global), false, ctx.sigChan, nil)
+		if ctx.terminal != nil {
+			rCommand.stdout = ctx.terminal.Stdout()
+			rCommand.stderr = ctx.terminal.Stderr()
+		}
+		if _, _, err := runShellCommand(rCommand); err != nil {
+			if exitErr, ok := asExitError(err); ok {
+				return fmt.Sprintf("run-if %q exited with code %d", section.RunIf, exitErr.ExitCode()), nil
+			}
+			return "", fmt.Errorf("cannot evaluate run-if %q: %w", section.RunIf, err)
+		}
+	}
+	return "", nil
+}
+
 func loadScheduledProfile(ctx *Context) {
 	ctx.schedule = ctx.profile.Schedules()[ctx.command]
 }
@@ -3,9 +3,13 @@ package main
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"runtime"
 	"testing"
 
 	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/platform"
+	"github.com/creativeprojects/resticprofile/util/maybe"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -99,3 +103,93 @@ func TestStartProfileOrGroup(t *testing.T) {
 		assert.Equal(t, 1, calls)
 	})
 }
+
+func TestGroupMemberActivation(t *testing.T) {
+	configContent := `version = "2"
+        [profiles.profile1]
+         repository = "test-repo"
+        [profiles.profile2]
+         repository = "test-repo"
+        [profiles.profile3]
+         repository = "test-repo"
+        [groups.group]
+         profiles = ["profile1", "profile2", "profile3"]
+        [groups.group.conditions.profile1]
+         enabled = false
+        [groups.group.conditions.profile3]
+         only-on-os = ["plan9"]
+    `
+	cfg, err := config.Load(bytes.NewBufferString(configContent), config.FormatTOML)
+	require.NoError(t, err)
+
+	ctx := &Context{
+		config:  cfg,
+		global:  &config.Global{},
+		request: Request{profile: "group"},
+	}
+	started := make([]string, 0)
+	err = startProfileOrGroup(ctx, func(ctx *Context) error {
+		started = append(started, ctx.request.profile)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"profile2"}, started)
+}
+
+func TestRunAbstractProfile(t *testing.T) {
+	configContent := `version = "2"
+        [profiles.base]
+         abstract = true
+         repository = "test-repo"
+    `
+	cfg, err := config.Load(bytes.NewBufferString(configContent), config.FormatTOML)
+	require.NoError(t, err)
+
+	ctx := &Context{
+		config:  cfg,
+		global:  &config.Global{},
+		request: Request{profile: "base"},
+	}
+	err = runProfile(ctx)
+	assert.EqualError(t, err, "profile 'base' is abstract: it can only be inherited")
+}
+
+func TestCheckActivation(t *testing.T) {
+	ctx := &Context{
+		global:  &config.Global{},
+		request: Request{profile: "profile"},
+		command: "backup",
+	}
+
+	fixtures := []struct {
+		section *config.ActivationSection
+		reason  string
+	}{
+		{section: nil},
+		{section: &config.ActivationSection{}},
+		{section: &config.ActivationSection{Enabled: maybe.True()}},
+		{section: &config.ActivationSection{Enabled: maybe.False()}, reason: "not enabled"},
+		{section: &config.ActivationSection{OnlyOnOS: []string{runtime.GOOS}}},
+		{section: &config.ActivationSection{OnlyOnOS: []string{"plan9"}}, reason: "is not in only-on-os"},
+		{section: &config.ActivationSection{OnlyOnHosts: []string{"*"}}},
+		{section: &config.ActivationSection{OnlyOnHosts: []string{"not-this-host-" + t.Name()}}, reason: "is not in only-on-hosts"},
+		{section: &config.ActivationSection{RunIf: "exit 0"}},
+		{section: &config.ActivationSection{RunIf: "exit 3"}, reason: `run-if "exit 3" exited with code 3`},
+		{section: &config.ActivationSection{RunIf: `test "$PROFILE_NAME@$PROFILE_COMMAND" = "profile@backup"`}},
+		{section: &config.ActivationSection{Enabled: maybe.False(), RunIf: "exit 0"}, reason: "not enabled"},
+	}
+	for _, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%+v", fixture.section), func(t *testing.T) {
+			if fixture.section != nil && fixture.section.RunIf != "" && platform.IsWindows() {
+				t.Skip("shell commands are for unix shells")
+			}
+			reason, err := checkActivation(ctx, nil, fixture.section)
+			require.NoError(t, err)
+			if fixture.reason == "" {
+				assert.Empty(t, reason)
+			} else {
+				assert.Contains(t, reason, fixture.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/term"
+	"github.com/creativeprojects/resticprofile/util/ansi"
+)
+
+// profileSelection declares the profiles selected with "--all" or "--select label=value" to run a restic command on
+type profileSelection struct {
+	all      bool
+	labels   map[string]string
+	parallel int
+}
+
+// selectionResult is the outcome of running the command on one of the selected profiles
+type selectionResult struct {
+	profile  string
+	duration time.Duration
+	err      error
+}
+
+// parseProfileSelection extracts "--all", "--select label=value" and "--parallel N" from the restic command arguments.
+// It returns nil when no profile selection was requested.
+func parseProfileSelection(args []string) (selection *profileSelection, remaining []string, err error) {
+	remaining = make([]string, 0, len(args))
+	current := &profileSelection{labels: make(map[string]string), parallel: 1}
+	selected := false
+
+	// value returns the value of a flag given as "--flag value" or "--flag=value"
+	value := func(index int, flag string) (string, int, error) {
+		if after, found := strings.CutPrefix(args[index], flag+"="); found {
+			return after, index, nil
+		}
+		if index+1 < len(args) {
+			return args[index+1], index + 1, nil
+		}
+		return "", index, fmt.Errorf("missing value for %s", flag)
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			remaining = append(remaining, args[i:]...)
+			i = len(args)
+
+		case arg == "--all":
+			current.all = true
+			selected = true
+
+		case arg == "--select" || strings.HasPrefix(arg, "--select="):
+			var selector string
+			if selector, i, err = value(i, "--select"); err != nil {
+				return
+			}
+			for _, label := range strings.Split(selector, ",") {
+				name, labelValue, found := strings.Cut(strings.TrimSpace(label), "=")
+				if !found || name == "" {
+					err = fmt.Errorf("invalid selector %q, expected label=value", label)
+					return
+				}
+				current.labels[name] = labelValue
+			}
+			selected = true
+
+		case arg == "--parallel" || strings.HasPrefix(arg, "--parallel="):
+			var parallel string
+			if parallel, i, err = value(i, "--parallel"); err != nil {
+				return
+			}
+			if current.parallel, err = strconv.Atoi(parallel); err != nil || current.parallel < 1 {
+				err = fmt.Errorf("invalid value %q for --parallel, expected a number greater than 0", parallel)
+				return
+			}
+
+		default:
+			remaining = append(remaining, arg)
+		}
+	}
+
+	if selected {
+		selection = current
+	} else if current.parallel > 1 {
+		err = errors.New("--parallel requires --all or --select")
+	}
+	return
+}
+
+// selectProfiles returns the sorted names of the profiles matching the selection (abstract profiles are never selected)
+func selectProfiles(c *config.Config, selection *profileSelection) (names []string) {
+	for _, name := range c.GetProfileNames() {
+		profile, err := c.GetProfile(name)
+		if err != nil {
+			clog.Errorf("cannot load profile '%s': %s", name, err.Error())
+			continue
+		}
+		if profile == nil || profile.Abstract || !profile.MatchLabels(selection.labels) {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return
+}
+
+// runSelectedProfiles runs the restic command on every profile of the selection, then displays a summary
+func runSelectedProfiles(ctx *Context, selection *profileSelection, runProfile func(ctx *Context) error) error {
+	names := selectProfiles(ctx.config, selection)
+	if len(names) == 0 {
+		return fmt.Errorf("%w: no profile matching the selection", ErrProfileNotFound)
+	}
+
+	// Catch CTR-C keypress, or other signal sent by a service manager (systemd)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGABRT)
+	defer signal.Stop(sigChan)
+	ctx.sigChan = sigChan
+
+	goCtx, cancelGoCtx := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGABRT)
+	defer cancelGoCtx()
+
+	notifyStart()
+	defer notifyStop()
+
+	parallel := min(selection.parallel, len(names))
+	if parallel > 1 && slices.ContainsFunc(names, hasBaseDir(ctx.config)) {
+		// the working directory is shared by the whole process
+		clog.Warning("some selected profiles are using \"base-dir\": running profiles one after the other")
+		parallel = 1
+	}
+
+	results := make([]selectionResult, len(names))
+	if parallel > 1 {
+		runSelectedProfilesInParallel(goCtx, ctx, names, parallel, results, runProfile)
+	} else {
+		for i, name := range names {
+			if goCtx.Err() != nil {
+				clog.Warning("interrupting the selected profiles run")
+				results = results[:i]
+				break
+			}
+			_, _ = fmt.Fprintf(ctx.terminal, "\n%s\n\n", ansi.Bold(fmt.Sprintf("[%d/%d] profile '%s'", i+1, len(names), name)))
+			results[i] = runSelectedProfile(ctx.WithProfile(name), runProfile)
+		}
+	}
+
+	return displaySelectionSummary(ctx.terminal, results)
+}
+
+// runSelectedProfilesInParallel runs up to parallel profiles at the same time.
+// The output of each profile is buffered and displayed under the profile header once it finished.
+func runSelectedProfilesInParallel(goCtx context.Context, ctx *Context, names []string, parallel int, results []selectionResult, runProfile func(ctx *Context) error) {
+	var (
+		wg        sync.WaitGroup
+		outputMux sync.Mutex
+		slots     = make(chan struct{}, parallel)
+	)
+	profiles := resolveSelectedProfiles(ctx.config, names, results)
+	for i, name := range names {
+		if profiles[i] == nil {
+			// the profile cannot be loaded: the error is already in the results
+			continue
+		}
+		slots <- struct{}{}
+		if goCtx.Err() != nil {
+			clog.Warning("interrupting the selected profiles run")
+			results[i] = selectionResult{profile: name, err: errors.New("interrupted")}
+			<-slots
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			output := &bytes.Buffer{}
+			terminal := term.NewTerminal(term.WithStdout(output), term.WithStderr(output), term.WithNoStdin())
+			profileCtx := ctx.WithProfile(name).WithTerminal(terminal)
+			profileCtx.profile = profiles[i]
+			profileCtx.parallel = true
+			results[i] = runSelectedProfile(profileCtx, runProfile)
+
+			outputMux.Lock()
+			defer outputMux.Unlock()
+			_, _ = fmt.Fprintf(ctx.terminal, "\n%s\n\n", ansi.Bold(fmt.Sprintf("[%d/%d] profile '%s'", i+1, len(names), name)))
+			_, _ = ctx.terminal.Write(output.Bytes())
+		}()
+	}
+	wg.Wait()
+}
+
+// resolveSelectedProfiles loads the profiles one after the other: loading a profile changes the state of the configuration,
+// which cannot be shared by the profiles running in parallel. A profile failing to load is reported in the results.
+func resolveSelectedProfiles(c *config.Config, names []string, results []selectionResult) []*config.Profile {
+	profiles := make([]*config.Profile, len(names))
+	for i, name := range names {
+		profile, cleanup, err := openProfile(c, name)
+		cleanup()
+		if err != nil {
+			clog.Errorf("profile '%s': %s", name, err.Error())
+			results[i] = selectionResult{profile: name, err: err}
+			continue
+		}
+		c.DisplayConfigurationIssues()
+		profiles[i] = profile
+	}
+	return profiles
+}
+
+func runSelectedProfile(ctx *Context, runProfile func(ctx *Context) error) selectionResult {
+	start := time.Now()
+	err := runProfile(ctx)
+	if err != nil {
+		clog.Errorf("profile '%s': %s", ctx.request.profile, err.Error())
+	}
+	return selectionResult{profile: ctx.request.profile, duration: time.Since(start), err: err}
+}
+
+// displaySelectionSummary displays the outcome of every profile and returns an error when at least one failed
+func displaySelectionSummary(terminal *term.Terminal, results []selectionResult) error {
+	out, closer := displayWriter(terminal)
+
+	failed := 0
+	out("\n%s (profile, result, duration):\n", ansi.Bold("Summary"))
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+			out("\t%s:\t%s\t%s\t%s\n", result.profile, ansi.Red("failed"), result.duration.Round(100*time.Millisecond), result.err.Error())
+		} else {
+			out("\t%s:\t%s\t%s\n", result.profile, ansi.Green("success"), result.duration.Round(100*time.Millisecond))
+		}
+	}
+	out("\n")
+	closer()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d profiles failed", failed, len(results))
+	}
+	return nil
+}
+
+// hasBaseDir returns a func checking whether a profile is changing the working directory
+func hasBaseDir(c *config.Config) func(string) bool {
+	return func(name string) bool {
+		profile, err := c.GetProfile(name)
+		return err == nil && profile != nil && profile.BaseDir != ""
+	}
+}
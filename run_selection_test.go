@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/term"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const selectionTestConfig = `
+version: "1"
+base:
+  abstract: true
+  labels:
+    env: prod
+one:
+  inherit: base
+two:
+  inherit: base
+  labels:
+    site: paris
+three:
+  labels:
+    env: dev
+four:
+  description: no label
+`
+
+func TestParseProfileSelection(t *testing.T) {
+	testCases := []struct {
+		args      []string
+		selection *profileSelection
+		remaining []string
+		err       string
+	}{
+		{
+			args:      []string{"--latest", "1"},
+			remaining: []string{"--latest", "1"},
+		},
+		{
+			args:      []string{"--all", "--latest", "1"},
+			selection: &profileSelection{all: true, labels: map[string]string{}, parallel: 1},
+			remaining: []string{"--latest", "1"},
+		},
+		{
+			args:      []string{"--select", "env=prod", "--parallel", "3", "--json"},
+			selection: &profileSelection{labels: map[string]string{"env": "prod"}, parallel: 3},
+			remaining: []string{"--json"},
+		},
+		{
+			args:      []string{"--select=env=prod,site=paris", "--select", "tier=", "--parallel=2"},
+			selection: &profileSelection{labels: map[string]string{"env": "prod", "site": "paris", "tier": ""}, parallel: 2},
+			remaining: []string{},
+		},
+		{
+			args:      []string{"--all", "--", "--select", "x=y"},
+			selection: &profileSelection{all: true, labels: map[string]string{}, parallel: 1},
+			remaining: []string{"--", "--select", "x=y"},
+		},
+		{args: []string{"--select"}, err: "missing value for --select"},
+		{args: []string{"--select", "env"}, err: `invalid selector "env"`},
+		{args: []string{"--all", "--parallel", "0"}, err: `invalid value "0" for --parallel`},
+		{args: []string{"--all", "--parallel", "many"}, err: `invalid value "many" for --parallel`},
+		{args: []string{"--parallel", "2"}, err: "--parallel requires --all or --select"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(strings.Join(testCase.args, " "), func(t *testing.T) {
+			selection, remaining, err := parseProfileSelection(testCase.args)
+			if testCase.err != "" {
+				assert.ErrorContains(t, err, testCase.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.selection, selection)
+			assert.Equal(t, testCase.remaining, remaining)
+		})
+	}
+}
+
+func TestSelectProfilesByLabels(t *testing.T) {
+	cfg, err := config.Load(strings.NewReader(selectionTestConfig), "yaml")
+	require.NoError(t, err)
+
+	selection := func(labels map[string]string) *profileSelection {
+		return &profileSelection{labels: labels, parallel: 1}
+	}
+	assert.Equal(t, []string{"four", "one", "three", "two"}, selectProfiles(cfg, selection(nil)))
+	assert.Equal(t, []string{"one", "two"}, selectProfiles(cfg, selection(map[string]string{"env": "prod"})))
+	assert.Equal(t, []string{"two"}, selectProfiles(cfg, selection(map[string]string{"ENV": "prod", "site": "paris"})))
+	assert.Empty(t, selectProfiles(cfg, selection(map[string]string{"env": "Prod"})))
+}
+
+func runSelectionTest(t *testing.T, parallel int, runProfile func(ctx *Context) error) (string, error) {
+	t.Helper()
+	cfg, err := config.Load(strings.NewReader(selectionTestConfig), "yaml")
+	require.NoError(t, err)
+
+	output := &bytes.Buffer{}
+	ctx := &Context{
+		config:   cfg,
+		terminal: term.NewTerminal(term.WithStdout(output), term.WithColors(false)),
+	}
+	selection := &profileSelection{labels: map[string]string{"env": "prod"}, parallel: parallel}
+	err = runSelectedProfiles(ctx, selection, runProfile)
+	return output.String(), err
+}
+
+func TestRunSelectedProfiles(t *testing.T) {
+	for _, parallel := range []int{1, 2} {
+		t.Run("", func(t *testing.T) {
+			mutex := sync.Mutex{}
+			profiles := make([]string, 0, 2)
+			output, err := runSelectionTest(t, parallel, func(ctx *Context) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				profiles = append(profiles, ctx.request.profile)
+				_, _ = ctx.terminal.Println("output of " + ctx.request.profile)
+				if ctx.request.profile == "two" {
+					return errors.New("failure")
+				}
+				return nil
+			})
+
+			assert.EqualError(t, err, "1 of 2 profiles failed")
+			assert.ElementsMatch(t, []string{"one", "two"}, profiles)
+			assert.Contains(t, output, "[1/2] profile 'one'\n\noutput of one\n")
+			assert.Contains(t, output, "[2/2] profile 'two'\n\noutput of two\n")
+			assert.Regexp(t, `(?m)^\s+one:\s+success\s+\S+\s*$`, output)
+			assert.Regexp(t, `(?m)^\s+two:\s+failed\s+\S+\s+failure\s*$`, output)
+		})
+	}
+}
+
+func TestRunSelectedProfilesInParallel(t *testing.T) {
+	running, maxRunning := int32(0), int32(0)
+	_, err := runSelectionTest(t, 2, func(ctx *Context) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			previous := atomic.LoadInt32(&maxRunning)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestRunSelectedProfilesNoMatch(t *testing.T) {
+	cfg, err := config.Load(strings.NewReader(selectionTestConfig), "yaml")
+	require.NoError(t, err)
+
+	ctx := &Context{config: cfg, terminal: term.NewTerminal(term.WithStdout(&bytes.Buffer{}))}
+	err = runSelectedProfiles(ctx, &profileSelection{labels: map[string]string{"env": "none"}, parallel: 1}, func(*Context) error {
+		t.Fatal("no profile should run")
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+}
+
+// run with "go test -race" to check the profiles running in parallel are not sharing the configuration state
+func TestRunSelectedProfilesInParallelWithConfig(t *testing.T) {
+	cfg, err := config.Load(strings.NewReader(`
+version: "1"
+base:
+  abstract: true
+  repository: "local:/backup/{{ .Profile.Name }}"
+  password-file: key
+  labels:
+    env: prod
+one:
+  inherit: base
+two:
+  inherit: base
+three:
+  inherit: base
+four:
+  inherit: base
+`), "yaml")
+	require.NoError(t, err)
+
+	output := &bytes.Buffer{}
+	ctx := &Context{
+		config:   cfg,
+		global:   config.NewGlobal(),
+		binary:   mockBinary,
+		command:  "snapshots",
+		request:  Request{arguments: []string{"--args"}},
+		noLock:   true,
+		terminal: term.NewTerminal(term.WithStdout(output), term.WithColors(false)),
+	}
+	selection := &profileSelection{labels: map[string]string{"env": "prod"}, parallel: 4}
+	err = runSelectedProfiles(ctx, selection, runProfile)
+	require.NoError(t, err)
+
+	for _, name := range []string{"one", "two", "three", "four"} {
+		assert.Regexp(t, `profile '`+name+`'\n\nargs: .*"--repo=local:/backup/`+name+`"`, output.String())
+	}
+}
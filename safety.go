@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/shell"
+)
+
+// retentionSafetyError is returned when the retention would remove more snapshots than allowed by the retention safety
+type retentionSafetyError struct {
+	remove  int
+	total   int
+	reasons []string
+}
+
+func (e *retentionSafetyError) Error() string {
+	return fmt.Sprintf("retention safety: refusing to remove %d of %d snapshots: %s (use --override-safety to apply the retention anyway)",
+		e.remove, e.total, strings.Join(e.reasons, ", "))
+}
+
+// asRetentionSafetyError returns the retentionSafetyError in the chain of err
+func asRetentionSafetyError(err error) (*retentionSafetyError, bool) {
+	safetyErr := new(retentionSafetyError)
+	if errors.As(err, &safetyErr) {
+		return safetyErr, true
+	}
+	return nil, false
+}
+
+// forgetGroup is a group of snapshots in the JSON output of "restic forget"
+type forgetGroup struct {
+	Host   string           `json:"host"`
+	Tags   []string         `json:"tags"`
+	Paths  []string         `json:"paths"`
+	Keep   []forgetSnapshot `json:"keep"`
+	Remove []forgetSnapshot `json:"remove"`
+}
+
+func (g forgetGroup) String() string {
+	var group []string
+	if g.Host != "" {
+		group = append(group, "host "+g.Host)
+	}
+	if len(g.Paths) > 0 {
+		group = append(group, "paths "+strings.Join(g.Paths, ","))
+	}
+	if len(g.Tags) > 0 {
+		group = append(group, "tags "+strings.Join(g.Tags, ","))
+	}
+	if len(group) == 0 {
+		return "all snapshots"
+	}
+	return strings.Join(group, " ")
+}
+
+type forgetSnapshot struct {
+	Time    time.Time `json:"time"`
+	ShortID string    `json:"short_id"`
+}
+
+// checkRetentionSafety lists the snapshots the retention would remove (with "forget --dry-run --json"),
+// and returns a retentionSafetyError when it would remove more snapshots than allowed
+func (r *resticWrapper) checkRetentionSafety(args *shell.Args) error {
+	safety := r.profile.Retention.GetSafety()
+	if safety == nil || r.dryRun {
+		return nil
+	}
+	if r.ctx.flags.overrideSafety {
+		clog.Warningf("profile '%s': retention safety is overridden", r.profile.Name)
+		return nil
+	}
+
+	args = args.Clone()
+	args.Remove("prune")
+	args.AddFlags("dry-run", []shell.Arg{})
+	args.AddFlags("json", []shell.Arg{})
+	rCommand := r.prepareCommand(constants.CommandForget, args, false)
+	output := &bytes.Buffer{}
+	rCommand.stdout = output
+	_, stderr, err := runShellCommand(rCommand)
+	if err != nil {
+		return newCommandError(rCommand, stderr, fmt.Errorf("retention safety on profile '%s': %w", r.profile.Name, err))
+	}
+
+	var groups []forgetGroup
+	if err = json.NewDecoder(output).Decode(&groups); err != nil {
+		return fmt.Errorf("retention safety on profile '%s': cannot read the snapshots to remove: %w", r.profile.Name, err)
+	}
+	if err = evaluateRetentionSafety(safety, groups, time.Now()); err != nil {
+		return err
+	}
+	clog.Debugf("profile '%s': retention safety passed", r.profile.Name)
+	return nil
+}
+
+// evaluateRetentionSafety returns a retentionSafetyError when the groups of snapshots cross a threshold of the retention safety
+func evaluateRetentionSafety(safety *config.RetentionSafetySection, groups []forgetGroup, now time.Time) error {
+	remove, total := 0, 0
+	var reasons []string
+	var newest time.Time
+	for _, group := range groups {
+		remove += len(group.Remove)
+		total += len(group.Remove) + len(group.Keep)
+		for _, snapshot := range group.Keep {
+			if snapshot.Time.After(newest) {
+				newest = snapshot.Time
+			}
+		}
+		if safety.MaxRemovePerGroup > 0 && len(group.Remove) > safety.MaxRemovePerGroup {
+			reasons = append(reasons, fmt.Sprintf("%d snapshots would be removed from %s (max-remove-per-group is %d)",
+				len(group.Remove), group, safety.MaxRemovePerGroup))
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	if safety.MaxRemovePercent > 0 && remove*100 > safety.MaxRemovePercent*total {
+		reasons = append(reasons, fmt.Sprintf("%d%% of the snapshots would be removed (max-remove-percent is %d%%)",
+			remove*100/total, safety.MaxRemovePercent))
+	}
+	if safety.KeepNewerThan > 0 && newest.Before(now.Add(-safety.KeepNewerThan)) {
+		reasons = append(reasons, fmt.Sprintf("no snapshot newer than %s would be kept", safety.KeepNewerThan))
+	}
+	if len(reasons) > 0 {
+		return &retentionSafetyError{remove: remove, total: total, reasons: reasons}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/term"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func forgetSnapshots(now time.Time, ages ...time.Duration) (snapshots []forgetSnapshot) {
+	for index, age := range ages {
+		snapshots = append(snapshots, forgetSnapshot{Time: now.Add(-age), ShortID: fmt.Sprintf("%08x", index)})
+	}
+	return
+}
+
+func TestEvaluateRetentionSafety(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	day := 24 * time.Hour
+	groups := []forgetGroup{
+		{
+			Host:   "host",
+			Paths:  []string{"/home"},
+			Keep:   forgetSnapshots(now, 2*day, 3*day),
+			Remove: forgetSnapshots(now, 4*day, 5*day, 6*day),
+		},
+		{
+			Host:   "host",
+			Paths:  []string{"/etc"},
+			Keep:   forgetSnapshots(now, day),
+			Remove: forgetSnapshots(now, 7*day),
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		safety config.RetentionSafetySection
+		groups []forgetGroup
+		reason string
+	}{
+		{
+			name:   "max percent not reached",
+			safety: config.RetentionSafetySection{MaxRemovePercent: 60},
+			groups: groups,
+		},
+		{
+			name:   "max percent",
+			safety: config.RetentionSafetySection{MaxRemovePercent: 50},
+			groups: groups,
+			reason: "57% of the snapshots would be removed (max-remove-percent is 50%)",
+		},
+		{
+			name:   "max per group not reached",
+			safety: config.RetentionSafetySection{MaxRemovePerGroup: 3},
+			groups: groups,
+		},
+		{
+			name:   "max per group",
+			safety: config.RetentionSafetySection{MaxRemovePerGroup: 2},
+			groups: groups,
+			reason: "3 snapshots would be removed from host host paths /home (max-remove-per-group is 2)",
+		},
+		{
+			name:   "newer snapshot kept",
+			safety: config.RetentionSafetySection{KeepNewerThan: 36 * time.Hour},
+			groups: groups,
+		},
+		{
+			name:   "no newer snapshot kept",
+			safety: config.RetentionSafetySection{KeepNewerThan: 12 * time.Hour},
+			groups: groups,
+			reason: "no snapshot newer than 12h0m0s would be kept",
+		},
+		{
+			name:   "no snapshot",
+			safety: config.RetentionSafetySection{MaxRemovePercent: 1, MaxRemovePerGroup: 1, KeepNewerThan: time.Hour},
+			groups: nil,
+		},
+		{
+			name:   "everything removed",
+			safety: config.RetentionSafetySection{KeepNewerThan: time.Hour},
+			groups: []forgetGroup{{Remove: forgetSnapshots(now, time.Minute)}},
+			reason: "no snapshot newer than 1h0m0s would be kept",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := evaluateRetentionSafety(&tc.safety, tc.groups, now)
+			if tc.reason == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.reason)
+			safetyErr, ok := asRetentionSafetyError(err)
+			require.True(t, ok)
+			assert.Equal(t, []string{tc.reason}, safetyErr.reasons)
+		})
+	}
+}
+
+func TestRetentionSafety(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	output := fmt.Sprintf(`[{"host":"host","paths":["/home"],"tags":null,"keep":[{"time":%q,"short_id":"11111111"}],"remove":[{"time":%q,"short_id":"22222222"},{"time":%q,"short_id":"33333333"}]}]`,
+		now.Add(-time.Hour).Format(time.RFC3339), now.Add(-2*time.Hour).Format(time.RFC3339), now.Add(-3*time.Hour).Format(time.RFC3339))
+	outputFile := filepath.Join(t.TempDir(), "forget.json")
+	require.NoError(t, os.WriteFile(outputFile, []byte(output), 0o600))
+
+	testCases := []struct {
+		name     string
+		safety   *config.RetentionSafetySection
+		override bool
+		dryRun   bool
+		fail     bool
+	}{
+		{name: "no safety"},
+		{name: "passed", safety: &config.RetentionSafetySection{MaxRemovePercent: 70}},
+		{name: "refused", safety: &config.RetentionSafetySection{MaxRemovePercent: 50}, fail: true},
+		{name: "override", safety: &config.RetentionSafetySection{MaxRemovePercent: 50}, override: true},
+		{name: "dry-run", safety: &config.RetentionSafetySection{MaxRemovePercent: 50}, dryRun: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			profile := config.NewProfile(nil, "name")
+			profile.Retention = &config.RetentionSection{Safety: tc.safety}
+			ctx := &Context{
+				binary:   mockBinary,
+				profile:  profile,
+				command:  "backup",
+				request:  Request{arguments: []string{"--stdout", "@" + outputFile}},
+				terminal: term.NewTerminal(),
+				flags:    commandLineFlags{overrideSafety: tc.override, dryRun: tc.dryRun},
+			}
+			wrapper := newResticWrapper(ctx)
+			err := wrapper.runRetention()
+			if !tc.fail {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			safetyErr, ok := asRetentionSafetyError(err)
+			require.True(t, ok)
+			assert.Equal(t, 2, safetyErr.remove)
+			assert.Equal(t, 3, safetyErr.total)
+
+			assert.Subset(t, wrapper.getFailEnvironment(err), []string{"RETENTION_REMOVE=2", "RETENTION_TOTAL=3"})
+			hookCtx := wrapper.getContextWithError(err)
+			assert.Equal(t, 2, hookCtx.Retention.Remove)
+			assert.Equal(t, 3, hookCtx.Retention.Total)
+		})
+	}
+}
+
+func TestRetentionSafetyInvalidOutput(t *testing.T) {
+	t.Parallel()
+
+	profile := config.NewProfile(nil, "name")
+	profile.Retention = &config.RetentionSection{Safety: &config.RetentionSafetySection{MaxRemovePercent: 50}}
+	ctx := &Context{
+		binary:   mockBinary,
+		profile:  profile,
+		command:  "backup",
+		request:  Request{arguments: []string{"--stdout", "not json"}},
+		terminal: term.NewTerminal(),
+	}
+	wrapper := newResticWrapper(ctx)
+	err := wrapper.runRetention()
+	assert.ErrorContains(t, err, "cannot read the snapshots to remove")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/shell"
+)
+
+// firstSecretFileDescriptor is the file descriptor of the first pipe inherited by restic (after stdin, stdout & stderr)
+const firstSecretFileDescriptor = 3
+
+// secretFlag declares a restic flag that can be passed through a pipe
+type secretFlag struct {
+	flag, fileFlag string   // flag with the value and flag reading the value from a file
+	env            string   // environment variable that can hold the value instead of the flag
+	overriddenBy   []string // flags taking precedence over the environment variable
+	fromRepository bool     // flag only available in commands reading from another repository
+}
+
+var secretFlags = []secretFlag{
+	{
+		flag:         constants.ParameterRepository,
+		fileFlag:     constants.ParameterRepositoryFile,
+		env:          "RESTIC_REPOSITORY",
+		overriddenBy: []string{constants.ParameterRepository, constants.ParameterRepositoryFile},
+	},
+	{
+		fileFlag:     constants.ParameterPasswordFile,
+		env:          "RESTIC_PASSWORD",
+		overriddenBy: []string{constants.ParameterPasswordFile, constants.ParameterPasswordCommand},
+	},
+	{
+		flag:           "from-repo",
+		fileFlag:       "from-repository-file",
+		env:            "RESTIC_FROM_REPOSITORY",
+		overriddenBy:   []string{"from-repo", "from-repository-file", "repo2", "repository-file2"},
+		fromRepository: true,
+	},
+	{
+		fileFlag:       "from-password-file",
+		env:            "RESTIC_FROM_PASSWORD",
+		overriddenBy:   []string{"from-password-file", "from-password-command", "password-file2", "password-command2"},
+		fromRepository: true,
+	},
+	{
+		// legacy flag of restic < 0.14
+		flag:           "repo2",
+		fileFlag:       "repository-file2",
+		fromRepository: true,
+	},
+}
+
+// handOffSecrets moves the confidential repositories and the passwords from the arguments and the environment
+// of a restic command to flags reading from inherited pipes (/dev/fd/3, /dev/fd/4, ...).
+// It returns the values to send through the pipes, in the order of the file descriptors.
+func handOffSecrets(command string, args *shell.Args, env []string) (secrets []string, newEnv []string) {
+	newEnv = env
+	readsFromRepository := command == constants.CommandCopy || command == constants.CommandInit
+
+	addSecret := func(fileFlag, value string) {
+		path := fmt.Sprintf("/dev/fd/%d", firstSecretFileDescriptor+len(secrets))
+		secrets = append(secrets, value)
+		args.AddFlag(fileFlag, shell.NewArg(path, shell.ArgConfigEscape))
+		clog.Debugf("passing --%s through an inherited pipe (%s)", fileFlag, path)
+	}
+
+	for _, secret := range secretFlags {
+		if secret.fromRepository && !readsFromRepository {
+			continue
+		}
+		// confidential value in flag
+		if values, found := args.Get(secret.flag); found && len(values) == 1 &&
+			values[0].GetConfidentialValue() != values[0].Value() {
+			args.Remove(secret.flag)
+			addSecret(secret.fileFlag, values[0].Value())
+			continue
+		}
+		// value in environment
+		if secret.env == "" || slices.ContainsFunc(secret.overriddenBy, hasFlag(args)) {
+			continue
+		}
+		if value := getEnvironmentValue(newEnv, secret.env); value != "" {
+			// the empty variable overrides any value inherited from the OS environment
+			newEnv = append(slices.DeleteFunc(slices.Clone(newEnv), isEnvironmentVariable(secret.env)), secret.env+"=")
+			addSecret(secret.fileFlag, value)
+		}
+	}
+	return
+}
+
+// hasFlag returns a func checking whether a flag is set in args
+func hasFlag(args *shell.Args) func(string) bool {
+	return func(flag string) bool {
+		_, found := args.Get(flag)
+		return found
+	}
+}
+
+// isEnvironmentVariable returns a func matching a "NAME=value" definition of the variable name
+func isEnvironmentVariable(name string) func(string) bool {
+	return func(definition string) bool {
+		return strings.HasPrefix(definition, name+"=")
+	}
+}
+
+// getEnvironmentValue returns the value of the last definition of the variable name in env
+func getEnvironmentValue(env []string, name string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if isEnvironmentVariable(name)(env[i]) {
+			return strings.TrimPrefix(env[i], name+"=")
+		}
+	}
+	return ""
+}
+
+// openSecretPipes creates one pipe per secret and returns the read ends to pass to the child process.
+// The secrets are written in the background as a pipe may not buffer the complete value.
+// closePipes must be called when the child process ended.
+func openSecretPipes(secrets []string) (files []*os.File, closePipes func(), err error) {
+	closePipes = func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}
+	for _, secret := range secrets {
+		var reader, writer *os.File
+		if reader, writer, err = os.Pipe(); err != nil {
+			closePipes()
+			return nil, nil, fmt.Errorf("cannot create pipe for secret: %w", err)
+		}
+		files = append(files, reader)
+		go func(value string) {
+			defer writer.Close()
+			// fails with a broken pipe when the child process exited without reading the value
+			if _, e := writer.WriteString(value); e != nil {
+				clog.Tracef("secret not sent through pipe: %s", e.Error())
+			}
+		}(secret)
+	}
+	return
+}
@@ -1,19 +1,25 @@
 package main
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"filippo.io/age"
 	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/crypt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -28,6 +34,40 @@ func TestSendRemoteFiles(t *testing.T) {
 	assert.Equal(t, recorder.Header().Get("Content-Type"), "application/x-tar")
 }
 
+func TestSendRemoteEncryptedFiles(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	encrypted, err := crypt.Encrypt([]byte("version: 2\n"), "yaml", crypt.KindAge, []age.Recipient{identity.Recipient()})
+	require.NoError(t, err)
+	configFile := filepath.Join(t.TempDir(), "profiles.yaml.age")
+	require.NoError(t, os.WriteFile(configFile, encrypted, 0o600))
+
+	recorder := httptest.NewRecorder()
+	sendRemoteFiles(&config.Remote{
+		ConfigurationFile: configFile,
+		ProfileName:       "test_profile",
+	}, "test_remote", nil, recorder)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// encrypted files are forwarded unchanged: the remote client decrypts them with its own key
+	reader := tar.NewReader(recorder.Body)
+	found := false
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		if header.Name == "profiles.yaml.age" {
+			content, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, encrypted, content)
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
 func TestSendRemoteFilesNotFound(t *testing.T) {
 	recorder := httptest.NewRecorder()
 	sendRemoteFiles(&config.Remote{
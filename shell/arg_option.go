@@ -18,7 +18,7 @@ type ConfidentialArgOption struct {
 }
 
 var confidentialFilterFunc filterFunc = func(value string) string {
-	return mask.Submatches(mask.RepositoryConfidentialPart, value)
+	return mask.URL(value)
 }
 
 func NewConfidentialArgOption(confidential bool) *ConfidentialArgOption {
@@ -124,7 +124,7 @@ func (a *Args) Rename(oldName, newName string) bool {
 	args, ok := a.Remove(oldName)
 	if ok {
 		for _, arg := range args {
-			a.AddFlag(newName, NewArg(arg.Value(), arg.Type()))
+			a.AddFlag(newName, arg.Clone())
 		}
 	}
 	args = a.RemoveArg(oldName)
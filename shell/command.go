@@ -44,6 +44,7 @@ type Command struct {
 	Stdin      io.Reader
 	Stdout     io.Writer
 	Stderr     io.Writer
+	ExtraFiles []*os.File // additional open files inherited by the command (file descriptor 3, 4, ...)
 	SetPID     SetPID
 	ScanStdout ScanOutput
 	sigChan    chan os.Signal
@@ -116,6 +117,7 @@ func (c *Command) Run() (monitor.Summary, string, error) {
 	}
 
 	cmd.Dir = c.Dir
+	cmd.ExtraFiles = c.ExtraFiles
 
 	start := time.Now()
 
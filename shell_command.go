@@ -27,6 +27,7 @@ type shellCommandDefinition struct {
 	setPID      shell.SetPID
 	scanOutput  shell.ScanOutput
 	streamError []config.StreamErrorSection
+	secrets     []string // confidential values passed through inherited pipes (see handOffSecrets)
 }
 
 // newShellCommand creates a new shell command definition
@@ -100,6 +101,15 @@ func runShellCommand(command shellCommandDefinition) (summary monitor.Summary, s
 		return
 	}
 
+	// secrets
+	if len(command.secrets) > 0 {
+		var closePipes func()
+		if shellCmd.ExtraFiles, closePipes, err = openSecretPipes(command.secrets); err != nil {
+			return
+		}
+		defer closePipes()
+	}
+
 	summary, stderr, err = shellCmd.Run()
 	return
 }
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/shell"
+)
+
+// commandRunner runs the commands managing the filesystem snapshots
+type commandRunner interface {
+	Run(command string, args ...string) error
+}
+
+// sourceSnapshotProvider creates and removes a read-only snapshot of a volume
+type sourceSnapshotProvider interface {
+	// Create creates a read-only snapshot of the volume, and makes it available in the target directory
+	Create(target string) error
+	// Remove removes the snapshot from the target directory, and deletes it
+	Remove(target string) error
+}
+
+// newSourceSnapshotProvider returns the provider of the type of snapshot
+func newSourceSnapshotProvider(section *config.SourceSnapshotSection, profileName string, runner commandRunner) (sourceSnapshotProvider, error) {
+	if section.Volume == "" {
+		return nil, errors.New("missing volume")
+	}
+	switch strings.ToLower(section.Type) {
+	case config.SourceSnapshotBtrfs:
+		return &btrfsSnapshot{volume: section.Volume, runner: runner}, nil
+	case config.SourceSnapshotLVM:
+		return &lvmSnapshot{
+			volume:  section.Volume,
+			name:    section.GetName(profileName),
+			size:    section.GetSize(),
+			options: section.GetMountOptions(),
+			runner:  runner,
+		}, nil
+	case config.SourceSnapshotZFS:
+		return &zfsSnapshot{dataset: section.Volume, name: section.GetName(profileName), runner: runner}, nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", section.Type)
+	}
+}
+
+// btrfsSnapshot is a read-only snapshot of a btrfs subvolume, created in the target directory
+type btrfsSnapshot struct {
+	volume string
+	runner commandRunner
+}
+
+func (s *btrfsSnapshot) Create(target string) error {
+	return s.runner.Run("btrfs", "subvolume", "snapshot", "-r", s.volume, target)
+}
+
+func (s *btrfsSnapshot) Remove(target string) error {
+	return s.runner.Run("btrfs", "subvolume", "delete", target)
+}
+
+// lvmSnapshot is a snapshot of an LVM logical volume, mounted read-only in the target directory
+type lvmSnapshot struct {
+	volume  string
+	name    string
+	size    string
+	options string
+	runner  commandRunner
+}
+
+// snapshot returns the snapshot volume, in the volume group of the logical volume
+func (s *lvmSnapshot) snapshot() string {
+	group, _, _ := strings.Cut(s.volume, "/")
+	return group + "/" + s.name
+}
+
+func (s *lvmSnapshot) Create(target string) error {
+	err := s.runner.Run("lvcreate", "--snapshot", "--permission", "r", "--size", s.size, "--name", s.name, s.volume)
+	if err != nil {
+		return err
+	}
+	if err = mkdirAndRun(target, s.runner, "mount", "-o", s.options, "/dev/"+s.snapshot(), target); err != nil {
+		return errors.Join(err, s.runner.Run("lvremove", "-f", s.snapshot()))
+	}
+	return nil
+}
+
+func (s *lvmSnapshot) Remove(target string) error {
+	if err := s.runner.Run("umount", target); err != nil {
+		return err
+	}
+	return s.runner.Run("lvremove", "-f", s.snapshot())
+}
+
+// zfsSnapshot is a snapshot of a ZFS dataset, mounted in the target directory
+type zfsSnapshot struct {
+	dataset string
+	name    string
+	runner  commandRunner
+}
+
+func (s *zfsSnapshot) snapshot() string {
+	return s.dataset + "@" + s.name
+}
+
+func (s *zfsSnapshot) Create(target string) error {
+	err := s.runner.Run("zfs", "snapshot", s.snapshot())
+	if err != nil {
+		return err
+	}
+	if err = mkdirAndRun(target, s.runner, "mount", "-t", "zfs", s.snapshot(), target); err != nil {
+		return errors.Join(err, s.runner.Run("zfs", "destroy", s.snapshot()))
+	}
+	return nil
+}
+
+func (s *zfsSnapshot) Remove(target string) error {
+	if err := s.runner.Run("umount", target); err != nil {
+		return err
+	}
+	return s.runner.Run("zfs", "destroy", s.snapshot())
+}
+
+// mkdirAndRun creates the directory before running the command
+func mkdirAndRun(dir string, runner commandRunner, command string, args ...string) error {
+	if err := os.Mkdir(dir, 0o700); err != nil && !os.IsExist(err) {
+		return err
+	}
+	return runner.Run(command, args...)
+}
+
+// shellCommandRunner runs the commands in the shell of the profile
+type shellCommandRunner struct {
+	wrapper *resticWrapper
+}
+
+func (s *shellCommandRunner) Run(command string, args ...string) error {
+	escaped := make([]string, len(args))
+	for index, arg := range args {
+		escaped[index] = shell.NewArg(arg, shell.ArgConfigEscape).String()
+	}
+	// no signal channel: the snapshot must be removed even after the profile was interrupted
+	rCommand := newShellCommand(command, escaped, s.wrapper.getEnvironment(true), s.wrapper.getShell(), s.wrapper.dryRun, nil, nil)
+	rCommand.stdout = s.wrapper.ctx.terminal.Stdout()
+	rCommand.stderr = s.wrapper.ctx.terminal.Stderr()
+	_, stderr, err := runShellCommand(rCommand)
+	if err != nil {
+		return newCommandError(rCommand, stderr, err)
+	}
+	return nil
+}
+
+// sourceSnapshot is the snapshot of the backup sources, available during the backup
+type sourceSnapshot struct {
+	provider sourceSnapshotProvider
+	// directory containing the snapshot, used as "source-base"
+	base string
+	// directory where the snapshot of the volume is available
+	target string
+	// first directory created for the snapshot, removed with the snapshot
+	created string
+	// backup sources, relative to the base directory
+	sources []string
+}
+
+// createSourceSnapshot creates the snapshot of the volume containing the backup sources.
+// The sources are rewritten relative to the base directory of the snapshot, so they keep their original path in the restic snapshot
+func (r *resticWrapper) createSourceSnapshot() error {
+	if r.profile.Backup == nil || r.profile.Backup.SourceSnapshot == nil || r.sourceSnapshot != nil {
+		return nil
+	}
+	section := r.profile.Backup.SourceSnapshot
+	if r.profile.Backup.UseStdin || len(r.profile.Backup.StdinSources) > 0 {
+		clog.Warningf("profile '%s': source-snapshot is not available with stdin", r.profile.Name)
+		return nil
+	}
+	snapshot, err := r.newSourceSnapshot(section)
+	if err != nil {
+		return fmt.Errorf("source snapshot on profile '%s': %w", r.profile.Name, err)
+	}
+
+	clog.Infof("profile '%s': creating %s snapshot of %s in %s", r.profile.Name, section.Type, section.Volume, snapshot.target)
+	snapshot.created = firstMissingDir(snapshot.target)
+	if err = os.MkdirAll(filepath.Dir(snapshot.target), 0o700); err == nil {
+		err = snapshot.provider.Create(snapshot.target)
+	}
+	if err != nil {
+		removeCreatedDirs(snapshot.target, snapshot.created)
+		return fmt.Errorf("source snapshot on profile '%s': %w", r.profile.Name, err)
+	}
+	r.sourceSnapshot = snapshot
+	return nil
+}
+
+// newSourceSnapshot prepares the snapshot of the volume, and the path of the backup sources in the snapshot
+func (r *resticWrapper) newSourceSnapshot(section *config.SourceSnapshotSection) (*sourceSnapshot, error) {
+	provider, err := newSourceSnapshotProvider(section, r.profile.Name, r.commandRunner)
+	if err != nil {
+		return nil, err
+	}
+	volumePath := section.GetPath()
+	if volumePath == "" {
+		return nil, errors.New("missing path of the volume")
+	}
+	volumePath, err = filepath.Abs(volumePath)
+	if err != nil {
+		return nil, err
+	}
+	// path of the volume from the root directory, e.g. "home" for "/home"
+	volumeDir := strings.TrimLeft(strings.TrimPrefix(volumePath, filepath.VolumeName(volumePath)), `/\`)
+
+	base := section.GetMount(r.profile.Name)
+	snapshot := &sourceSnapshot{
+		provider: provider,
+		base:     base,
+		target:   filepath.Join(base, volumeDir),
+	}
+	sources := r.getBackupSources()
+	if len(sources) == 0 {
+		return nil, errors.New("no source to back up")
+	}
+	for _, source := range sources {
+		source, err = filepath.Abs(source)
+		if err != nil {
+			return nil, err
+		}
+		relative, err := filepath.Rel(volumePath, source)
+		if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("source %q is not in %q", source, volumePath)
+		}
+		snapshot.sources = append(snapshot.sources, filepath.Join(volumeDir, relative))
+	}
+	return snapshot, nil
+}
+
+// removeSourceSnapshot removes the snapshot of the backup sources. It runs in the finally phase of the profile
+func (r *resticWrapper) removeSourceSnapshot() {
+	snapshot := r.sourceSnapshot
+	if snapshot == nil {
+		return
+	}
+	r.sourceSnapshot = nil
+	clog.Infof("profile '%s': removing snapshot %s", r.profile.Name, snapshot.target)
+	if err := snapshot.provider.Remove(snapshot.target); err != nil {
+		clog.Errorf("profile '%s': cannot remove snapshot %s: %s", r.profile.Name, snapshot.target, err)
+		return
+	}
+	removeCreatedDirs(snapshot.target, snapshot.created)
+}
+
+// firstMissingDir returns the first directory of the path that does not exist
+func firstMissingDir(path string) string {
+	parent := existingParent(path)
+	if parent == path {
+		return ""
+	}
+	for filepath.Dir(path) != parent {
+		path = filepath.Dir(path)
+	}
+	return path
+}
+
+// removeCreatedDirs removes the empty directories from dir up to the first directory created
+func removeCreatedDirs(dir, created string) {
+	if created == "" {
+		return
+	}
+	for {
+		if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+			clog.Debugf("cannot remove directory %s: %s", dir, err)
+			return
+		}
+		if dir == created || filepath.Dir(dir) == dir {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/platform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCommandRunner records the commands instead of running them
+type fakeCommandRunner struct {
+	commands []string
+	fail     string // commands starting with this prefix fail
+	onRun    func(commandLine string)
+}
+
+func (f *fakeCommandRunner) Run(command string, args ...string) error {
+	commandLine := strings.Join(append([]string{command}, args...), " ")
+	f.commands = append(f.commands, commandLine)
+	if f.fail != "" && strings.HasPrefix(commandLine, f.fail) {
+		return errors.New("failed")
+	}
+	if f.onRun != nil {
+		f.onRun(commandLine)
+	}
+	return nil
+}
+
+func TestSourceSnapshotProviders(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		section config.SourceSnapshotSection
+		create  []string
+		remove  []string
+	}{
+		{
+			section: config.SourceSnapshotSection{Type: "btrfs", Volume: "/home"},
+			create:  []string{"btrfs subvolume snapshot -r /home {target}"},
+			remove:  []string{"btrfs subvolume delete {target}"},
+		},
+		{
+			section: config.SourceSnapshotSection{Type: "lvm", Volume: "vg0/home"},
+			create:  []string{"lvcreate --snapshot --permission r --size 1G --name resticprofile-name vg0/home", "mount -o ro /dev/vg0/resticprofile-name {target}"},
+			remove:  []string{"umount {target}", "lvremove -f vg0/resticprofile-name"},
+		},
+		{
+			section: config.SourceSnapshotSection{Type: "LVM", Volume: "vg0/home", Name: "snap", Size: "10%ORIGIN", MountOptions: "ro,nouuid"},
+			create:  []string{"lvcreate --snapshot --permission r --size 10%ORIGIN --name snap vg0/home", "mount -o ro,nouuid /dev/vg0/snap {target}"},
+			remove:  []string{"umount {target}", "lvremove -f vg0/snap"},
+		},
+		{
+			section: config.SourceSnapshotSection{Type: "zfs", Volume: "tank/home"},
+			create:  []string{"zfs snapshot tank/home@resticprofile-name", "mount -t zfs tank/home@resticprofile-name {target}"},
+			remove:  []string{"umount {target}", "zfs destroy tank/home@resticprofile-name"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.section.Type, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "target")
+			runner := &fakeCommandRunner{}
+			provider, err := newSourceSnapshotProvider(&tc.section, "name", runner)
+			require.NoError(t, err)
+
+			require.NoError(t, provider.Create(target))
+			assert.Equal(t, tc.create, replaceTarget(runner.commands, target))
+
+			runner.commands = nil
+			require.NoError(t, provider.Remove(target))
+			assert.Equal(t, tc.remove, replaceTarget(runner.commands, target))
+		})
+	}
+}
+
+func replaceTarget(commands []string, target string) []string {
+	for index := range commands {
+		commands[index] = strings.ReplaceAll(commands[index], target, "{target}")
+	}
+	return commands
+}
+
+func TestSourceSnapshotProviderErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := newSourceSnapshotProvider(&config.SourceSnapshotSection{Type: "lvm"}, "name", &fakeCommandRunner{})
+	assert.ErrorContains(t, err, "missing volume")
+
+	_, err = newSourceSnapshotProvider(&config.SourceSnapshotSection{Type: "ext4", Volume: "/"}, "name", &fakeCommandRunner{})
+	assert.ErrorContains(t, err, `unknown type "ext4"`)
+
+	// the snapshot is deleted when it cannot be mounted
+	runner := &fakeCommandRunner{fail: "mount"}
+	provider, err := newSourceSnapshotProvider(&config.SourceSnapshotSection{Type: "zfs", Volume: "tank/home"}, "name", runner)
+	require.NoError(t, err)
+	assert.Error(t, provider.Create(filepath.Join(t.TempDir(), "target")))
+	assert.Equal(t, "zfs destroy tank/home@resticprofile-name", runner.commands[len(runner.commands)-1])
+}
+
+func newSourceSnapshotWrapper(t *testing.T, binary string, arguments ...string) (*resticWrapper, *fakeCommandRunner, *bytes.Buffer) {
+	t.Helper()
+	if platform.IsWindows() {
+		t.Skip("filesystem snapshots are not available on Windows")
+	}
+	profile := config.NewProfile(nil, "name")
+	profile.Backup = &config.BackupSection{
+		Source: []string{"/home/user", "/home/other/documents"},
+		SourceSnapshot: &config.SourceSnapshotSection{
+			Type:   config.SourceSnapshotLVM,
+			Volume: "vg0/home",
+			Path:   "/home",
+			Mount:  filepath.Join(t.TempDir(), "snapshot"),
+		},
+	}
+	wrapper, stdout := newTestWrapper(t, profile, constants.CommandBackup, binary, arguments...)
+	runner := &fakeCommandRunner{}
+	wrapper.commandRunner = runner
+	return wrapper, runner, stdout
+}
+
+func TestSourceSnapshotBackup(t *testing.T) {
+	t.Parallel()
+
+	wrapper, runner, stdout := newSourceSnapshotWrapper(t, mockBinary, "--args")
+	mount := wrapper.profile.Backup.SourceSnapshot.Mount
+	target := filepath.Join(mount, "home")
+
+	require.NoError(t, wrapper.createSourceSnapshot())
+	assert.DirExists(t, target)
+
+	// the sources are relative to the snapshot, so they keep their path in the restic snapshot
+	rCommand := wrapper.prepareCommand(constants.CommandBackup, wrapper.profile.GetCommandFlags(constants.CommandBackup), false)
+	assert.Equal(t, mount, rCommand.dir)
+	assert.Equal(t, []string{"backup", "--args", "home/user", "home/other/documents"}, rCommand.args)
+
+	require.NoError(t, wrapper.runProfile())
+	assert.Contains(t, stdout.String(), `"home/user" "home/other/documents"`)
+	assert.Equal(t, []string{
+		"lvcreate --snapshot --permission r --size 1G --name resticprofile-name vg0/home",
+		"mount -o ro /dev/vg0/resticprofile-name " + target,
+		"umount " + target,
+		"lvremove -f vg0/resticprofile-name",
+	}, runner.commands)
+	assert.NoDirExists(t, mount)
+	assert.Nil(t, wrapper.sourceSnapshot)
+}
+
+func TestSourceSnapshotRemovedAfterFailure(t *testing.T) {
+	t.Parallel()
+
+	wrapper, runner, _ := newSourceSnapshotWrapper(t, "exit")
+	assert.Error(t, wrapper.runProfile())
+	require.Len(t, runner.commands, 4)
+	assert.Equal(t, "lvremove -f vg0/resticprofile-name", runner.commands[3])
+	assert.NoDirExists(t, wrapper.profile.Backup.SourceSnapshot.Mount)
+}
+
+func TestSourceSnapshotRemovedAfterSignal(t *testing.T) {
+	t.Parallel()
+
+	wrapper, runner, _ := newSourceSnapshotWrapper(t, mockBinary, "--sleep", "5000")
+	timer := time.AfterFunc(300*time.Millisecond, func() {
+		wrapper.sigChan <- os.Interrupt
+	})
+	defer timer.Stop()
+
+	start := time.Now()
+	assert.Error(t, wrapper.runProfile())
+	assert.Less(t, time.Since(start), 5*time.Second)
+	require.Len(t, runner.commands, 4)
+	assert.Equal(t, "lvremove -f vg0/resticprofile-name", runner.commands[3])
+}
+
+func TestSourceSnapshotSourceOutsideVolume(t *testing.T) {
+	t.Parallel()
+
+	wrapper, runner, _ := newSourceSnapshotWrapper(t, mockBinary)
+	wrapper.profile.Backup.Source = []string{"/home/user", "/etc"}
+	err := wrapper.runProfile()
+	assert.ErrorContains(t, err, `source "/etc" is not in "/home"`)
+	assert.Empty(t, runner.commands)
+}
+
+func TestSourceSnapshotCreateFailure(t *testing.T) {
+	t.Parallel()
+
+	wrapper, runner, _ := newSourceSnapshotWrapper(t, mockBinary)
+	runner.fail = "lvcreate"
+	err := wrapper.runProfile()
+	assert.ErrorContains(t, err, "source snapshot on profile 'name'")
+	assert.Len(t, runner.commands, 1)
+	assert.NoDirExists(t, wrapper.profile.Backup.SourceSnapshot.Mount)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/monitor"
+	"github.com/creativeprojects/resticprofile/restic"
+	"github.com/creativeprojects/resticprofile/shell"
+)
+
+// runStdinSources backs up each stdin source in a snapshot of its own, and reports a single summary
+// with the result of each source
+func (r *resticWrapper) runStdinSources(args *shell.Args) (err error) {
+	sources := r.profile.Backup.StdinSources
+	r.start(constants.CommandBackup)
+	start := time.Now()
+
+	// the backup of each source is collected instead of being reported
+	progress := r.progress
+	collector := &summaryCollector{}
+	r.progress = []monitor.Receiver{collector}
+	defer func() {
+		r.progress = progress
+		r.stdinSource = nil
+	}()
+
+	r.sources = make([]monitor.SourceSummary, 0, len(sources))
+	var total monitor.Summary
+	var stderr []string
+	for index := range sources {
+		source := &sources[index]
+		clog.Infof("profile '%s': source %d/%d '%s'", r.profile.Name, index+1, len(sources), source.Name)
+
+		*collector = summaryCollector{}
+		r.stdinSource = source
+		sourceStart := time.Now()
+		var sourceErr error
+		if len(source.Command) == 0 {
+			sourceErr = fmt.Errorf("stdin source '%s' on profile '%s': no command", source.Name, r.profile.Name)
+		} else {
+			sourceErr = r.runCommandWithFlags(constants.CommandBackup, r.getStdinSourceFlags(args, source))
+		}
+
+		total.FilesNew += collector.summary.FilesNew
+		total.FilesChanged += collector.summary.FilesChanged
+		total.FilesUnmodified += collector.summary.FilesUnmodified
+		total.FilesTotal += collector.summary.FilesTotal
+		total.BytesAdded += collector.summary.BytesAdded
+		total.BytesAddedPacked += collector.summary.BytesAddedPacked
+		total.BytesTotal += collector.summary.BytesTotal
+		total.Attempts = max(total.Attempts, collector.summary.Attempts)
+		total.OutputAnalysis = collector.summary.OutputAnalysis
+		r.sources = append(r.sources, monitor.SourceSummary{
+			Name:       source.Name,
+			Duration:   time.Since(sourceStart),
+			BytesAdded: collector.summary.BytesAdded,
+			Error:      sourceErr,
+		})
+		if collector.stderr != "" {
+			stderr = append(stderr, collector.stderr)
+		}
+
+		if sourceErr == nil {
+			continue
+		}
+		if err == nil {
+			err = sourceErr
+		}
+		if !source.ContinueOnError || isInterrupted(sourceErr) {
+			break
+		}
+		clog.Errorf("profile '%s': source %d/%d '%s' failed, continuing with the next source: %s", r.profile.Name, index+1, len(sources), source.Name, sourceErr.Error())
+	}
+
+	total.Duration = time.Since(start)
+	total.Sources = r.sources
+	r.progress = progress
+	r.summary(constants.CommandBackup, total, strings.Join(stderr, "\n"), err)
+	return
+}
+
+// getStdinSourceFlags returns the flags of the backup of a stdin source
+func (r *resticWrapper) getStdinSourceFlags(args *shell.Args, source *config.StdinSourceSection) *shell.Args {
+	args = args.Clone()
+	args.Remove(constants.ParameterStdin)
+	if r.useStdinFromCommand(source) {
+		args.AddFlags(constants.ParameterStdinCommand, []shell.Arg{})
+	} else {
+		args.AddFlags(constants.ParameterStdin, []shell.Arg{})
+	}
+	if source.Filename != "" {
+		args.AddFlag(constants.ParameterStdinFilename, shell.NewArg(source.Filename, shell.ArgConfigEscape))
+	}
+	if len(source.Tag) > 0 {
+		tags, _ := args.Get(constants.ParameterTag)
+		args.AddFlags(constants.ParameterTag, append(slices.Clone(tags), shell.NewArgsSlice(source.Tag, shell.ArgConfigEscape)...))
+	}
+	return args
+}
+
+// useStdinFromCommand returns true when restic can run the command of the source itself ("--stdin-from-command"):
+// the restic version must be known to support it, and the source must be a single command without any shell syntax
+func (r *resticWrapper) useStdinFromCommand(source *config.StdinSourceSection) bool {
+	if len(source.Command) != 1 || strings.ContainsAny(source.Command[0], "|&;<>()$`'*?~{}") {
+		return false
+	}
+	version := r.getResticVersion()
+	if version == restic.AnyVersion {
+		return false
+	}
+	if command, found := restic.GetCommandForVersion(constants.CommandBackup, version, false); found {
+		_, found = command.Lookup(constants.ParameterStdinCommand)
+		return found
+	}
+	return false
+}
+
+// addStdinFromCommand adds the command of the current stdin source at the end of the restic arguments
+func (r *resticWrapper) addStdinFromCommand(rCommand *shellCommandDefinition) {
+	arguments := []string{"--"}
+	for _, argument := range shell.SplitArguments(r.stdinSource.Command[0]) {
+		arguments = append(arguments, shell.NewArg(argument, shell.ArgConfigEscape).String())
+	}
+	rCommand.args = append(slices.Clone(rCommand.args), arguments...)
+	rCommand.publicArgs = append(slices.Clone(rCommand.publicArgs), arguments...)
+}
+
+// summaryCollector keeps the last summary reported by the backup of a stdin source
+type summaryCollector struct {
+	summary monitor.Summary
+	stderr  string
+}
+
+func (c *summaryCollector) Start(command string)         {}
+func (c *summaryCollector) Status(status monitor.Status) {}
+func (c *summaryCollector) Summary(command string, summary monitor.Summary, stderr string, result error) {
+	c.summary = summary
+	c.stderr = stderr
+}
+
+// Verify interface
+var _ monitor.Receiver = &summaryCollector{}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/monitor"
+	"github.com/creativeprojects/resticprofile/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStdinSourcesWrapper(t *testing.T, sources ...config.StdinSourceSection) (*resticWrapper, *stepsReceiver, *bytes.Buffer) {
+	t.Helper()
+	profile := config.NewProfile(nil, "name")
+	profile.Backup = &config.BackupSection{StdinSources: sources}
+	wrapper, stdout := newTestWrapper(t, profile, constants.CommandBackup, mockBinary)
+	receiver := &stepsReceiver{summaries: make(map[string]monitor.Summary), results: make(map[string]error)}
+	wrapper.addProgress(receiver)
+	return wrapper, receiver, stdout
+}
+
+func TestStdinSources(t *testing.T) {
+	t.Parallel()
+
+	wrapper, receiver, stdout := newStdinSourcesWrapper(t,
+		config.StdinSourceSection{Name: "first", Command: []string{"echo first-content"}},
+		config.StdinSourceSection{Name: "second", Command: []string{"echo second-content"}},
+	)
+	require.NoError(t, wrapper.runProfile())
+
+	assert.Contains(t, stdout.String(), "first-content")
+	assert.Contains(t, stdout.String(), "second-content")
+
+	// a single backup is reported, with the result of each source
+	assert.Equal(t, []string{constants.CommandBackup}, receiver.started)
+	sources := receiver.summaries[constants.CommandBackup].Sources
+	require.Len(t, sources, 2)
+	assert.Equal(t, "first", sources[0].Name)
+	assert.Equal(t, "second", sources[1].Name)
+	assert.NoError(t, sources[0].Error)
+	assert.NoError(t, sources[1].Error)
+
+	// the sources are available in the context of the hooks
+	assert.Len(t, wrapper.getContext().Sources, 2)
+}
+
+func TestStdinSourcesFailure(t *testing.T) {
+	t.Parallel()
+
+	for _, continueOnError := range []bool{false, true} {
+		wrapper, receiver, stdout := newStdinSourcesWrapper(t,
+			config.StdinSourceSection{Name: "failing", Command: []string{"exit 2"}, ContinueOnError: continueOnError},
+			config.StdinSourceSection{Name: "second", Command: []string{"echo second-content"}},
+		)
+		err := wrapper.runProfile()
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "stdin source 'failing'")
+		assert.Equal(t, err, receiver.results[constants.CommandBackup])
+
+		sources := receiver.summaries[constants.CommandBackup].Sources
+		if continueOnError {
+			require.Len(t, sources, 2)
+			assert.Error(t, sources[0].Error)
+			assert.NoError(t, sources[1].Error)
+			assert.Contains(t, stdout.String(), "second-content")
+		} else {
+			require.Len(t, sources, 1)
+			assert.Error(t, sources[0].Error)
+			assert.NotContains(t, stdout.String(), "second-content")
+		}
+	}
+}
+
+func TestStdinSourceWithoutCommand(t *testing.T) {
+	t.Parallel()
+
+	wrapper, _, _ := newStdinSourcesWrapper(t, config.StdinSourceSection{Name: "empty"})
+	assert.ErrorContains(t, wrapper.runProfile(), "stdin source 'empty' on profile 'name': no command")
+}
+
+func TestStdinSourceFlags(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		version     string
+		command     []string
+		fromCommand bool
+	}{
+		{version: "", command: []string{"pg_dump db"}},
+		{version: "0.16.0", command: []string{"pg_dump db"}},
+		{version: "0.17.0", command: []string{"pg_dump db"}, fromCommand: true},
+		{version: "0.17.0", command: []string{"pg_dump db | gzip"}},
+		{version: "0.17.0", command: []string{"pg_dump db", "pg_dump other"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.version+" "+strings.Join(tc.command, ","), func(t *testing.T) {
+			wrapper, _, stdout := newStdinSourcesWrapper(t, config.StdinSourceSection{
+				Name:     "postgres",
+				Command:  tc.command,
+				Filename: "postgres.sql",
+				Tag:      []string{"database"},
+			})
+			wrapper.global.ResticVersion = tc.version
+			wrapper.moreArgs = []string{"--args"}
+			wrapper.profile.Backup.SetOtherFlag(constants.ParameterTag, []string{"server"})
+			source := &wrapper.profile.Backup.StdinSources[0]
+			assert.Equal(t, tc.fromCommand, wrapper.useStdinFromCommand(source))
+
+			flags := wrapper.getStdinSourceFlags(wrapper.profile.GetCommandFlags(constants.CommandBackup), source)
+			tags, _ := flags.Get(constants.ParameterTag)
+			assert.Len(t, tags, 2)
+			filename, _ := flags.Get(constants.ParameterStdinFilename)
+			assert.Equal(t, []string{"postgres.sql"}, argValues(filename))
+			_, found := flags.Get(constants.ParameterStdinCommand)
+			assert.Equal(t, tc.fromCommand, found)
+			_, found = flags.Get(constants.ParameterStdin)
+			assert.Equal(t, !tc.fromCommand, found)
+
+			if tc.fromCommand {
+				require.NoError(t, wrapper.runBackup(flags))
+				assert.Contains(t, stdout.String(), `"--stdin-filename=postgres.sql" "--stdin-from-command" "--tag=server" "--tag=database"`)
+				assert.Contains(t, stdout.String(), `"--" "pg_dump" "db"`)
+			}
+		})
+	}
+}
+
+func argValues(args []shell.Arg) (values []string) {
+	for _, arg := range args {
+		values = append(values, arg.Value())
+	}
+	return
+}
@@ -23,6 +23,7 @@ func main() {
 	command := os.Args[1]
 
 	stderr := ""
+	stdout := ""
 	stdoutFile := ""
 	stdin := false
 	exit := 0
@@ -32,13 +33,19 @@ func main() {
 	flags.Usage = func() {}
 	flags.SetOutput(io.Discard)
 	flags.StringVar(&stderr, "stderr", "", "send this message to stderr")
+	flags.StringVar(&stdout, "stdout", "", "send this message to stdout (or the content of the file with @file)")
 	flags.StringVar(&stdoutFile, "stdout-file", "", "redirect stdout to a file")
 	flags.BoolVar(&stdin, "stdin", false, "read stdin and send to stdout")
 	flags.IntVar(&exit, "exit", 0, "set exit code")
 	flags.BoolVar(&arguments, "args", false, "display command line arguments")
 	flags.IntVar(&sleep, "sleep", 0, "sleep timer in ms")
 
-	if err := flags.Parse(os.Args[2:]); err != nil {
+	// unknown flags are skipped
+	for args := os.Args[2:]; ; args = flags.Args() {
+		err := flags.Parse(args)
+		if err == nil {
+			break
+		}
 		if errors.Is(err, flag.ErrHelp) {
 			return
 		} else if !strings.Contains(err.Error(), "flag provided but not defined") {
@@ -67,6 +74,20 @@ func main() {
 		fmt.Printf("command: %s\n", command)
 	}
 
+	if stdout != "" {
+		if strings.HasPrefix(stdout, "@") {
+			if file, err := os.Open(stdout[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit = 3
+			} else {
+				_, _ = io.Copy(os.Stdout, file)
+				file.Close()
+			}
+		} else {
+			fmt.Println(stdout)
+		}
+	}
+
 	if stderr != "" {
 		if strings.HasPrefix(stderr, "@") {
 			if file, err := os.Open(stderr[1:]); err != nil {
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+)
+
+// sourcesManifest is the digest of the backup sources, saved after a successful backup
+type sourcesManifest struct {
+	Time   time.Time `json:"time"`
+	Files  int       `json:"files"`
+	Digest string    `json:"digest"`
+}
+
+// getManifestFile returns the path of the manifest file of the profile
+func getManifestFile(backup *config.BackupSection, profileName string) string {
+	if backup.ManifestFile != "" {
+		return backup.ManifestFile
+	}
+	return filepath.Join(xdg.StateHome, constants.ApplicationName, "manifest-"+profileName+".json")
+}
+
+func loadManifest(filename string) (*sourcesManifest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(sourcesManifest)
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func saveManifest(filename string, manifest *sourcesManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o600)
+}
+
+// isBackupUnchanged scans the backup sources, and returns true when they did not change since the last successful backup.
+// The scan is kept to update the manifest once the backup succeeded.
+func (r *resticWrapper) isBackupUnchanged() bool {
+	r.sourcesManifest = nil
+	backup := r.profile.Backup
+	if backup == nil || !backup.SkipIfUnchanged || r.dryRun {
+		return false
+	}
+	if backup.UseStdin || len(backup.StdinSources) > 0 || len(backup.FilesFrom) > 0 || len(backup.FilesFromRaw) > 0 || len(backup.FilesFromVerbatim) > 0 {
+		clog.Warningf("profile '%s': skip-if-unchanged is not available with stdin or files-from", r.profile.Name)
+		return false
+	}
+	sources := r.getBackupSources()
+	if len(sources) == 0 {
+		return false
+	}
+	excludes, err := newExcludeMatcher(backup)
+	if err != nil {
+		clog.Warningf("profile '%s': skip-if-unchanged: %s", r.profile.Name, err)
+		return false
+	}
+
+	start := time.Now()
+	manifest, err := scanSources(sources, excludes)
+	if err != nil {
+		clog.Warningf("profile '%s': skip-if-unchanged: cannot scan the sources: %s", r.profile.Name, err)
+		return false
+	}
+	clog.Debugf("profile '%s': scanned %d files in %s", r.profile.Name, manifest.Files, time.Since(start).Round(time.Millisecond))
+	r.sourcesManifest = manifest
+
+	filename := getManifestFile(backup, r.profile.Name)
+	previous, err := loadManifest(filename)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			clog.Warningf("profile '%s': cannot load manifest %q: %s", r.profile.Name, filename, err)
+		}
+		return false
+	}
+	return previous.Digest == manifest.Digest
+}
+
+// saveSourcesManifest saves the scan of the backup sources made before the backup that succeeded
+func (r *resticWrapper) saveSourcesManifest() {
+	if r.sourcesManifest == nil {
+		return
+	}
+	filename := getManifestFile(r.profile.Backup, r.profile.Name)
+	if err := saveManifest(filename, r.sourcesManifest); err != nil {
+		clog.Warningf("profile '%s': cannot save manifest %q: %s", r.profile.Name, filename, err)
+	}
+	r.sourcesManifest = nil
+}
+
+// scanSources returns the digest of the paths, sizes and modification times of all the files in the sources
+func scanSources(sources []string, excludes *excludeMatcher) (*sourcesManifest, error) {
+	manifest := &sourcesManifest{Time: time.Now()}
+	hash := sha256.New()
+	// a change of the sources or of the exclusions is a change too
+	_, _ = fmt.Fprintf(hash, "%q\n%q\n", sources, excludes.raw)
+
+	for _, source := range sources {
+		err := filepath.WalkDir(source, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if excludes.match(name) {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			manifest.Files++
+			_, _ = fmt.Fprintf(hash, "%s\x00%d\x00%d\x00%s\n", name, info.Size(), info.ModTime().UnixNano(), info.Mode())
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	manifest.Digest = hex.EncodeToString(hash.Sum(nil))
+	return manifest, nil
+}
+
+// excludeMatcher matches paths with the exclude patterns of restic
+type excludeMatcher struct {
+	raw      []string
+	patterns []excludePattern
+}
+
+type excludePattern struct {
+	parts       []string
+	insensitive bool
+}
+
+// newExcludeMatcher loads the patterns of "exclude", "iexclude", "exclude-file" and "iexclude-file".
+// When a pattern is negated ("!"), no path is excluded: the scan can only see more files than restic.
+func newExcludeMatcher(backup *config.BackupSection) (*excludeMatcher, error) {
+	matcher := &excludeMatcher{}
+	sets := []struct {
+		patterns    []string
+		files       []string
+		insensitive bool
+	}{
+		{backup.Exclude, backup.ExcludeFile, false},
+		{backup.Iexclude, backup.IexcludeFile, true},
+	}
+	for _, set := range sets {
+		patterns := set.patterns
+		for _, file := range set.files {
+			filePatterns, err := readExcludeFile(file)
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, filePatterns...)
+		}
+		for _, pattern := range patterns {
+			matcher.add(pattern, set.insensitive)
+		}
+	}
+	if slices.ContainsFunc(matcher.raw, func(pattern string) bool { return strings.HasPrefix(pattern, "!") }) {
+		matcher.patterns = nil
+	}
+	return matcher, nil
+}
+
+func readExcludeFile(filename string) (patterns []string, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read exclude file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, os.ExpandEnv(line))
+	}
+	return patterns, scanner.Err()
+}
+
+func (m *excludeMatcher) add(pattern string, insensitive bool) {
+	m.raw = append(m.raw, pattern)
+	pattern = filepath.ToSlash(pattern)
+	if insensitive {
+		pattern = strings.ToLower(pattern)
+	}
+	parts := strings.Split(strings.Trim(pattern, "/"), "/")
+	if !strings.HasPrefix(pattern, "/") {
+		// a relative pattern matches at any depth
+		parts = append([]string{"**"}, parts...)
+	}
+	m.patterns = append(m.patterns, excludePattern{parts: parts, insensitive: insensitive})
+}
+
+// match returns true when the path is excluded. Parent directories are expected to be matched first.
+func (m *excludeMatcher) match(name string) bool {
+	if m == nil || len(m.patterns) == 0 {
+		return false
+	}
+	name = filepath.ToSlash(strings.TrimPrefix(name, filepath.VolumeName(name)))
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	var lowerParts []string
+	for _, pattern := range m.patterns {
+		candidate := parts
+		if pattern.insensitive {
+			if lowerParts == nil {
+				lowerParts = strings.Split(strings.ToLower(strings.Trim(name, "/")), "/")
+			}
+			candidate = lowerParts
+		}
+		if matchParts(pattern.parts, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchParts matches the path elements with the pattern elements, "**" matching any number of elements
+func matchParts(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for index := 0; index <= len(parts); index++ {
+			if matchParts(pattern[1:], parts[index:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if matched, err := path.Match(pattern[0], parts[0]); err != nil || !matched {
+		return false
+	}
+	return matchParts(pattern[1:], parts[1:])
+}
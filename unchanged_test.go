@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/creativeprojects/resticprofile/monitor"
+	"github.com/creativeprojects/resticprofile/term"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExcludeMatcher(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		pattern     string
+		insensitive bool
+		path        string
+		match       bool
+	}{
+		{pattern: "*.tmp", path: "/home/user/file.tmp", match: true},
+		{pattern: "*.tmp", path: "/home/user/file.txt", match: false},
+		{pattern: "*.tmp", path: "/home/user/FILE.TMP", match: false},
+		{pattern: "*.tmp", insensitive: true, path: "/home/user/FILE.TMP", match: true},
+		{pattern: "cache", path: "/home/user/cache", match: true},
+		{pattern: "user/cache", path: "/home/user/cache", match: true},
+		{pattern: "/home/*/cache", path: "/home/user/cache", match: true},
+		{pattern: "/user/cache", path: "/home/user/cache", match: false},
+		{pattern: "/home/**/cache", path: "/home/user/a/b/cache", match: true},
+		{pattern: "/home/**", path: "/home/user", match: true},
+		{pattern: "/home", path: "/homes", match: false},
+		{pattern: "[", path: "/home", match: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.path, func(t *testing.T) {
+			matcher := &excludeMatcher{}
+			matcher.add(tc.pattern, tc.insensitive)
+			assert.Equal(t, tc.match, matcher.match(filepath.FromSlash(tc.path)))
+		})
+	}
+}
+
+func TestExcludeMatcherFromBackupSection(t *testing.T) {
+	t.Parallel()
+
+	excludeFile := filepath.Join(t.TempDir(), "excludes")
+	require.NoError(t, os.WriteFile(excludeFile, []byte("# comment\n\n*.log\n"), 0o600))
+
+	matcher, err := newExcludeMatcher(&config.BackupSection{
+		Exclude:      []string{"*.tmp"},
+		Iexclude:     []string{"*.bak"},
+		ExcludeFile:  []string{excludeFile},
+		IexcludeFile: nil,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.tmp", "*.log", "*.bak"}, matcher.raw)
+	assert.True(t, matcher.match("/file.tmp"))
+	assert.True(t, matcher.match("/file.log"))
+	assert.True(t, matcher.match("/file.BAK"))
+	assert.False(t, matcher.match("/file.txt"))
+
+	// a negated pattern disables the exclusions
+	matcher, err = newExcludeMatcher(&config.BackupSection{Exclude: []string{"*.tmp", "!keep.tmp"}})
+	require.NoError(t, err)
+	assert.False(t, matcher.match("/file.tmp"))
+
+	_, err = newExcludeMatcher(&config.BackupSection{ExcludeFile: []string{excludeFile + ".missing"}})
+	assert.ErrorContains(t, err, "cannot read exclude file")
+}
+
+func TestScanSources(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	excluded := filepath.Join(dir, "cache", "file")
+	require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Dir(excluded), 0o700))
+	require.NoError(t, os.WriteFile(excluded, []byte("content"), 0o600))
+
+	matcher := &excludeMatcher{}
+	matcher.add("cache", false)
+
+	scan := func() *sourcesManifest {
+		t.Helper()
+		manifest, err := scanSources([]string{dir}, matcher)
+		require.NoError(t, err)
+		return manifest
+	}
+	first := scan()
+	assert.Equal(t, 2, first.Files) // the source directory and the file
+	assert.Equal(t, first.Digest, scan().Digest)
+
+	// changes in excluded files are ignored
+	require.NoError(t, os.WriteFile(excluded, []byte("new content"), 0o600))
+	assert.Equal(t, first.Digest, scan().Digest)
+
+	// changes of size or modification time are detected
+	require.NoError(t, os.WriteFile(file, []byte("new content"), 0o600))
+	assert.NotEqual(t, first.Digest, scan().Digest)
+
+	second := scan()
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(-time.Hour)))
+	assert.NotEqual(t, second.Digest, scan().Digest)
+
+	_, err := scanSources([]string{filepath.Join(dir, "missing")}, matcher)
+	assert.Error(t, err)
+}
+
+func TestSkipIfUnchanged(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	manifestFile := filepath.Join(dir, "state", "manifest.json")
+	require.NoError(t, os.Mkdir(source, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "file"), []byte("content"), 0o600))
+
+	profile := config.NewProfile(nil, "name")
+	profile.Backup = &config.BackupSection{
+		Source:          []string{source},
+		SkipIfUnchanged: true,
+		ManifestFile:    manifestFile,
+	}
+
+	runBackup := func(binary string) (monitor.Summary, error) {
+		t.Helper()
+		ctx := &Context{
+			binary:   binary,
+			profile:  profile,
+			command:  constants.CommandBackup,
+			terminal: term.NewTerminal(term.WithStdout(&bytes.Buffer{}), term.WithStderr(&bytes.Buffer{})),
+		}
+		receiver := &stepsReceiver{summaries: make(map[string]monitor.Summary), results: make(map[string]error)}
+		wrapper := newResticWrapper(ctx)
+		wrapper.addProgress(receiver)
+		err := wrapper.runProfile()
+		return receiver.summaries[constants.CommandBackup], err
+	}
+
+	// a failed backup doesn't save the manifest
+	_, err := runBackup("exit")
+	assert.Error(t, err)
+	assert.NoFileExists(t, manifestFile)
+
+	summary, err := runBackup(mockBinary)
+	require.NoError(t, err)
+	assert.False(t, summary.Skipped)
+	assert.FileExists(t, manifestFile)
+
+	summary, err = runBackup(mockBinary)
+	require.NoError(t, err)
+	assert.True(t, summary.Skipped)
+
+	require.NoError(t, os.WriteFile(filepath.Join(source, "new file"), []byte("content"), 0o600))
+	summary, err = runBackup(mockBinary)
+	require.NoError(t, err)
+	assert.False(t, summary.Skipped)
+
+	summary, err = runBackup(mockBinary)
+	require.NoError(t, err)
+	assert.True(t, summary.Skipped)
+}
+
+func TestSkipIfUnchangedNotAvailable(t *testing.T) {
+	t.Parallel()
+
+	profile := config.NewProfile(nil, "name")
+	profile.Backup = &config.BackupSection{
+		Source:          []string{t.TempDir()},
+		SkipIfUnchanged: true,
+		ManifestFile:    filepath.Join(t.TempDir(), "manifest.json"),
+		UseStdin:        true,
+	}
+	wrapper := newResticWrapper(&Context{profile: profile, terminal: term.NewTerminal()})
+	assert.False(t, wrapper.isBackupUnchanged())
+	assert.Nil(t, wrapper.sourcesManifest)
+}
@@ -2,9 +2,11 @@ package templates
 
 import (
 	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"maps"
@@ -17,7 +19,9 @@ import (
 	"strings"
 	"sync"
 	"text/template"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/creativeprojects/resticprofile/util"
 	"github.com/creativeprojects/resticprofile/util/collect"
 )
@@ -47,6 +51,20 @@ import (
 //   - {{ tempDir }} => "/path/to/unique-tempdir"
 //   - {{ tempFile "filename" }} => "/path/to/unique-tempdir/filename"
 //   - {{ "seed" | randInt 123 456 }} => 166
+//   - {{ getenv "HOME" }} => "/home/user"
+//   - {{ getenv "UNDEFINED" "default" }} => "default"
+//   - {{ readFile "/path/to/file" }} => "file content"
+//   - {{ fileExists "/path/to/file" }} => true
+//   - {{ "plain" | sha256 }} => "a116c9ed...5f90475"
+//   - {{ hostname }} => "host"
+//   - {{ now | date "2006-01-02" }} => "2024-05-31"
+//   - {{ now | addDate 0 0 -7 | date "2006-01-02" }} => "2024-05-24"
+//   - {{ now | addDuration "-36h" | date "Mon" }} => "Wed"
+//   - {{ map "k" "v" | toJson }} => "{\"k\":\"v\"}"
+//   - {{ with fromJson "{\"k\":\"v\"}" }} {{ .k }} {{ end }} => " v "
+//   - {{ "" | default "value" }} => "value"
+//   - {{ .Env.NAME | required "NAME must be set" }} => fails with "NAME must be set" when empty
+//   - {{ semverCompare ">= 0.16" "0.17.1" }} => true
 func TemplateFuncs(funcs ...map[string]any) (templateFuncs map[string]any) {
 	templateFuncs = map[string]any{
 		"contains":   func(search any, src any) bool { return strings.Contains(toString(src), toString(search)) },
@@ -69,6 +87,21 @@ func TemplateFuncs(funcs ...map[string]any) (templateFuncs map[string]any) {
 		"tempFile":   TempFile,
 		"env":        func() string { return TempFile(".env.none") }, // satisfies the {{env}} interface w.o. functionality
 		"randInt":    randInt,
+
+		"getenv":        getenv,
+		"readFile":      readFile,
+		"fileExists":    fileExists,
+		"sha256":        func(src any) string { return fmt.Sprintf("%x", sha256.Sum256([]byte(toString(src)))) },
+		"hostname":      os.Hostname,
+		"now":           time.Now,
+		"date":          formatDate,
+		"addDate":       addDate,
+		"addDuration":   addDuration,
+		"toJson":        toJSON,
+		"fromJson":      fromJSON,
+		"default":       defaultValue,
+		"required":      required,
+		"semverCompare": semverCompare(nil),
 	}
 
 	// aliases
@@ -175,6 +208,144 @@ func EnvFileFunc(receiverFunc EnvFileReceiverFunc) map[string]any {
 	}
 }
 
+// ResticVersionFunc declares the backend interface for the "{{ semverCompare }}" template function
+type ResticVersionFunc func() string
+
+// SemverCompareFunc creates a template func "semverCompare" that checks a constraint against the restic version
+// when no version is passed as argument
+func SemverCompareFunc(resticVersion ResticVersionFunc) map[string]any {
+	return map[string]any{
+		"semverCompare": semverCompare(resticVersion),
+	}
+}
+
+// semverCompare returns a func checking a constraint against version (or the restic version when no version is passed)
+func semverCompare(resticVersion ResticVersionFunc) func(constraint string, version ...string) (bool, error) {
+	return func(constraint string, version ...string) (bool, error) {
+		return checkVersion(constraint, resticVersion, version...)
+	}
+}
+
+func checkVersion(constraint string, resticVersion ResticVersionFunc, version ...string) (bool, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+	actual := ""
+	if len(version) > 0 {
+		actual = version[0]
+	} else if resticVersion != nil {
+		actual = resticVersion()
+	}
+	if actual == "" {
+		return false, errors.New("no version to compare with")
+	}
+	v, err := semver.NewVersion(actual)
+	if err != nil {
+		return false, fmt.Errorf("invalid version %q: %w", actual, err)
+	}
+	return c.Check(v), nil
+}
+
+// getenv returns the value of the environment variable name, or the first default value when not set or empty
+func getenv(name string, defaultValue ...string) string {
+	if value := os.Getenv(name); value != "" || len(defaultValue) == 0 {
+		return value
+	}
+	return defaultValue[0]
+}
+
+func readFile(filename string) (string, error) {
+	content, err := os.ReadFile(filename)
+	return string(content), err
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// toTime converts a time, a unix timestamp or a RFC 3339 string to time.Time
+func toTime(arg any) (time.Time, error) {
+	switch t := arg.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t != nil {
+			return *t, nil
+		}
+	case int:
+		return time.Unix(int64(t), 0), nil
+	case int64:
+		return time.Unix(t, 0), nil
+	case string:
+		return time.Parse(time.RFC3339, t)
+	}
+	return time.Time{}, fmt.Errorf("cannot convert %v (%T) to time", arg, arg)
+}
+
+func withTime[R any](arg any, fn func(time.Time) R) (result R, err error) {
+	var t time.Time
+	if t, err = toTime(arg); err == nil {
+		result = fn(t)
+	}
+	return
+}
+
+func formatDate(layout string, t any) (string, error) {
+	return withTime(t, func(t time.Time) string { return t.Format(layout) })
+}
+
+func addDate(years, months, days int, t any) (time.Time, error) {
+	return withTime(t, func(t time.Time) time.Time { return t.AddDate(years, months, days) })
+}
+
+func addDuration(duration string, t any) (time.Time, error) {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return withTime(t, func(t time.Time) time.Time { return t.Add(d) })
+}
+
+func toJSON(value any) (string, error) {
+	content, err := json.Marshal(value)
+	return string(content), err
+}
+
+func fromJSON(content string) (value any, err error) {
+	err = json.Unmarshal([]byte(content), &value)
+	return
+}
+
+// isEmpty returns true for nil, zero values and empty collections
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
+
+func defaultValue(defaultValue, value any) any {
+	if isEmpty(value) {
+		return defaultValue
+	}
+	return value
+}
+
+func required(message string, value any) (any, error) {
+	if isEmpty(value) {
+		return nil, errors.New(message)
+	}
+	return value, nil
+}
+
 func toAny[T any](arg T) any { return arg }
 
 func toString(arg any) string {
@@ -8,6 +8,7 @@ import (
 	"path"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/creativeprojects/resticprofile/platform"
 	"github.com/creativeprojects/resticprofile/util"
@@ -19,6 +20,7 @@ func TestTemplateFuncs(t *testing.T) {
 	defer util.ClearTempDir()
 	dir := TempDir()
 	file := TempFile("test.txt")
+	hostname, _ := os.Hostname()
 
 	tests := []struct {
 		template, expected string
@@ -63,8 +65,35 @@ func TestTemplateFuncs(t *testing.T) {
 		{template: `{{ "hello" | randInt 5 6 }}`, expected: `5`},
 		{template: `{{ "" | randInt -1000 1000 }}`, expected: `419`},
 		{template: `{{ "ABC" | randInt -1000 1000 }}`, expected: `-272`},
+		{template: `{{ getenv "TEST_TEMPLATE_FUNCS" }}`, expected: `env-value`},
+		{template: `{{ getenv "TEST_TEMPLATE_FUNCS" "default" }}`, expected: `env-value`},
+		{template: `{{ getenv "TEST_TEMPLATE_FUNCS_UNDEFINED" }}`, expected: ``},
+		{template: `{{ getenv "TEST_TEMPLATE_FUNCS_UNDEFINED" "default" }}`, expected: `default`},
+		{template: `{{ readFile "` + file + `" }}`, expected: `file-content`},
+		{template: `{{ fileExists "` + file + `" }}`, expected: `true`},
+		{template: `{{ fileExists "` + file + `.missing" }}`, expected: `false`},
+		{template: `{{ "plain" | sha256 }}`, expected: "a116c9ed46d6207734a43317d30fd88f52ac8634c37d904bbf4e41d865f90475"},
+		{template: `{{ hostname }}`, expected: hostname},
+		{template: `{{ now | date "2006" | len }}`, expected: `4`},
+		{template: `{{ 1700000000 | date "2006-01-02T15:04Z07:00" }}`, expected: time.Unix(1700000000, 0).Format("2006-01-02T15:04Z07:00")},
+		{template: `{{ "2024-03-01T10:00:00Z" | addDate 0 0 -1 | date "2006-01-02" }}`, expected: `2024-02-29`},
+		{template: `{{ "2024-03-01T10:00:00Z" | addDate 1 1 0 | date "2006-01-02" }}`, expected: `2025-04-01`},
+		{template: `{{ "2024-03-01T10:00:00Z" | addDuration "-36h" | date "Mon 15:04" }}`, expected: `Wed 22:00`},
+		{template: `{{ map "k" "v" | toJson }}`, expected: `{"k":"v"}`},
+		{template: `{{ list 1 "a" true | toJson }}`, expected: `[1,"a",true]`},
+		{template: `{{ with fromJson "{\"k\":[1,2]}" }}{{ .k | toJson }}{{ end }}`, expected: `[1,2]`},
+		{template: `{{ "" | default "value" }}`, expected: `value`},
+		{template: `{{ "set" | default "value" }}`, expected: `set`},
+		{template: `{{ list | default "value" }}`, expected: `value`},
+		{template: `{{ 0 | default 10 }}`, expected: `10`},
+		{template: `{{ "set" | required "must be set" }}`, expected: `set`},
+		{template: `{{ semverCompare ">= 0.16" "0.17.1" }}`, expected: `true`},
+		{template: `{{ semverCompare "< 0.16" "0.17.1" }}`, expected: `false`},
 	}
 
+	t.Setenv("TEST_TEMPLATE_FUNCS", "env-value")
+	require.NoError(t, os.WriteFile(file, []byte("file-content"), 0o600))
+
 	extraFuncs := map[string]any{
 		"hello": func() string { return "Hello World" },
 	}
@@ -125,3 +154,48 @@ func TestTemplateFuncs(t *testing.T) {
 		}
 	})
 }
+
+func TestTemplateFuncErrors(t *testing.T) {
+	tests := []struct {
+		template, err string
+	}{
+		{template: `{{ "" | required "value must be set" }}`, err: "value must be set"},
+		{template: `{{ readFile "/path/to/missing/file" }}`, err: "error calling readFile"},
+		{template: `{{ fromJson "{" }}`, err: "error calling fromJson"},
+		{template: `{{ "not a date" | date "2006" }}`, err: "error calling date"},
+		{template: `{{ now | addDuration "1x" }}`, err: "error calling addDuration"},
+		{template: `{{ semverCompare "> 1" }}`, err: "no version to compare with"},
+		{template: `{{ semverCompare "invalid" "1.0" }}`, err: `invalid version constraint "invalid"`},
+		{template: `{{ semverCompare "> 1" "invalid" }}`, err: `invalid version "invalid"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.template, func(t *testing.T) {
+			tpl, err := New("test-template").Parse("\n" + test.template)
+			require.NoError(t, err)
+
+			err = tpl.Execute(&strings.Builder{}, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "template: test-template:2:")
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestSemverCompareFunc(t *testing.T) {
+	version := ""
+	extras := SemverCompareFunc(func() string { return version })
+
+	tpl, err := New("test-template", extras).Parse(`{{ semverCompare ">= 0.16" }}-{{ semverCompare ">= 0.16" "0.9" }}`)
+	require.NoError(t, err)
+
+	buffer := &strings.Builder{}
+	for _, version = range []string{"0.16.0", "0.15"} {
+		buffer.Reset()
+		require.NoError(t, tpl.Execute(buffer, nil))
+		assert.Equal(t, fmt.Sprintf("%t-false", version == "0.16.0"), buffer.String())
+	}
+
+	version = ""
+	assert.ErrorContains(t, tpl.Execute(buffer, nil), "no version to compare with")
+}
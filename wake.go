@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/creativeprojects/clog"
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+)
+
+// errHostNotAwake is returned when the host to wake up is not reachable before the timeout
+var errHostNotAwake = errors.New("host did not wake up")
+
+// runnerWithWake wakes up the remote host of the profile before running the action
+func (r *resticWrapper) runnerWithWake(action func() error) func() error {
+	return func() error {
+		if err := r.wakeHost(); err != nil {
+			return err
+		}
+		return action()
+	}
+}
+
+// wakeHost sends a Wake-on-LAN magic packet to the host, and waits until its TCP port is reachable
+func (r *resticWrapper) wakeHost() error {
+	wake := r.profile.Wake
+	if wake == nil || (wake.MAC == "" && wake.Host == "") {
+		return nil
+	}
+	if r.dryRun {
+		clog.Infof("dry-run: wake up %s", wake.MAC)
+		return nil
+	}
+	if wake.Host != "" && isReachable(wake.Host, wake.GetInterval()) {
+		clog.Debugf("profile '%s': %s is already reachable", r.profile.Name, wake.Host)
+		return nil
+	}
+	if wake.MAC != "" {
+		clog.Infof("profile '%s': sending wake-on-lan packet to %s", r.profile.Name, wake.MAC)
+		if err := sendMagicPacket(wake.MAC, wake.GetBroadcast()); err != nil {
+			return fmt.Errorf("wake-on-lan on profile '%s': %w", r.profile.Name, err)
+		}
+	}
+	if wake.Host == "" {
+		return nil
+	}
+	return r.waitForHost(wake)
+}
+
+// waitForHost polls the TCP port of the host until it is reachable, or until the timeout
+func (r *resticWrapper) waitForHost(wake *config.WakeSection) error {
+	start := time.Now()
+	deadline := start.Add(wake.GetTimeout())
+	for {
+		if isReachable(wake.Host, min(wake.GetInterval(), time.Until(deadline))) {
+			clog.Infof("profile '%s': %s is reachable after %s", r.profile.Name, wake.Host, time.Since(start).Round(time.Second))
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%w on profile '%s': %s is not reachable after %s", errHostNotAwake, r.profile.Name, wake.Host, wake.GetTimeout())
+		}
+		clog.Debugf("profile '%s': waiting for %s", r.profile.Name, wake.Host)
+		if err := interruptibleSleep(min(wake.GetInterval(), time.Until(deadline)), r.sigChan); err != nil {
+			return err
+		}
+	}
+}
+
+// isReachable returns true when a TCP connection to the address succeeds
+func isReachable(address string, timeout time.Duration) bool {
+	conn, err := net.DialTimeout("tcp", address, max(timeout, 100*time.Millisecond))
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// sendMagicPacket sends the Wake-on-LAN magic packet of the MAC address to the broadcast address.
+// The default Wake-on-LAN port is used when the broadcast address has no port
+func sendMagicPacket(mac, broadcast string) error {
+	packet, err := magicPacket(mac)
+	if err != nil {
+		return err
+	}
+	if _, _, err = net.SplitHostPort(broadcast); err != nil {
+		broadcast = net.JoinHostPort(broadcast, constants.WakeOnLANPort)
+	}
+	address, err := net.ResolveUDPAddr("udp", broadcast)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialUDP("udp", nil, address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(packet)
+	return err
+}
+
+// magicPacket returns the Wake-on-LAN magic packet: 6 bytes 0xFF followed by the MAC address repeated 16 times
+func magicPacket(mac string) ([]byte, error) {
+	address, err := net.ParseMAC(mac)
+	if err != nil {
+		return nil, err
+	}
+	if len(address) != 6 {
+		return nil, fmt.Errorf("invalid MAC address %q: 6 bytes expected", mac)
+	}
+	packet := bytes.Repeat([]byte{0xff}, 6)
+	packet = append(packet, bytes.Repeat(address, 16)...)
+	return packet, nil
+}
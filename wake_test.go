@@ -0,0 +1,177 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/creativeprojects/resticprofile/config"
+	"github.com/creativeprojects/resticprofile/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMagicPacket(t *testing.T) {
+	t.Parallel()
+
+	packet, err := magicPacket("00:11:22:aa:bb:cc")
+	require.NoError(t, err)
+	require.Len(t, packet, 102)
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, packet[:6])
+	for offset := 6; offset < len(packet); offset += 6 {
+		assert.Equal(t, []byte{0x00, 0x11, 0x22, 0xaa, 0xbb, 0xcc}, packet[offset:offset+6])
+	}
+
+	_, err = magicPacket("not a mac")
+	assert.Error(t, err)
+	_, err = magicPacket("00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01")
+	assert.ErrorContains(t, err, "6 bytes expected")
+}
+
+// listenUDP returns the address of a local UDP listener, and a channel receiving the first packet
+func listenUDP(t *testing.T) (string, <-chan []byte) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		buffer := make([]byte, 1024)
+		n, _, err := conn.ReadFrom(buffer)
+		if err == nil {
+			received <- buffer[:n]
+		}
+	}()
+	return conn.LocalAddr().String(), received
+}
+
+// freeTCPAddress returns a local TCP address with no listener
+func freeTCPAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func newWakeWrapper(t *testing.T, wake *config.WakeSection) *resticWrapper {
+	t.Helper()
+	profile := config.NewProfile(nil, "name")
+	profile.Wake = wake
+	wrapper, _ := newTestWrapper(t, profile, constants.CommandCheck, mockBinary)
+	return wrapper
+}
+
+func TestWakeHost(t *testing.T) {
+	t.Parallel()
+
+	broadcast, received := listenUDP(t)
+	host := freeTCPAddress(t)
+	wrapper := newWakeWrapper(t, &config.WakeSection{
+		MAC:       "00:11:22:aa:bb:cc",
+		Broadcast: broadcast,
+		Host:      host,
+		Timeout:   10 * time.Second,
+		Interval:  100 * time.Millisecond,
+	})
+
+	// the host wakes up once it received the magic packet
+	go func() {
+		select {
+		case <-received:
+		case <-time.After(10 * time.Second):
+			return
+		}
+		time.Sleep(300 * time.Millisecond)
+		listener, err := net.Listen("tcp", host)
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { listener.Close() })
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	assert.NoError(t, wrapper.runProfile())
+}
+
+func TestWakeHostAlreadyReachable(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	broadcast, received := listenUDP(t)
+	wrapper := newWakeWrapper(t, &config.WakeSection{MAC: "00:11:22:aa:bb:cc", Broadcast: broadcast, Host: listener.Addr().String()})
+	require.NoError(t, wrapper.wakeHost())
+	select {
+	case <-received:
+		t.Error("magic packet sent to a host already reachable")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWakeHostSendOnly(t *testing.T) {
+	t.Parallel()
+
+	broadcast, received := listenUDP(t)
+	wrapper := newWakeWrapper(t, &config.WakeSection{MAC: "00-11-22-AA-BB-CC", Broadcast: broadcast})
+	require.NoError(t, wrapper.wakeHost())
+	select {
+	case packet := <-received:
+		assert.Len(t, packet, 102)
+	case <-time.After(5 * time.Second):
+		t.Error("magic packet not received")
+	}
+}
+
+func TestWakeHostTimeout(t *testing.T) {
+	t.Parallel()
+
+	broadcast, _ := listenUDP(t)
+	host := freeTCPAddress(t)
+	testFile := filepath.Join(t.TempDir(), "TestWakeHostTimeout.txt")
+	wrapper := newWakeWrapper(t, &config.WakeSection{
+		MAC:       "00:11:22:aa:bb:cc",
+		Broadcast: broadcast,
+		Host:      host,
+		Timeout:   300 * time.Millisecond,
+		Interval:  100 * time.Millisecond,
+	})
+	wrapper.profile.RunAfterFail = []string{"echo $ERROR_MESSAGE > " + testFile}
+
+	err := wrapper.runProfile()
+	assert.ErrorIs(t, err, errHostNotAwake)
+	assert.ErrorContains(t, err, host+" is not reachable after 300ms")
+
+	// the error is available to the failure hooks
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "host did not wake up on profile 'name'")
+}
+
+func TestWakeHostInvalidMAC(t *testing.T) {
+	t.Parallel()
+
+	wrapper := newWakeWrapper(t, &config.WakeSection{MAC: "invalid"})
+	assert.ErrorContains(t, wrapper.runProfile(), "wake-on-lan on profile 'name'")
+}
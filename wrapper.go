@@ -18,6 +18,7 @@ import (
 	"github.com/creativeprojects/resticprofile/config"
 	"github.com/creativeprojects/resticprofile/constants"
 	"github.com/creativeprojects/resticprofile/lock"
+	"github.com/creativeprojects/resticprofile/mask"
 	"github.com/creativeprojects/resticprofile/monitor"
 	"github.com/creativeprojects/resticprofile/monitor/hook"
 	"github.com/creativeprojects/resticprofile/restic"
@@ -46,6 +47,22 @@ type resticWrapper struct {
 	executionTime time.Duration
 	doneTryUnlock bool
 	previousEnv   string
+	steps         []monitor.StepSummary
+	sources       []monitor.SourceSummary
+	attempts      int
+	anomalies     []string
+	// stdin source being backed up, when the backup has "stdin-sources"
+	stdinSource *config.StdinSourceSection
+	// snapshot of the volume containing the backup sources
+	sourceSnapshot *sourceSnapshot
+	commandRunner  commandRunner
+	// mount points of the volumes to unmount once the profile finished
+	mounted   []string
+	isMounted func(path string) (bool, error)
+	// scan of the backup sources, saved once the backup succeeded
+	sourcesManifest *sourcesManifest
+	// sets the priority of the limits of a time window
+	setLimitsPriority func(limits *config.LimitsSection)
 }
 
 func newResticWrapper(ctx *Context) *resticWrapper {
@@ -55,7 +72,7 @@ func newResticWrapper(ctx *Context) *resticWrapper {
 
 	resticDryRun := slices.ContainsFunc(ctx.request.arguments, collect.In("--dry-run", "-n"))
 
-	return &resticWrapper{
+	wrapper := &resticWrapper{
 		ctx:      ctx,
 		dryRun:   ctx.flags.dryRun,
 		noLock:   false,
@@ -77,6 +94,12 @@ func newResticWrapper(ctx *Context) *resticWrapper {
 		executionTime: 0,
 		doneTryUnlock: false,
 	}
+	wrapper.commandRunner = &shellCommandRunner{wrapper: wrapper}
+	wrapper.isMounted = isMountPoint
+	if !ctx.flags.noPriority {
+		wrapper.setLimitsPriority = setPriorityFromLimits
+	}
+	return wrapper
 }
 
 // ignoreLock configures resticWrapper to ignore the lock defined in profile
@@ -137,6 +160,46 @@ func (r *resticWrapper) getCommandAction(command string) func() error {
 	return func() error { return r.runCommand(command) }
 }
 
+// getCompositeAction returns the action running the steps of a command declared in the "commands" section of the profile
+func (r *resticWrapper) getCompositeAction(composite *config.CommandSection) func() error {
+	return func() (err error) {
+		r.start(r.command)
+		r.steps = make([]monitor.StepSummary, 0, len(composite.Steps))
+		start := time.Now()
+
+		for index := range composite.Steps {
+			step := &composite.Steps[index]
+			clog.Infof("profile '%s': step %d/%d of '%s'", r.profile.Name, index+1, len(composite.Steps), r.command)
+
+			stepStart := time.Now()
+			var stepErr error
+			if step.Command == constants.CommandBackup {
+				stepErr = r.runBackup(r.profile.GetCommandStepFlags(step))
+			} else {
+				stepErr = r.runCommandWithFlags(step.Command, r.profile.GetCommandStepFlags(step))
+			}
+			r.steps = append(r.steps, monitor.StepSummary{
+				Command:  step.Command,
+				Duration: time.Since(stepStart),
+				Error:    stepErr,
+			})
+			if stepErr == nil {
+				continue
+			}
+			if err == nil {
+				err = stepErr
+			}
+			if !step.ContinueOnError || isInterrupted(stepErr) {
+				break
+			}
+			clog.Errorf("step %d/%d '%s' failed, continuing with the next step: %s", index+1, len(composite.Steps), step.Command, stepErr.Error())
+		}
+
+		r.summary(r.command, monitor.Summary{Duration: time.Since(start), Steps: r.steps}, "", err)
+		return
+	}
+}
+
 func (r *resticWrapper) getCopyAction() func() error {
 	copyAction := r.getCommandAction(constants.CommandCopy)
 
@@ -151,7 +214,9 @@ func (r *resticWrapper) getCopyAction() func() error {
 }
 
 func (r *resticWrapper) getBackupAction() func() error {
-	backupAction := r.getCommandAction(constants.CommandBackup)
+	backupAction := func() error {
+		return r.runBackup(r.profile.GetCommandFlags(constants.CommandBackup))
+	}
 
 	return func() (err error) {
 		// Check before
@@ -209,7 +274,12 @@ func (r *resticWrapper) runProfile() error {
 	err := lockRun(lockFile, r.profile.ForceLock, r.lockWait, r.sigChan, func(setPID lock.SetPID) error {
 		r.setPID = setPID
 		return runOnFailure(
-			r.runnerWithBeforeAndAfter(profileShellCommands, "", func() (err error) {
+			r.runnerWithWake(r.runnerWithMounts(r.runnerWithBeforeAndAfter(profileShellCommands, "", func() (err error) {
+				// preflight checks run after the "run-before" of the profile (which may mount the sources)
+				if err = r.runPreflight(); err != nil {
+					return
+				}
+
 				// breaking change from 0.7.0 and 0.7.1:
 				// run the initialization after the pre-profile commands
 				if (r.global.Initialize || r.profile.Initialize) && r.command != constants.CommandInit {
@@ -220,6 +290,7 @@ func (r *resticWrapper) runProfile() error {
 				r.sendBefore(sendMonitoring, r.command)
 
 				// Main command
+				r.applyLimitsPriority(time.Now())
 				{
 					var runner func() error
 					switch r.command {
@@ -228,7 +299,11 @@ func (r *resticWrapper) runProfile() error {
 					case constants.CommandBackup:
 						runner = r.getBackupAction()
 					default:
-						runner = r.getCommandAction(r.command)
+						if composite, found := r.profile.GetCompositeCommand(r.command); found {
+							runner = r.getCompositeAction(composite)
+						} else {
+							runner = r.getCommandAction(r.command)
+						}
 					}
 
 					// Wrap command action in "run-before" & "run-after" from section
@@ -242,7 +317,7 @@ func (r *resticWrapper) runProfile() error {
 					r.sendAfter(sendMonitoring, r.command)
 				}
 				return
-			}),
+			}))),
 			// on failure
 			func(err error) {
 				r.sendAfterFail(sendMonitoring, r.command, err)
@@ -254,6 +329,9 @@ func (r *resticWrapper) runProfile() error {
 			},
 			// finally
 			func(err error) {
+				// volumes are unmounted last, even when a "run-finally" command panics
+				defer r.unmountVolumes()
+				r.removeSourceSnapshot()
 				r.runFinalShellCommands(r.command, err)
 				r.sendFinally(sendMonitoring, r.command, err)
 			},
@@ -332,9 +410,14 @@ func (r *resticWrapper) validArgumentsFilter(validArgs []string) argumentsFilter
 	}
 }
 
-func (r *resticWrapper) getShell() (shell []string) {
-	if r.global != nil {
-		shell = collect.All(r.global.ShellBinary, collect.Not(collect.In("auto")))
+func (r *resticWrapper) getShell() []string {
+	return getShell(r.global)
+}
+
+// getShell returns the shell binaries configured in the global section (nil for auto-detection)
+func getShell(global *config.Global) (shell []string) {
+	if global != nil {
+		shell = collect.All(global.ShellBinary, collect.Not(collect.In("auto")))
 	}
 	return
 }
@@ -379,7 +462,10 @@ func (r *resticWrapper) prepareCommand(command string, args *shell.Args, allowEx
 
 	// Special case for backup command
 	var dir string
-	if command == constants.CommandBackup {
+	if command == constants.CommandBackup && r.sourceSnapshot != nil {
+		args.AddArgs(shell.NewArgsSlice(r.sourceSnapshot.sources, shell.ArgConfigBackupSource))
+		dir = r.sourceSnapshot.base
+	} else if command == constants.CommandBackup && r.stdinSource == nil {
 		args.AddArgs(shell.NewArgsSlice(r.profile.GetBackupSource(), shell.ArgConfigBackupSource))
 		if r.profile.Backup != nil && r.profile.Backup.SourceRelative {
 			dir = r.profile.Backup.SourceBase
@@ -390,6 +476,9 @@ func (r *resticWrapper) prepareCommand(command string, args *shell.Args, allowEx
 		args.AddArgs(shell.NewArgsSlice(r.profile.GetCopySnapshotIDs(), shell.ArgConfigEscape))
 	}
 
+	// Override bandwidth and priority from the time window restic starts in
+	r.applyLimits(args, time.Now())
+
 	env := r.getEnvironment(true)
 	env = append(env, r.getProfileEnvironment()...)
 
@@ -401,6 +490,12 @@ func (r *resticWrapper) prepareCommand(command string, args *shell.Args, allowEx
 		args = args.Modify(modifier)
 	}
 
+	// Pass confidential repositories and passwords through inherited pipes
+	var secrets []string
+	if r.global.UsePipeSecrets() {
+		secrets, env = handOffSecrets(command, args, env)
+	}
+
 	// Add retry-lock (supported from restic 0.16, depends on filter being enabled)
 	if lockRetryTime, enabled := r.remainingLockRetryTime(); enabled && filter != nil {
 		// limiting the retry handling in restic, we need to make sure we can retry internally so that unlock is called.
@@ -441,6 +536,7 @@ func (r *resticWrapper) prepareCommand(command string, args *shell.Args, allowEx
 	rCommand.stderr = r.ctx.terminal.Stderr()
 	rCommand.streamError = r.profile.StreamError
 	rCommand.dir = dir
+	rCommand.secrets = secrets
 
 	return rCommand
 }
@@ -480,13 +576,17 @@ func (r *resticWrapper) runCheck() error {
 	clog.Infof("profile '%s': checking repository consistency", r.profile.Name)
 	r.start(constants.CommandCheck)
 	args := r.profile.GetCommandFlags(constants.CommandCheck)
-	for {
+	for attempt := 1; ; attempt++ {
 		rCommand := r.prepareCommand(constants.CommandCheck, args, false)
 		summary, stderr, err := runShellCommand(rCommand)
 		r.executionTime += summary.Duration
+		summary.Attempts = r.countAttempts(constants.CommandCheck, attempt)
 		r.summary(constants.CommandCheck, summary, stderr, err)
 		if err != nil {
 			retry, interruptedError := r.canRetryAfterError(constants.CommandCheck, summary)
+			if !retry && interruptedError == nil {
+				retry, interruptedError = r.canRetryAfterFailure(constants.CommandCheck, attempt, err, stderr)
+			}
 			if retry {
 				continue
 			}
@@ -501,16 +601,28 @@ func (r *resticWrapper) runCheck() error {
 }
 
 func (r *resticWrapper) runRetention() error {
+	if r.isRetentionBlocked() {
+		clog.Warningf("profile '%s': retention skipped after the anomaly detected in the backup", r.profile.Name)
+		return nil
+	}
 	clog.Infof("profile '%s': cleaning up repository using retention information", r.profile.Name)
 	r.start(constants.SectionConfigurationRetention)
 	args := r.profile.GetRetentionFlags()
-	for {
+	if err := r.checkRetentionSafety(args); err != nil {
+		r.summary(constants.SectionConfigurationRetention, monitor.Summary{}, "", err)
+		return err
+	}
+	for attempt := 1; ; attempt++ {
 		rCommand := r.prepareCommand(constants.CommandForget, args, false)
 		summary, stderr, err := runShellCommand(rCommand)
 		r.executionTime += summary.Duration
+		summary.Attempts = r.countAttempts(constants.CommandForget, attempt)
 		r.summary(constants.SectionConfigurationRetention, summary, stderr, err)
 		if err != nil {
 			retry, interruptedError := r.canRetryAfterError(constants.CommandForget, summary)
+			if !retry && interruptedError == nil {
+				retry, interruptedError = r.canRetryAfterFailure(constants.CommandForget, attempt, err, stderr)
+			}
 			if retry {
 				continue
 			}
@@ -525,14 +637,39 @@ func (r *resticWrapper) runRetention() error {
 }
 
 func (r *resticWrapper) runCommand(command string) error {
+	return r.runCommandWithFlags(command, r.profile.GetCommandFlags(command))
+}
+
+// runBackup runs the backup command from the snapshot of the sources when "source-snapshot" is configured,
+// and once for each source when "stdin-sources" is configured
+func (r *resticWrapper) runBackup(args *shell.Args) error {
+	if err := r.createSourceSnapshot(); err != nil {
+		return err
+	}
+	if r.profile.Backup == nil || len(r.profile.Backup.StdinSources) == 0 {
+		return r.runCommandWithFlags(constants.CommandBackup, args)
+	}
+	return r.runStdinSources(args)
+}
+
+func (r *resticWrapper) runCommandWithFlags(command string, args *shell.Args) error {
+	if command == constants.CommandForget && r.isRetentionBlocked() {
+		clog.Warningf("profile '%s': '%s' skipped after the anomaly detected in the backup", r.profile.Name, command)
+		return nil
+	}
 	clog.Infof("profile '%s': starting '%s'", r.profile.Name, command)
 	r.start(command)
-	args := r.profile.GetCommandFlags(command)
+
+	if command == constants.CommandBackup && r.isBackupUnchanged() {
+		clog.Infof("profile '%s': skipping '%s', the sources did not change since the last backup", r.profile.Name, command)
+		r.summary(command, monitor.Summary{Skipped: true}, "", nil)
+		return nil
+	}
 
 	streamSource := io.NopCloser(strings.NewReader(""))
 	defer func() { streamSource.Close() }()
 
-	for {
+	for attempt := 1; ; attempt++ {
 		if err := streamSource.Close(); err != nil {
 			return fmt.Errorf("%s on profile '%s'. Failed closing stream source: %w", r.command, r.profile.Name, err)
 		}
@@ -541,7 +678,7 @@ func (r *resticWrapper) runCommand(command string) error {
 
 		if command == constants.CommandBackup && r.profile.Backup != nil {
 			// Add output scanners
-			if len(r.progress) > 0 {
+			if len(r.progress) > 0 || r.profile.Backup.GetAnomaly() != nil {
 				if r.profile.Backup.ExtendedStatus {
 					rCommand.scanOutput = shell.ScanBackupJson
 				} else if !r.ctx.terminal.StdoutIsTerminal() {
@@ -551,6 +688,11 @@ func (r *resticWrapper) runCommand(command string) error {
 				}
 			}
 
+			// The command of a stdin source is run by restic itself
+			if r.stdinSource != nil && r.useStdinFromCommand(r.stdinSource) {
+				r.addStdinFromCommand(&rCommand)
+			}
+
 			// Redirect a stream source to stdin of restic if configured
 			if source, err := r.prepareStreamSource(); err == nil {
 				if source != nil {
@@ -564,10 +706,18 @@ func (r *resticWrapper) runCommand(command string) error {
 
 		summary, stderr, err := runShellCommand(rCommand)
 		r.executionTime += summary.Duration
-		r.summary(r.command, summary, stderr, err)
+		summary.Attempts = r.countAttempts(command, attempt)
+		if err == nil && rCommand.scanOutput != nil && r.stdinSource == nil {
+			summary.Anomalies = r.detectAnomalies(summary)
+			r.anomalies = summary.Anomalies
+		}
+		r.summary(command, summary, stderr, err)
 
 		if err != nil && !r.canSucceedAfterError(command, err) {
 			retry, interruptedError := r.canRetryAfterError(command, summary)
+			if !retry && interruptedError == nil {
+				retry, interruptedError = r.canRetryAfterFailure(command, attempt, err, stderr)
+			}
 			if retry {
 				continue
 			}
@@ -578,6 +728,12 @@ func (r *resticWrapper) runCommand(command string) error {
 			return newCommandError(rCommand, stderr, fmt.Errorf("%s on profile '%s': %w", r.command, r.profile.Name, err))
 		}
 		clog.Infof("profile '%s': finished '%s'", r.profile.Name, command)
+		if command == constants.CommandBackup && err == nil {
+			r.saveSourcesManifest()
+		}
+		if len(summary.Anomalies) > 0 {
+			return r.runOnAnomaly(command)
+		}
 		return nil
 	}
 }
@@ -743,10 +899,17 @@ func (r *resticWrapper) stringifyEnvironment(env *util.Environment) string {
 // (name and command for now)
 func (r *resticWrapper) getProfileEnvironment() []string {
 	ctx := r.getContext()
-	return []string{
+	env := []string{
 		fmt.Sprintf("%s=%s", constants.EnvProfileName, ctx.ProfileName),
 		fmt.Sprintf("%s=%s", constants.EnvProfileCommand, ctx.ProfileCommand),
 	}
+	if ctx.Attempts > 0 {
+		env = append(env, fmt.Sprintf("%s=%d", constants.EnvAttempts, ctx.Attempts))
+	}
+	if len(ctx.Anomalies) > 0 {
+		env = append(env, fmt.Sprintf("%s=%s", constants.EnvAnomaly, strings.Join(ctx.Anomalies, ", ")))
+	}
+	return env
 }
 
 // getFailEnvironment returns additional environment variables describing the failure
@@ -767,6 +930,10 @@ func (r *resticWrapper) getFailEnvironment(err error) (env []string) {
 		// Deprecated: STDERR can originate from (pre/post)-command which doesn't need to be restic
 		env = append(env, fmt.Sprintf("RESTIC_STDERR=%s", ctx.Stderr))
 	}
+	if safetyErr, ok := asRetentionSafetyError(err); ok {
+		env = append(env, fmt.Sprintf("%s=%d", constants.EnvRetentionRemove, safetyErr.remove))
+		env = append(env, fmt.Sprintf("%s=%d", constants.EnvRetentionTotal, safetyErr.total))
+	}
 	return
 }
 
@@ -774,12 +941,51 @@ func (r *resticWrapper) getContext() hook.Context {
 	return hook.Context{
 		ProfileName:    r.profile.Name,
 		ProfileCommand: r.command,
+		Steps:          r.getStepsContext(),
+		Sources:        r.getSourcesContext(),
+		Attempts:       r.attempts,
+		Anomalies:      r.anomalies,
+	}
+}
+
+// getStepsContext returns the steps of a composite command that already ran
+func (r *resticWrapper) getStepsContext() (steps []hook.StepContext) {
+	for _, step := range r.steps {
+		stepContext := hook.StepContext{
+			Command:  step.Command,
+			Success:  step.Error == nil,
+			Duration: step.Duration.Round(time.Second).String(),
+		}
+		if step.Error != nil {
+			stepContext.Error = mask.Text(step.Error.Error())
+		}
+		steps = append(steps, stepContext)
 	}
+	return
+}
+
+// getSourcesContext returns the stdin sources of the backup that already ran
+func (r *resticWrapper) getSourcesContext() (sources []hook.SourceContext) {
+	for _, source := range r.sources {
+		sourceContext := hook.SourceContext{
+			Name:     source.Name,
+			Success:  source.Error == nil,
+			Duration: source.Duration.Round(time.Second).String(),
+		}
+		if source.Error != nil {
+			sourceContext.Error = mask.Text(source.Error.Error())
+		}
+		sources = append(sources, sourceContext)
+	}
+	return
 }
 
 func (r *resticWrapper) getContextWithError(err error) hook.Context {
 	ctx := r.getContext()
 	ctx.Error = r.getErrorContext(err)
+	if safetyErr, ok := asRetentionSafetyError(err); ok {
+		ctx.Retention = hook.RetentionContext{Remove: safetyErr.remove, Total: safetyErr.total}
+	}
 	return ctx
 }
 
@@ -788,7 +994,7 @@ func (r *resticWrapper) getErrorContext(err error) hook.ErrorContext {
 	if err == nil {
 		return ctx
 	}
-	ctx.Message = err.Error()
+	ctx.Message = mask.Text(err.Error())
 
 	fail := &commandError{}
 	if errors.As(err, &fail) {
@@ -797,9 +1003,9 @@ func (r *resticWrapper) getErrorContext(err error) hook.ErrorContext {
 			exitCode = code
 		}
 
-		ctx.CommandLine = fail.Commandline()
+		ctx.CommandLine = mask.Text(fail.Commandline())
 		ctx.ExitCode = strconv.Itoa(exitCode)
-		ctx.Stderr = fail.Stderr()
+		ctx.Stderr = mask.Text(fail.Stderr())
 	}
 	return ctx
 }
@@ -851,6 +1057,56 @@ func (r *resticWrapper) canRetryAfterError(command string, summary monitor.Summa
 	return retry, nil
 }
 
+// countAttempts records the number of runs of a command having a retry policy, and returns it (0 without retry policy)
+func (r *resticWrapper) countAttempts(command string, attempt int) int {
+	r.attempts = 0
+	if r.profile.GetRetry(command) != nil {
+		r.attempts = attempt
+	}
+	return r.attempts
+}
+
+// canRetryAfterFailure returns true if the retry policy of the command allows another attempt after the failure.
+// It waits for the retry delay before returning.
+func (r *resticWrapper) canRetryAfterFailure(command string, attempt int, err error, stderr string) (bool, error) {
+	retry := r.profile.GetRetry(command)
+	if retry == nil {
+		return false, nil
+	}
+	exitCode := -1
+	if exitErr, ok := asExitError(err); ok {
+		exitCode = exitErr.ExitCode()
+	}
+	matches, patternErr := retry.Matches(exitCode, stderr)
+	if patternErr != nil {
+		clog.Errorf("profile '%s': %s", r.profile.Name, patternErr)
+	}
+	if !matches {
+		return false, nil
+	}
+
+	maxAttempts := retry.GetMaxAttempts()
+	if attempt >= maxAttempts {
+		clog.Errorf("profile '%s': '%s' failed after %d attempts", r.profile.Name, command, attempt)
+		return false, nil
+	}
+	delay := retry.GetDelay(attempt)
+	if retry.UseLockWait && r.lockWait != nil {
+		// time spent waiting between attempts is taken from the lock wait budget
+		remaining := *r.lockWait - time.Since(r.startTime) + r.executionTime
+		if remaining < delay {
+			clog.Errorf("profile '%s': '%s' failed (attempt %d/%d), not enough lock wait time left to retry", r.profile.Name, command, attempt, maxAttempts)
+			return false, nil
+		}
+	}
+
+	clog.Warningf("profile '%s': '%s' failed (attempt %d/%d), retrying in %s: %s", r.profile.Name, command, attempt, maxAttempts, delay, err)
+	if err = interruptibleSleep(delay, r.sigChan); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (r *resticWrapper) canRetryAfterRemoteLockFailure(output monitor.OutputAnalysis) (bool, time.Duration) {
 	if !output.ContainsRemoteLockFailure() {
 		return false, 0
@@ -956,3 +1212,14 @@ func asExitError(err error) (*exec.ExitError, bool) {
 	}
 	return nil, false
 }
+
+// isInterrupted returns true when the error is caused by an interruption (signal) rather than by a failure of restic
+func isInterrupted(err error) bool {
+	if errors.Is(err, errInterrupt) {
+		return true
+	}
+	if exitErr, ok := asExitError(err); ok {
+		return exitErr.ExitCode() == constants.ResticExitCodeInterrupted || exitErr.ExitCode() < 0
+	}
+	return false
+}
@@ -17,9 +17,16 @@ import (
 )
 
 func (r *resticWrapper) prepareStreamSource() (io.ReadCloser, error) {
+	if source := r.stdinSource; source != nil {
+		if r.useStdinFromCommand(source) {
+			//nolint:nilnil
+			return nil, nil
+		}
+		return r.prepareCommandStreamSource(source.Command, fmt.Sprintf("stdin source '%s'", source.Name))
+	}
 	if r.profile.Backup != nil && r.profile.Backup.UseStdin {
 		if len(r.profile.Backup.StdinCommand) > 0 {
-			return r.prepareCommandStreamSource()
+			return r.prepareCommandStreamSource(r.profile.Backup.StdinCommand, "'stdin-command'")
 		} else {
 			return r.prepareStdinStreamSource()
 		}
@@ -56,7 +63,7 @@ func (r *resticWrapper) prepareStdinStreamSource() (io.ReadCloser, error) {
 	return readCloser, nil
 }
 
-func (r *resticWrapper) prepareCommandStreamSource() (io.ReadCloser, error) {
+func (r *resticWrapper) prepareCommandStreamSource(commands []string, label string) (io.ReadCloser, error) {
 	clog.Debug("redirecting command output to the backup")
 	pipeReader, pipeWriter := io.Pipe()
 	bufferedWriter := bufio.NewWriterSize(pipeWriter, 8*1024)
@@ -72,8 +79,8 @@ func (r *resticWrapper) prepareCommandStreamSource() (io.ReadCloser, error) {
 		env := r.getEnvironment(true)
 		env = append(env, r.getProfileEnvironment()...)
 
-		for i, sourceCommand := range r.profile.Backup.StdinCommand {
-			clog.Debugf("starting 'stdin-command' command %d/%d: %s", i+1, len(r.profile.Backup.StdinCommand), sourceCommand)
+		for i, sourceCommand := range commands {
+			clog.Debugf("starting %s command %d/%d: %s", label, i+1, len(commands), sourceCommand)
 			rCommand := newShellCommand(sourceCommand, nil, env, r.getShell(), r.dryRun, commandSignals, nil)
 			rCommand.stdout = bufferedWriter
 			rCommand.stderr = r.ctx.terminal.Stderr()
@@ -83,7 +90,7 @@ func (r *resticWrapper) prepareCommandStreamSource() (io.ReadCloser, error) {
 				// discard unflushed output
 				bufferedWriter.Reset(pipeWriter)
 				// push command error to reader
-				err = newCommandError(rCommand, stderr, fmt.Errorf("'stdin-command' on profile '%s': %w", r.profile.Name, err))
+				err = newCommandError(rCommand, stderr, fmt.Errorf("%s on profile '%s': %w", label, r.profile.Name, err))
 				if closeError := pipeWriter.CloseWithError(err); closeError != nil {
 					clog.Errorf("Failed closing pipe for command '%s' after %w ; close error: %w", sourceCommand, err, closeError)
 				}
@@ -94,7 +101,7 @@ func (r *resticWrapper) prepareCommandStreamSource() (io.ReadCloser, error) {
 
 	closePipe := func() error {
 		defer func() {
-			clog.Debugf("stopping 'stdin-command'")
+			clog.Debugf("stopping %s", label)
 			signal.Stop(commandSignals)
 			commandSignals <- os.Interrupt
 		}()
@@ -106,7 +113,7 @@ func (r *resticWrapper) prepareCommandStreamSource() (io.ReadCloser, error) {
 	{
 		initialBytes := make([]byte, 512)
 		if n, err := pipeReader.Read(initialBytes); err == nil || errors.Is(err, io.EOF) {
-			clog.Debugf("initial %d bytes successfully read from 'stdin-command'", n)
+			clog.Debugf("initial %d bytes successfully read from %s", n, label)
 			initialReader = bytes.NewReader(initialBytes[:n])
 		} else {
 			_ = closePipe()
@@ -33,6 +33,22 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// newTestWrapper returns a wrapper running the command on the profile with the binary,
+// and the buffer receiving the output of the commands
+func newTestWrapper(t *testing.T, profile *config.Profile, command, binary string, arguments ...string) (*resticWrapper, *bytes.Buffer) {
+	t.Helper()
+	stdout := &bytes.Buffer{}
+	ctx := &Context{
+		binary:   binary,
+		profile:  profile,
+		command:  command,
+		request:  Request{arguments: arguments},
+		sigChan:  make(chan os.Signal, 1),
+		terminal: term.NewTerminal(term.WithStdout(stdout), term.WithStderr(&bytes.Buffer{})),
+	}
+	return newResticWrapper(ctx), stdout
+}
+
 func TestValidResticArgumentsList(t *testing.T) {
 	t.Parallel()
 
@@ -935,6 +951,149 @@ func TestBackupWithResticLockFailureCancelled(t *testing.T) {
 	assert.ErrorIs(t, err, errInterrupt)
 }
 
+func TestBackupWithRetry(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		retry     *config.RetrySection
+		arguments []string
+		attempts  int
+	}{
+		{
+			name:      "no retry policy",
+			arguments: []string{"--exit", "1"},
+			attempts:  0,
+		},
+		{
+			name:      "exit code",
+			retry:     &config.RetrySection{ExitCodes: []int{1}, MaxAttempts: 3, Delay: time.Millisecond},
+			arguments: []string{"--exit", "1"},
+			attempts:  3,
+		},
+		{
+			name:      "other exit code",
+			retry:     &config.RetrySection{ExitCodes: []int{1}, MaxAttempts: 3, Delay: time.Millisecond},
+			arguments: []string{"--exit", "10"},
+			attempts:  1,
+		},
+		{
+			name:      "error pattern",
+			retry:     &config.RetrySection{ErrorPatterns: []string{"connection reset"}, MaxAttempts: 2, Delay: time.Millisecond},
+			arguments: []string{"--stderr", "Fatal: read: connection reset by peer", "--exit", "1"},
+			attempts:  2,
+		},
+		{
+			name:      "other error",
+			retry:     &config.RetrySection{ErrorPatterns: []string{"connection reset"}, MaxAttempts: 2, Delay: time.Millisecond},
+			arguments: []string{"--stderr", "Fatal: wrong password", "--exit", "1"},
+			attempts:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			statusFile := filepath.Join(t.TempDir(), "status.json")
+			profile := config.NewProfile(nil, "name")
+			profile.StatusFile = statusFile
+			profile.Backup = &config.BackupSection{}
+			profile.Backup.Retry = tc.retry
+			ctx := &Context{
+				binary:   mockBinary,
+				profile:  profile,
+				command:  constants.CommandBackup,
+				request:  Request{arguments: tc.arguments},
+				sigChan:  make(chan os.Signal, 1),
+				terminal: term.NewTerminal(),
+			}
+			wrapper := newResticWrapper(ctx)
+			wrapper.addProgress(status.NewProgress(profile, status.NewStatus(statusFile)))
+			err := wrapper.runCommand(constants.CommandBackup)
+			require.Error(t, err)
+
+			backup := status.NewStatus(statusFile).Load().Profile("name").Backup
+			require.NotNil(t, backup)
+			assert.Equal(t, tc.attempts, backup.Attempts)
+			if tc.attempts > 0 {
+				assert.Contains(t, wrapper.getProfileEnvironment(), fmt.Sprintf("ATTEMPTS=%d", tc.attempts))
+			} else {
+				assert.NotContains(t, strings.Join(wrapper.getProfileEnvironment(), "\n"), "ATTEMPTS=")
+			}
+		})
+	}
+}
+
+func TestCheckWithRetry(t *testing.T) {
+	t.Parallel()
+
+	profile := config.NewProfile(nil, "name")
+	profile.Check = &config.GenericSectionWithSchedule{}
+	profile.Check.Retry = &config.RetrySection{ExitCodes: []int{10}, MaxAttempts: 2, Delay: time.Millisecond}
+	ctx := &Context{
+		binary:   mockBinary,
+		profile:  profile,
+		command:  constants.CommandCheck,
+		request:  Request{arguments: []string{"--exit", "10"}},
+		sigChan:  make(chan os.Signal, 1),
+		terminal: term.NewTerminal(),
+	}
+	wrapper := newResticWrapper(ctx)
+	err := wrapper.runCheck()
+	require.Error(t, err)
+	assert.Equal(t, 2, wrapper.getContext().Attempts)
+}
+
+func TestBackupWithRetryCancelled(t *testing.T) {
+	t.Parallel()
+
+	sigChan := make(chan os.Signal, 1)
+	profile := config.NewProfile(nil, "name")
+	profile.Backup = &config.BackupSection{}
+	profile.Backup.Retry = &config.RetrySection{ExitCodes: []int{1}, Delay: time.Hour}
+	ctx := &Context{
+		binary:   mockBinary,
+		profile:  profile,
+		command:  constants.CommandBackup,
+		request:  Request{arguments: []string{"--exit", "1"}},
+		sigChan:  sigChan,
+		terminal: term.NewTerminal(),
+	}
+	wrapper := newResticWrapper(ctx)
+
+	timer := time.AfterFunc(1*time.Second, func() {
+		sigChan <- os.Interrupt
+	})
+	defer timer.Stop()
+
+	err := wrapper.runCommand(constants.CommandBackup)
+	assert.ErrorIs(t, err, errInterrupt)
+}
+
+func TestBackupWithRetryOnLockWaitBudget(t *testing.T) {
+	t.Parallel()
+
+	lockWait := time.Minute
+	profile := config.NewProfile(nil, "name")
+	profile.Backup = &config.BackupSection{}
+	profile.Backup.Retry = &config.RetrySection{ExitCodes: []int{1}, Delay: 2 * time.Minute, UseLockWait: true}
+	ctx := &Context{
+		binary:   mockBinary,
+		profile:  profile,
+		command:  constants.CommandBackup,
+		request:  Request{arguments: []string{"--exit", "1"}},
+		sigChan:  make(chan os.Signal, 1),
+		terminal: term.NewTerminal(),
+	}
+	wrapper := newResticWrapper(ctx)
+	wrapper.lockWait = &lockWait
+	wrapper.startTime = time.Now()
+	err := wrapper.runCommand(constants.CommandBackup)
+	require.Error(t, err)
+	assert.Equal(t, 1, wrapper.getContext().Attempts)
+}
+
 func TestBackupWithNoConfiguration(t *testing.T) {
 	t.Parallel()
 
@@ -954,8 +1113,9 @@ func TestBackupWithNoConfiguration(t *testing.T) {
 func TestBackupWithNoConfigurationButStatusFile(t *testing.T) {
 	t.Parallel()
 
+	statusFile := filepath.Join(t.TempDir(), "status.json")
 	profile := config.NewProfile(nil, "name")
-	profile.StatusFile = "status.json"
+	profile.StatusFile = statusFile
 	ctx := &Context{
 		binary:   mockBinary,
 		profile:  profile,
@@ -964,7 +1124,7 @@ func TestBackupWithNoConfigurationButStatusFile(t *testing.T) {
 		terminal: term.NewTerminal(),
 	}
 	wrapper := newResticWrapper(ctx)
-	wrapper.addProgress(status.NewProgress(profile, status.NewStatus("status.json")))
+	wrapper.addProgress(status.NewProgress(profile, status.NewStatus(statusFile)))
 	err := wrapper.runCommand("backup")
 	require.Error(t, err)
 }
@@ -1744,3 +1904,106 @@ func TestRunUnlockWithCommandLineFlags(t *testing.T) {
 	cmd := wrapper.prepareCommand(constants.CommandUnlock, args, false)
 	assert.Equal(t, []string{"unlock"}, cmd.args)
 }
+
+// stepsReceiver records the commands reported to a monitor.Receiver
+type stepsReceiver struct {
+	started   []string
+	summaries map[string]monitor.Summary
+	results   map[string]error
+}
+
+func (s *stepsReceiver) Start(command string)         { s.started = append(s.started, command) }
+func (s *stepsReceiver) Status(status monitor.Status) {}
+func (s *stepsReceiver) Summary(command string, summary monitor.Summary, stderr string, result error) {
+	s.summaries[command] = summary
+	s.results[command] = result
+}
+
+func TestRunCompositeCommand(t *testing.T) {
+	testConfig := `
+version: "1"
+profile:
+  prune:
+    exit: 2
+  commands:
+    maintenance:
+      steps:
+        - command: forget
+        - command: prune
+          continue-on-error: true
+        - command: check
+    strict:
+      steps:
+        - command: forget
+        - command: prune
+        - command: check
+    passing:
+      steps:
+        - command: forget
+        - command: prune
+          exit: 0
+`
+	testCases := []struct {
+		command  string
+		started  []string
+		failed   []string
+		hasError bool
+	}{
+		{
+			command:  "maintenance",
+			started:  []string{"maintenance", "forget", "prune", "check"},
+			failed:   []string{"prune"},
+			hasError: true,
+		},
+		{
+			command:  "strict",
+			started:  []string{"strict", "forget", "prune"},
+			failed:   []string{"prune"},
+			hasError: true,
+		},
+		{
+			command: "passing",
+			started: []string{"passing", "forget", "prune"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.command, func(t *testing.T) {
+			cfg, err := config.Load(strings.NewReader(testConfig), "yaml")
+			require.NoError(t, err)
+			profile, err := cfg.GetProfile("profile")
+			require.NoError(t, err)
+
+			ctx := &Context{
+				binary:   mockBinary,
+				profile:  profile,
+				command:  testCase.command,
+				terminal: term.NewTerminal(term.WithStdout(&bytes.Buffer{}), term.WithStderr(&bytes.Buffer{})),
+			}
+			receiver := &stepsReceiver{summaries: make(map[string]monitor.Summary), results: make(map[string]error)}
+			wrapper := newResticWrapper(ctx)
+			wrapper.addProgress(receiver)
+
+			err = wrapper.runProfile()
+			if testCase.hasError {
+				assert.ErrorContains(t, err, "exit status 2")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, testCase.started, receiver.started)
+
+			// each step is reported on its own, then the composite command with all the steps
+			steps := receiver.summaries[testCase.command].Steps
+			require.Len(t, steps, len(testCase.started)-1)
+			for index, step := range steps {
+				assert.Equal(t, testCase.started[index+1], step.Command)
+				assert.Equal(t, slices.Contains(testCase.failed, step.Command), step.Error != nil)
+				assert.Equal(t, step.Error != nil, receiver.results[step.Command] != nil)
+			}
+			assert.Equal(t, err, receiver.results[testCase.command])
+
+			// steps are available in the context of the hooks
+			assert.Len(t, wrapper.getContext().Steps, len(steps))
+		})
+	}
+}